@@ -4,10 +4,13 @@ import (
 	"auth-service/internal/config"
 	"auth-service/internal/logger"
 	grpcserver "auth-service/internal/server/grpc" // единый алиас
+	httpserver "auth-service/internal/server/http"
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 // App - основная структура приложения
@@ -15,6 +18,8 @@ type App struct {
 	config     *config.Config
 	logger     logger.Logger
 	grpcServer *grpcserver.Server // используем алиас
+	httpServer *httpserver.Server
+	deps       *Dependencies
 }
 
 // New создает новое приложение
@@ -71,9 +76,14 @@ func (a *App) initDependencies() error {
 		return err
 	}
 
+	a.deps = deps
+
 	// Создаем gRPC сервер и сохраняем в структуру App
 	a.grpcServer = grpcserver.NewServer(deps.AuthHandler, a.logger)
 
+	// HTTP сервер для публичных эндпоинтов (JWKS)
+	a.httpServer = httpserver.NewServer(deps.HTTPHandler, a.logger)
+
 	return nil
 }
 
@@ -85,8 +95,18 @@ func (a *App) Run() error {
 	if err := a.grpcServer.Start(a.config.GRPCPort); err != nil {
 		return err
 	}
+	if err := a.httpServer.Start(a.config.Port); err != nil {
+		return err
+	}
 	// reflection.Register(a.grpcServer)
+
+	// Фоновые задачи (ротация ключей и т.п.)
+	ctx, cancel := context.WithCancel(context.Background())
+	a.deps.StartWorkers(ctx)
+
 	a.waitForShutdown()
+	cancel()
+	a.deps.Close()
 	return nil
 }
 
@@ -99,5 +119,15 @@ func (a *App) waitForShutdown() {
 	a.logger.Info("Received signal, shutting down...", logger.F("signal", sig))
 
 	a.grpcServer.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.httpServer.Stop(ctx); err != nil {
+		a.logger.Error("HTTP server shutdown failed", logger.F("error", err))
+	}
+	// Письма, поставленные после ответа клиенту, не должны теряться при остановке
+	if err := a.deps.AuthService.Close(ctx); err != nil {
+		a.logger.Warn("Background operations did not finish", logger.F("error", err))
+	}
 	a.logger.Info("Service stopped gracefully")
 }
@@ -5,12 +5,30 @@ import (
 	"auth-service/internal/database"
 	"auth-service/internal/handler"
 	"auth-service/internal/handler/grpchandler"
+	"auth-service/internal/handler/httphandler"
 	"auth-service/internal/logger"
 	"auth-service/internal/repository"
+	"auth-service/internal/repository/memory"
 	"auth-service/internal/repository/postgres"
 	"auth-service/internal/service"
+	"auth-service/internal/util/dpop"
 	"auth-service/internal/util/jwt"
+	"auth-service/internal/util/mailer"
+	"auth-service/internal/util/paseto"
+	"auth-service/internal/util/password"
+	"auth-service/internal/util/secretbox"
+	"auth-service/internal/util/webauthn"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -18,11 +36,35 @@ import (
 
 // Dependencies контейнер зависимостей
 type Dependencies struct {
-	DB          *sqlx.DB
-	JWTManager  jwt.TokenManager
-	UserRepo    repository.UserRepository
-	AuthService service.AuthService
-	AuthHandler handler.AuthHandler
+	DB            *sqlx.DB
+	JWTManager    jwt.TokenManager
+	KeySet        jwt.KeySetProvider
+	UserRepo      repository.UserRepository
+	RefreshRepo   repository.RefreshTokenRepository
+	HistoryRepo   repository.PasswordHistoryRepository
+	ResetRepo     repository.PasswordResetRepository
+	VerifyRepo    repository.EmailVerificationRepository
+	MFARepo       repository.MFARepository
+	WebAuthnRepo  repository.WebAuthnRepository
+	AuditRepo     repository.AuditRepository
+	LoginCodeRepo repository.LoginCodeRepository
+	AttemptRepo   repository.LoginAttemptRepository
+	OutboxRepo    repository.MailOutboxRepository
+	RevokedRepo   repository.TokenRevocationRepository
+	OpaqueRepo    repository.OpaqueTokenRepository
+	KeyRepo       repository.SigningKeyRepository
+	KeyRotator    service.KeyRotator
+	TokenService  service.TokenService
+	Policy        *service.TokenExchangePolicy
+	AuthService   service.AuthService
+	KeyService    service.KeyService
+	Exchange      service.TokenExchangeService
+	Mail          service.MailDispatcher
+	AuthHandler   handler.AuthHandler
+	HTTPHandler   http.Handler
+
+	// фоновые задачи, запускаются вместе с приложением
+	workers []func(ctx context.Context)
 }
 
 // NewDependencies создает все зависимости в правильном порядке
@@ -34,17 +76,23 @@ func NewDependencies(cfg *config.Config, log logger.Logger) (*Dependencies, erro
 		return nil, err
 	}
 
-	// 2. JWT менеджер
-	deps.initJWTManager(cfg, log)
+	// 2. Репозитории
+	if err := deps.initRepositories(cfg, log); err != nil {
+		return nil, err
+	}
 
-	// 3. Репозитории
-	deps.initRepositories(log)
+	// 3. JWT менеджер
+	if err := deps.initJWTManager(cfg, log); err != nil {
+		return nil, err
+	}
 
 	// 4. Сервисы
-	deps.initServices(log)
+	if err := deps.initServices(cfg, log); err != nil {
+		return nil, err
+	}
 
 	// 5. Обработчики
-	deps.initHandlers(log)
+	deps.initHandlers(cfg, log)
 
 	return deps, nil
 }
@@ -85,38 +133,643 @@ func (d *Dependencies) initDatabase(cfg *config.Config, log logger.Logger) error
 }
 
 // initJWTManager инициализирует JWT менеджер
-func (d *Dependencies) initJWTManager(cfg *config.Config, log logger.Logger) {
+func (d *Dependencies) initJWTManager(cfg *config.Config, log logger.Logger) error {
 	jwtCfg := jwt.Config{
 		AccessTokenSecret:  cfg.JWTSecret,
 		RefreshTokenSecret: cfg.JWTRefreshSecret,
 		AccessTokenExpiry:  cfg.AccessTokenExpiry,
 		RefreshTokenExpiry: cfg.RefreshTokenExpiry,
+		Issuer:             cfg.JWTIssuer,
+		Audience:           cfg.JWTAudience,
+	}
+
+	// Токены, выпущенные обменом, адресованы другим сервисам: их принимают ValidateToken и Introspect
+	// для resource серверов, но не операции над аккаунтом (AuthConfig.Audience)
+	policy, err := service.LoadTokenExchangePolicy(cfg.TokenExchangePolicyFile)
+	if err != nil {
+		return err
+	}
+	d.Policy = policy
+	jwtCfg.AcceptedAudiences = policy.Audiences()
+
+	if cfg.JWTSigningKeyFile != "" {
+		signingKey, err := jwt.LoadSigningKeyFile(cfg.JWTSigningKeyID, cfg.JWTSigningKeyFile)
+		if err != nil {
+			return fmt.Errorf("load jwt signing key: %w", err)
+		}
+		jwtCfg.SigningKey = signingKey
+	}
+
+	for _, entry := range cfg.JWTVerificationKeyFiles {
+		kid, path := "", entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			kid, path = entry[:i], entry[i+1:]
+		}
+
+		verificationKey, err := jwt.LoadVerificationKeyFile(kid, path)
+		if err != nil {
+			return fmt.Errorf("load jwt verification key %q: %w", path, err)
+		}
+		jwtCfg.VerificationKeys = append(jwtCfg.VerificationKeys, verificationKey)
+	}
+
+	manager := jwt.NewManager(jwtCfg, jwt.WithRevocationChecker(d.RevokedRepo))
+	d.JWTManager = manager
+	d.KeySet = manager
+	var keyRing jwt.KeyRing = manager
+
+	signing := "HS256"
+	if jwtCfg.SigningKey != nil {
+		signing = jwtCfg.SigningKey.Algorithm + " (kid " + jwtCfg.SigningKey.ID + ")"
+	}
+
+	switch cfg.TokenFormat {
+	case "paseto":
+		pasetoManager, err := newPasetoManager(cfg, jwtCfg, d.RevokedRepo)
+		if err != nil {
+			return err
+		}
+		d.JWTManager = pasetoManager
+		d.KeySet = pasetoManager
+		keyRing = pasetoManager
+
+		signing = "PASETO v4." + cfg.PasetoPurpose
+		if d.KeyRepo != nil && cfg.PasetoPurpose == jwt.PasetoPublic && cfg.JWTSigningAlgorithm != jwt.AlgorithmEdDSA {
+			return fmt.Errorf("PASETO v4.public key rotation requires JWT_SIGNING_ALGORITHM=%s", jwt.AlgorithmEdDSA)
+		}
+
+	// В opaque режиме клиенты получают случайные токены, JWT менеджер остается только для JWKS
+	case "opaque":
+		d.JWTManager = jwt.NewOpaqueManager(jwtCfg, d.OpaqueRepo, d.RevokedRepo)
+		log.Info("Opaque token manager configured",
+			logger.F("store", cfg.OpaqueTokenStore),
+			logger.F("access_expiry", jwtCfg.AccessTokenExpiry),
+			logger.F("refresh_expiry", jwtCfg.RefreshTokenExpiry),
+		)
+		return nil
 	}
 
-	d.JWTManager = jwt.NewManager(jwtCfg)
+	// Ключи из хранилища с ротацией заменяют статические ключи из файлов
+	if d.KeyRepo != nil {
+		if cfg.JWTKeyGracePeriod < cfg.AccessTokenExpiry {
+			log.Warn("JWT key grace period is shorter than access token expiry, tokens may be rejected after rotation",
+				logger.F("grace_period", cfg.JWTKeyGracePeriod),
+				logger.F("access_expiry", cfg.AccessTokenExpiry),
+			)
+		}
+
+		d.KeyRotator = service.NewKeyRotator(d.KeyRepo, keyRing, service.KeyRotationConfig{
+			Algorithm:        cfg.JWTSigningAlgorithm,
+			RotationInterval: cfg.JWTKeyRotationInterval,
+			GracePeriod:      cfg.JWTKeyGracePeriod,
+			CheckInterval:    cfg.JWTKeyRotationCheck,
+		}, log)
+
+		if err := d.KeyRotator.Sync(context.Background()); err != nil {
+			return fmt.Errorf("load jwt keys from store: %w", err)
+		}
+		d.workers = append(d.workers, d.KeyRotator.Run)
+
+		signing += ", " + cfg.JWTSigningAlgorithm + " keys rotated (store " + cfg.JWTKeyStore + ")"
+	}
 
 	log.Info("JWT manager configured",
+		logger.F("signing", signing),
+		logger.F("verification_keys", len(jwtCfg.VerificationKeys)),
 		logger.F("access_expiry", jwtCfg.AccessTokenExpiry),
 		logger.F("refresh_expiry", jwtCfg.RefreshTokenExpiry),
 	)
+	return nil
+}
+
+// newPasetoManager собирает PASETO менеджер. Симметричные ключи берутся только из PASETO_*_KEY (hex):
+// refresh ключ нужен всегда, local - для PASETO_PURPOSE=local.
+func newPasetoManager(cfg *config.Config, jwtCfg jwt.Config, revocations jwt.RevocationChecker) (*jwt.PasetoManager, error) {
+	var localKey []byte
+	if cfg.PasetoPurpose == jwt.PasetoLocal {
+		key, err := pasetoKey(cfg.PasetoLocalKey)
+		if err != nil {
+			return nil, fmt.Errorf("PASETO_LOCAL_KEY: %w", err)
+		}
+		localKey = key
+	}
+	refreshKey, err := pasetoKey(cfg.PasetoRefreshKey)
+	if err != nil {
+		return nil, fmt.Errorf("PASETO_REFRESH_KEY: %w", err)
+	}
+
+	return jwt.NewPasetoManager(jwtCfg, jwt.PasetoConfig{
+		Purpose:    cfg.PasetoPurpose,
+		LocalKey:   localKey,
+		RefreshKey: refreshKey,
+	}, revocations)
+}
+
+// pasetoKey декодирует обязательный симметричный ключ v4.local
+func pasetoKey(hexKey string) ([]byte, error) {
+	if hexKey == "" {
+		return nil, errors.New("must be set when TOKEN_FORMAT=paseto")
+	}
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex: %w", err)
+	}
+	if len(key) != paseto.LocalKeySize {
+		return nil, fmt.Errorf("must be %d bytes, got %d", paseto.LocalKeySize, len(key))
+	}
+	return key, nil
 }
 
 // initRepositories инициализирует репозитории
-func (d *Dependencies) initRepositories(log logger.Logger) {
+func (d *Dependencies) initRepositories(cfg *config.Config, log logger.Logger) error {
 	d.UserRepo = postgres.NewUserRepository(d.DB, log)
 	log.Info("User repository initialized")
+
+	d.RefreshRepo = postgres.NewRefreshTokenRepository(d.DB, log)
+	log.Info("Refresh token repository initialized")
+
+	d.HistoryRepo = postgres.NewPasswordHistoryRepository(d.DB, log)
+	log.Info("Password history repository initialized")
+
+	d.ResetRepo = postgres.NewPasswordResetRepository(d.DB, log)
+	d.VerifyRepo = postgres.NewEmailVerificationRepository(d.DB, log)
+	d.OutboxRepo = postgres.NewMailOutboxRepository(d.DB, log)
+	log.Info("Password reset, email verification and mail outbox repositories initialized")
+
+	d.MFARepo = postgres.NewMFARepository(d.DB, log)
+	log.Info("MFA repository initialized")
+
+	d.WebAuthnRepo = postgres.NewWebAuthnRepository(d.DB, log)
+	log.Info("WebAuthn repository initialized")
+
+	d.AuditRepo = postgres.NewAuditRepository(d.DB, log)
+	log.Info("Audit repository initialized")
+
+	d.LoginCodeRepo = postgres.NewLoginCodeRepository(d.DB, log)
+	log.Info("Login code repository initialized")
+
+	switch cfg.LoginAttemptStore {
+	case "memory":
+		d.AttemptRepo = memory.NewLoginAttemptRepository()
+	case "postgres":
+		d.AttemptRepo = postgres.NewLoginAttemptRepository(d.DB, log)
+	default:
+		return fmt.Errorf("unknown LOGIN_ATTEMPT_STORE %q", cfg.LoginAttemptStore)
+	}
+	log.Info("Login attempt repository initialized", logger.F("store", cfg.LoginAttemptStore))
+
+	switch cfg.RevocationStore {
+	case "memory":
+		d.RevokedRepo = memory.NewTokenRevocationRepository()
+	case "postgres":
+		d.RevokedRepo = postgres.NewTokenRevocationRepository(d.DB, log)
+	default:
+		return fmt.Errorf("unknown TOKEN_REVOCATION_STORE %q", cfg.RevocationStore)
+	}
+	log.Info("Token revocation repository initialized", logger.F("store", cfg.RevocationStore))
+
+	switch cfg.TokenFormat {
+	case "jwt", "paseto":
+	case "opaque":
+		switch cfg.OpaqueTokenStore {
+		case "memory":
+			d.OpaqueRepo = memory.NewOpaqueTokenRepository()
+		case "postgres":
+			d.OpaqueRepo = postgres.NewOpaqueTokenRepository(d.DB, log)
+		default:
+			return fmt.Errorf("unknown OPAQUE_TOKEN_STORE %q", cfg.OpaqueTokenStore)
+		}
+		log.Info("Opaque token repository initialized", logger.F("store", cfg.OpaqueTokenStore))
+	default:
+		return fmt.Errorf("unknown TOKEN_FORMAT %q", cfg.TokenFormat)
+	}
+
+	switch cfg.JWTKeyStore {
+	case "":
+	case "memory":
+		d.KeyRepo = memory.NewSigningKeyRepository()
+	case "postgres":
+		d.KeyRepo = postgres.NewSigningKeyRepository(d.DB, log)
+	default:
+		return fmt.Errorf("unknown JWT_KEY_STORE %q", cfg.JWTKeyStore)
+	}
+	if d.KeyRepo != nil {
+		log.Info("Signing key repository initialized", logger.F("store", cfg.JWTKeyStore))
+	}
+
+	return nil
 }
 
 // initServices инициализирует сервисы
-func (d *Dependencies) initServices(log logger.Logger) {
-	d.AuthService = service.NewAuthService(d.UserRepo, d.JWTManager, log)
+func (d *Dependencies) initServices(cfg *config.Config, log logger.Logger) error {
+	var tokenOpts []service.TokenServiceOption
+	switch cfg.UnverifiedLoginMode {
+	case service.UnverifiedLoginAllow, service.UnverifiedLoginDeny:
+	case service.UnverifiedLoginRestricted:
+		tokenOpts = append(tokenOpts, service.WithUnverifiedRestriction())
+	default:
+		return fmt.Errorf("unknown UNVERIFIED_LOGIN_MODE %q", cfg.UnverifiedLoginMode)
+	}
+
+	d.TokenService = service.NewTokenService(d.UserRepo, d.RefreshRepo, d.RevokedRepo, d.JWTManager, service.ValidationCacheConfig{
+		Size:        cfg.TokenCacheSize,
+		TTL:         cfg.TokenCacheTTL,
+		NegativeTTL: cfg.TokenCacheNegativeTTL,
+	}, log, tokenOpts...)
+	log.Info("Token service initialized", logger.F("unverified_login_mode", cfg.UnverifiedLoginMode))
+
+	// Записи denylist нужны только до exp токена
+	d.workers = append(d.workers, periodic("purge_revoked_tokens", cfg.RevocationPurgeInterval, func(ctx context.Context) error {
+		_, err := d.RevokedRepo.DeleteExpired(ctx, time.Now())
+		return err
+	}, log))
+
+	if d.OpaqueRepo != nil {
+		d.workers = append(d.workers, periodic("purge_opaque_tokens", cfg.OpaquePurgeInterval, func(ctx context.Context) error {
+			_, err := d.OpaqueRepo.DeleteExpired(ctx, time.Now())
+			return err
+		}, log))
+	}
+
+	// История паролей нужна только в пределах срока хранения
+	if cfg.PasswordHistoryRetention > 0 {
+		d.workers = append(d.workers, periodic("purge_password_history", cfg.PasswordHistoryPurgeInterval, func(ctx context.Context) error {
+			_, err := d.HistoryRepo.DeleteOlderThan(ctx, time.Now().Add(-cfg.PasswordHistoryRetention))
+			return err
+		}, log))
+	}
+
+	dpopVerifier := dpop.NewVerifier(dpop.Config{
+		MaxAge:          cfg.DPoPProofMaxAge,
+		ClockSkew:       cfg.DPoPClockSkew,
+		ReplayCacheSize: cfg.DPoPReplayCacheSize,
+	})
+
+	hasher, err := newPasswordHasher(cfg, log)
+	if err != nil {
+		return err
+	}
+
+	policy, err := newPasswordPolicy(cfg, log)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := newMFASecretBox(cfg, log)
+	if err != nil {
+		return err
+	}
+
+	relyingParty, err := newRelyingParty(cfg, log)
+	if err != nil {
+		return err
+	}
+
+	attempts := service.NewAttemptTracker(d.AttemptRepo, service.AttemptTrackerConfig{
+		AccountThreshold: cfg.LoginAccountThreshold,
+		IPThreshold:      cfg.LoginIPThreshold,
+		BaseDelay:        cfg.LoginBaseDelay,
+		MaxDelay:         cfg.LoginMaxDelay,
+		LockoutThreshold: cfg.LoginLockoutThreshold,
+		LockoutDuration:  cfg.LoginLockoutDuration,
+		Window:           cfg.LoginAttemptWindow,
+	}, log)
+
+	d.AuthService, err = service.NewAuthService(service.AuthDependencies{
+		Users:           d.UserRepo,
+		PasswordHistory: d.HistoryRepo,
+		PasswordResets:  d.ResetRepo,
+		Verifications:   d.VerifyRepo,
+		Outbox:          d.OutboxRepo,
+		MFA:             d.MFARepo,
+		Secrets:         secrets,
+		WebAuthn:        d.WebAuthnRepo,
+		RelyingParty:    relyingParty,
+		LoginCodes:      d.LoginCodeRepo,
+		Audit:           d.AuditRepo,
+		Attempts:        attempts,
+		Tokens:          d.TokenService,
+		Hasher:          hasher,
+		Policy:          policy,
+		DPoP:            dpopVerifier,
+	}, service.AuthConfig{
+		Audience: cfg.JWTAudience,
+
+		DPoPTokenEndpoint: cfg.DPoPTokenEndpoint,
+		PasswordResetTTL:  cfg.PasswordResetTTL,
+		PasswordResetURL:  cfg.PasswordResetURL,
+
+		PasswordResetCooldown: cfg.PasswordResetCooldown,
+
+		UnverifiedLoginMode:        cfg.UnverifiedLoginMode,
+		EmailVerificationTTL:       cfg.EmailVerificationTTL,
+		EmailVerificationURL:       cfg.EmailVerificationURL,
+		VerificationResendInterval: cfg.EmailVerificationResendInterval,
+
+		TOTPIssuer:        cfg.TOTPIssuer,
+		MFAChallengeTTL:   cfg.MFAChallengeTTL,
+		MFAMaxAttempts:    cfg.MFAMaxAttempts,
+		RecoveryCodeCount: cfg.MFARecoveryCodeCount,
+
+		WebAuthnSessionTTL: cfg.WebAuthnTimeout,
+
+		LoginCodeTTL:         cfg.LoginCodeTTL,
+		LoginCodeURL:         cfg.LoginCodeURL,
+		LoginCodeMaxAttempts: cfg.LoginCodeMaxAttempts,
+		LoginCodeCooldown:    cfg.LoginCodeCooldown,
+
+		HardenedErrors: cfg.HardenedAuthErrors,
+	}, log)
+	if err != nil {
+		return fmt.Errorf("init auth service: %w", err)
+	}
 	log.Info("Auth service initialized")
+
+	d.workers = append(d.workers, periodic("purge_password_reset_tokens", cfg.PasswordResetPurgeInterval, func(ctx context.Context) error {
+		_, err := d.ResetRepo.DeleteExpired(ctx, time.Now())
+		return err
+	}, log))
+
+	d.workers = append(d.workers, periodic("purge_email_verification_tokens", cfg.EmailVerificationPurgeInterval, func(ctx context.Context) error {
+		_, err := d.VerifyRepo.DeleteExpired(ctx, time.Now())
+		return err
+	}, log))
+
+	d.workers = append(d.workers, periodic("purge_login_codes", cfg.LoginCodePurgeInterval, func(ctx context.Context) error {
+		_, err := d.LoginCodeRepo.DeleteExpired(ctx, time.Now())
+		return err
+	}, log))
+
+	d.workers = append(d.workers, periodic("purge_mfa_challenges", cfg.MFAChallengePurgeInterval, func(ctx context.Context) error {
+		_, err := d.MFARepo.DeleteExpiredChallenges(ctx, time.Now())
+		return err
+	}, log))
+
+	d.workers = append(d.workers, periodic("purge_login_attempts", cfg.LoginAttemptPurgeInterval, func(ctx context.Context) error {
+		_, err := d.AttemptRepo.DeleteStale(ctx, time.Now().Add(-cfg.LoginAttemptWindow))
+		return err
+	}, log))
+
+	d.workers = append(d.workers, periodic("purge_webauthn_sessions", cfg.WebAuthnSessionPurgeInterval, func(ctx context.Context) error {
+		_, err := d.WebAuthnRepo.DeleteExpiredSessions(ctx, time.Now())
+		return err
+	}, log))
+
+	m, err := newMailer(cfg)
+	if err != nil {
+		return err
+	}
+	d.Mail = service.NewMailDispatcher(d.OutboxRepo, m, service.MailDispatchConfig{
+		BatchSize:    cfg.MailBatchSize,
+		MaxAttempts:  cfg.MailMaxAttempts,
+		RetryBackoff: cfg.MailRetryBackoff,
+		Lease:        mailSendLease,
+	}, log)
+	d.workers = append(d.workers, periodic("dispatch_mail", cfg.MailDispatchInterval, func(ctx context.Context) error {
+		_, err := d.Mail.Dispatch(ctx)
+		return err
+	}, log))
+	log.Info("Mail dispatcher initialized", logger.F("mailer", cfg.Mailer))
+
+	d.KeyService = service.NewKeyService(d.KeySet, cfg.JWKSCacheMaxAge, log)
+	log.Info("Key service initialized")
+
+	d.Exchange = service.NewTokenExchangeService(d.TokenService, d.JWTManager, d.Policy, log)
+	log.Info("Token exchange service initialized", logger.F("audiences", d.Policy.Audiences()))
+
+	return nil
+}
+
+// mailSendLease - сколько письмо недоступно другим экземплярам, пока его отправляют
+const mailSendLease = 2 * time.Minute
+
+// newMailer выбирает способ доставки писем по MAILER
+func newMailer(cfg *config.Config) (mailer.Mailer, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		return mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.MailFileDir, cfg.MailFrom)
+	case "stdout":
+		return mailer.NewWriterMailer(os.Stdout, cfg.MailFrom), nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q", cfg.Mailer)
+	}
+}
+
+// newPasswordPolicy создает политику паролей; корпус утечек открывается на все время работы
+func newPasswordPolicy(cfg *config.Config, log logger.Logger) (*password.Policy, error) {
+	var breached password.BreachedChecker
+	if cfg.PasswordBreachedFile != "" {
+		corpus, err := password.OpenBreachedFile(cfg.PasswordBreachedFile)
+		if err != nil {
+			return nil, err
+		}
+		breached = corpus
+	} else {
+		log.Warn("PASSWORD_BREACHED_FILE is not set, breached password check is disabled")
+	}
+
+	return password.NewPolicy(password.PolicyConfig{
+		MinLength:     cfg.PasswordMinLength,
+		MaxLength:     cfg.PasswordMaxLength,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+		BannedWords:   cfg.PasswordBannedWords,
+		HistorySize:   cfg.PasswordHistorySize,
+	}, breached), nil
+}
+
+// newPasswordHasher создает хешер паролей: новые хеши - PASSWORD_HASH_ALGORITHM,
+// хеши остальных алгоритмов проверяются и перехешируются при входе.
+// Если заданы перцы, хеши дополнительно перчатся текущей версией.
+func newPasswordHasher(cfg *config.Config, log logger.Logger) (password.PasswordHasher, error) {
+	argon2Params := password.DefaultArgon2Params
+	argon2Params.Memory = uint32(cfg.Argon2Memory)
+	argon2Params.Iterations = uint32(cfg.Argon2Iterations)
+	argon2Params.Parallelism = uint8(cfg.Argon2Parallelism)
+
+	scryptParams := password.DefaultScryptParams
+	scryptParams.LogN = uint8(cfg.ScryptLogN)
+
+	hashers := map[string]password.PasswordHasher{
+		password.AlgorithmArgon2id: password.NewArgon2id(argon2Params),
+		password.AlgorithmBcrypt:   password.NewBcrypt(cfg.BcryptCost),
+		password.AlgorithmScrypt:   password.NewScrypt(scryptParams),
+	}
+
+	preferred, ok := hashers[cfg.PasswordHashAlgorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown PASSWORD_HASH_ALGORITHM %q", cfg.PasswordHashAlgorithm)
+	}
+	delete(hashers, cfg.PasswordHashAlgorithm)
+
+	legacy := make([]password.PasswordHasher, 0, len(hashers))
+	for _, hasher := range hashers {
+		legacy = append(legacy, hasher)
+	}
+
+	hasher := password.NewHasher(preferred, legacy...)
+
+	peppers, err := loadPeppers(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(peppers.Keys) == 0 {
+		log.Warn("password pepper is not configured, hashes are stored without pepper")
+		log.Info("Password hasher initialized", logger.F("algorithm", hasher.Algorithm()))
+		return hasher, nil
+	}
+
+	hasher, err = password.NewPepperedHasher(hasher, peppers)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Password hasher initialized",
+		logger.F("algorithm", hasher.Algorithm()),
+		logger.F("pepper_version", peppers.Current),
+		logger.F("pepper_versions", len(peppers.Keys)),
+	)
+
+	return hasher, nil
+}
+
+// loadPeppers читает перцы из PASSWORD_PEPPER_FILES ("версия=путь") и PASSWORD_PEPPERS ("версия=base64").
+// Текущая версия - PASSWORD_PEPPER_VERSION или старшая из загруженных.
+func loadPeppers(cfg *config.Config) (password.Peppers, error) {
+	current, keys, err := loadVersionedKeys(cfg.PasswordPepperVersion, cfg.PasswordPepperFiles, cfg.PasswordPeppers)
+	if err != nil {
+		return password.Peppers{}, fmt.Errorf("password pepper: %w", err)
+	}
+	return password.Peppers{Current: current, Keys: keys}, nil
+}
+
+// newMFASecretBox создает шифрование TOTP секретов ключами MFA_ENCRYPTION_KEY*.
+// Без настроенных ключей сервис не стартует: ключ не выводится из других секретов.
+// Исключение - dev режим, там ключ случайный и живет до перезапуска.
+func newMFASecretBox(cfg *config.Config, log logger.Logger) (*secretbox.Box, error) {
+	current, keys, err := loadVersionedKeys(cfg.MFAEncryptionKeyVersion, cfg.MFAEncryptionKeyFiles, cfg.MFAEncryptionKeys)
+	if err != nil {
+		return nil, fmt.Errorf("mfa encryption key: %w", err)
+	}
+	if len(keys) == 0 {
+		if !cfg.MFAEphemeralKey {
+			return nil, errors.New("mfa encryption key: MFA_ENCRYPTION_KEYS or MFA_ENCRYPTION_KEY_FILES must be set")
+		}
+		key := make([]byte, secretbox.KeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("mfa encryption key: %w", err)
+		}
+		log.Warn("MFA encryption keys are not configured, using a random key until restart (dev mode only)")
+		current, keys = 1, map[int][]byte{1: key}
+	}
+
+	box, err := secretbox.New(secretbox.Keys{Current: current, Keys: keys})
+	if err != nil {
+		return nil, fmt.Errorf("mfa encryption key: %w", err)
+	}
+	return box, nil
+}
+
+// newRelyingParty настраивает WebAuthn; без WEBAUTHN_RP_ID возвращает nil и RPC passkeys выключены
+func newRelyingParty(cfg *config.Config, log logger.Logger) (*webauthn.RelyingParty, error) {
+	if cfg.WebAuthnRPID == "" {
+		log.Info("WebAuthn is disabled, WEBAUTHN_RP_ID is not set")
+		return nil, nil
+	}
+
+	rp, err := webauthn.New(webauthn.Config{
+		RPID:             cfg.WebAuthnRPID,
+		RPName:           cfg.WebAuthnRPName,
+		Origins:          cfg.WebAuthnOrigins,
+		Timeout:          cfg.WebAuthnTimeout,
+		UserVerification: cfg.WebAuthnUserVerification,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webauthn: %w", err)
+	}
+	return rp, nil
+}
+
+// loadVersionedKeys читает ключи из файлов ("версия=путь") и значений ("версия=base64").
+// current = 0 означает старшую из загруженных версий.
+func loadVersionedKeys(current int, files, inline []string) (int, map[int][]byte, error) {
+	keys := make(map[int][]byte)
+
+	add := func(spec string, load func(value string) ([]byte, error)) error {
+		rawVersion, value, ok := strings.Cut(spec, "=")
+		version, err := strconv.Atoi(strings.TrimSpace(rawVersion))
+		if !ok || err != nil {
+			return errors.New("invalid key spec, expected version=value")
+		}
+		if _, exists := keys[version]; exists {
+			return fmt.Errorf("duplicate key version %d", version)
+		}
+
+		key, err := load(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("load key version %d: %w", version, err)
+		}
+		keys[version] = key
+		return nil
+	}
+
+	for _, spec := range files {
+		if err := add(spec, func(path string) ([]byte, error) {
+			data, err := os.ReadFile(path)
+			return bytes.TrimSpace(data), err
+		}); err != nil {
+			return 0, nil, err
+		}
+	}
+	for _, spec := range inline {
+		if err := add(spec, base64.StdEncoding.DecodeString); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if current == 0 {
+		for version := range keys {
+			current = max(current, version)
+		}
+	}
+
+	return current, keys, nil
 }
 
 // initHandlers инициализирует обработчики
-func (d *Dependencies) initHandlers(log logger.Logger) {
-	d.AuthHandler = grpchandler.NewAuthHandler(d.AuthService, log)
+func (d *Dependencies) initHandlers(cfg *config.Config, log logger.Logger) {
+	d.AuthHandler = grpchandler.NewAuthHandler(d.AuthService, d.KeyService, d.Exchange, cfg.LoginTrustForwardedFor, cfg.APIKey, log)
 	log.Info("Auth handler initialized")
+
+	// Introspection без API ключа не публикуем: endpoint раскрывает данные токенов
+	var introspection http.Handler
+	if cfg.APIKey != "" {
+		introspection = httphandler.NewIntrospectionHandler(d.TokenService, cfg.APIKey, log)
+	} else {
+		log.Warn("API_KEY is not set, HTTP and gRPC token introspection are disabled")
+	}
+
+	d.HTTPHandler = httphandler.NewRouter(
+		httphandler.NewJWKSHandler(d.KeySet, cfg.JWKSCacheMaxAge, log),
+		introspection,
+	)
+	log.Info("HTTP handlers initialized")
+}
+
+// StartWorkers запускает фоновые задачи, они останавливаются при отмене ctx
+func (d *Dependencies) StartWorkers(ctx context.Context) {
+	for _, worker := range d.workers {
+		go worker(ctx)
+	}
 }
 
 // Close закрывает все зависимости
@@ -0,0 +1,26 @@
+package app
+
+import (
+	"auth-service/internal/logger"
+	"context"
+	"time"
+)
+
+// periodic возвращает фоновую задачу, которая вызывает job каждые interval
+func periodic(name string, interval time.Duration, job func(ctx context.Context) error, log logger.Logger) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := job(ctx); err != nil {
+					log.Error("background job failed", logger.F("job", name), logger.F("error", err))
+				}
+			}
+		}
+	}
+}
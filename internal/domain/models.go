@@ -4,21 +4,195 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// ScopeVerifyEmail - единственный scope ограниченного токена пользователя с неподтвержденным email
+const ScopeVerifyEmail = "email:verify"
+
 type User struct {
-	ID           uuid.UUID `json:"id" db:"id"`
-	UserName     string    `json:"user_name" db:"username"`
-	Email        string    `json:"email" db:"email"`
-	PasswordHash string    `json:"password_hash" db:"password_hash"`
-	Create_at    time.Time `json:"create_at" db:"create_at"`
-	Update_at    time.Time `json:"update_at" db:"update_at"`
+	ID              uuid.UUID      `json:"id" db:"id"`
+	UserName        string         `json:"user_name" db:"username"`
+	Email           string         `json:"email" db:"email"`
+	PasswordHash    string         `json:"password_hash" db:"password_hash"`
+	Roles           pq.StringArray `json:"roles" db:"roles"`
+	Scopes          pq.StringArray `json:"scopes" db:"scopes"`
+	TokenVersion    int64          `json:"token_version" db:"token_version"`         // claim ver, растет при смене пароля
+	EmailVerifiedAt *time.Time     `json:"email_verified_at" db:"email_verified_at"` // nil - email не подтвержден
+	Create_at       time.Time      `json:"create_at" db:"create_at"`
+	Update_at       time.Time      `json:"update_at" db:"update_at"`
 }
 
+// RefreshToken - сохраненный refresh токен. Все токены, полученные ротацией
+// из одного логина, принадлежат одному семейству (FamilyID).
 type RefreshToken struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	AuthId    uuid.UUID `json:"auth_id" db:"auth_id"`
-	Token     string    `json:"token" db:"token"`
-	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
-	CreateAt  time.Time `json:"create_at" db:"create_at"`
+	ID         uuid.UUID  `json:"id" db:"id"`
+	AuthId     uuid.UUID  `json:"auth_id" db:"auth_id"`
+	FamilyID   uuid.UUID  `json:"family_id" db:"family_id"`
+	TokenHash  string     `json:"-" db:"token_hash"`                // SHA-256 от токена
+	JKT        string     `json:"jkt" db:"jkt"`                     // thumbprint DPoP ключа, к которому привязано семейство
+	Version    int64      `json:"token_version" db:"token_version"` // версия токенов пользователя на момент выдачи
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	CreateAt   time.Time  `json:"create_at" db:"create_at"`
+	UsedAt     *time.Time `json:"used_at" db:"used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	ReplacedBy *uuid.UUID `json:"replaced_by" db:"replaced_by"`
+}
+
+// SigningKeyState - стадия жизненного цикла ключа подписи
+type SigningKeyState string
+
+const (
+	SigningKeyNext     SigningKeyState = "next"     // опубликован в JWKS, но еще не подписывает
+	SigningKeyActive   SigningKeyState = "active"   // подписывает новые токены
+	SigningKeyRetiring SigningKeyState = "retiring" // только проверка в течение grace периода
+	SigningKeyRevoked  SigningKeyState = "revoked"  // больше не принимается
+)
+
+type SigningKey struct {
+	ID          string          `json:"id" db:"id"` // kid
+	Version     int64           `json:"version" db:"version"`
+	Algorithm   string          `json:"algorithm" db:"algorithm"`
+	PrivateKey  string          `json:"-" db:"private_key"` // PEM (PKCS#8)
+	State       SigningKeyState `json:"state" db:"state"`
+	CreateAt    time.Time       `json:"create_at" db:"create_at"`
+	ActivatedAt *time.Time      `json:"activated_at" db:"activated_at"`
+	RetiredAt   *time.Time      `json:"retired_at" db:"retired_at"`
+	RevokedAt   *time.Time      `json:"revoked_at" db:"revoked_at"`
+}
+
+// PasswordHistoryEntry - прежний хеш пароля, по нему запрещается повтор пароля
+type PasswordHistoryEntry struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	UserID       uuid.UUID `json:"user_id" db:"user_id"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	CreateAt     time.Time `json:"create_at" db:"create_at"`
+}
+
+// PasswordResetToken - одноразовый токен сброса пароля; хранится только хеш
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"` // SHA-256 от токена
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreateAt  time.Time  `json:"create_at" db:"create_at"`
+}
+
+// EmailVerificationToken - одноразовый токен подтверждения email; хранится только хеш
+type EmailVerificationToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"` // SHA-256 от токена
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreateAt  time.Time  `json:"create_at" db:"create_at"`
+}
+
+// OutboxMessage - письмо в очереди на отправку
+type OutboxMessage struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	Recipient     string     `json:"recipient" db:"recipient"`
+	Subject       string     `json:"subject" db:"subject"`
+	Body          string     `json:"-" db:"body"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     *string    `json:"last_error" db:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at" db:"sent_at"`
+	FailedAt      *time.Time `json:"failed_at" db:"failed_at"`
+	CreateAt      time.Time  `json:"create_at" db:"create_at"`
+}
+
+// TOTPFactor - TOTP второй фактор пользователя
+type TOTPFactor struct {
+	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
+	Secret       string     `json:"-" db:"secret"` // зашифрованный base32 секрет
+	ConfirmedAt  *time.Time `json:"confirmed_at" db:"confirmed_at"`
+	LastUsedStep int64      `json:"last_used_step" db:"last_used_step"`
+	CreateAt     time.Time  `json:"create_at" db:"create_at"`
+	UpdateAt     time.Time  `json:"update_at" db:"update_at"`
+}
+
+// MFAChallenge - незавершенный вход, ожидающий второй фактор
+type MFAChallenge struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	JKT       string     `json:"jkt" db:"jkt"`
+	Attempts  int        `json:"attempts" db:"attempts"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreateAt  time.Time  `json:"create_at" db:"create_at"`
+}
+
+// WebAuthnCredential - passkey или ключ безопасности пользователя
+type WebAuthnCredential struct {
+	ID           uuid.UUID      `json:"id" db:"id"`
+	UserID       uuid.UUID      `json:"user_id" db:"user_id"`
+	CredentialID []byte         `json:"credential_id" db:"credential_id"`
+	PublicKey    []byte         `json:"-" db:"public_key"` // COSE_Key
+	SignCount    int64          `json:"sign_count" db:"sign_count"`
+	Transports   pq.StringArray `json:"transports" db:"transports"`
+	Name         string         `json:"name" db:"name"`
+	CreateAt     time.Time      `json:"create_at" db:"create_at"`
+	LastUsedAt   *time.Time     `json:"last_used_at" db:"last_used_at"`
+}
+
+// Назначение церемонии WebAuthn
+const (
+	WebAuthnRegistration = "registration"
+	WebAuthnLogin        = "login"
+)
+
+// WebAuthnSession - выданный challenge, ожидающий ответа аутентификатора
+type WebAuthnSession struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    *uuid.UUID `json:"user_id" db:"user_id"` // nil для входа без указания пользователя
+	TokenHash string     `json:"-" db:"token_hash"`
+	Challenge []byte     `json:"-" db:"challenge"`
+	Purpose   string     `json:"purpose" db:"purpose"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	CreateAt  time.Time  `json:"create_at" db:"create_at"`
+}
+
+// Типы событий журнала безопасности
+const (
+	AuditRecoveryCodeUsed         = "mfa.recovery_code_used"
+	AuditRecoveryCodesRegenerated = "mfa.recovery_codes_regenerated"
+	AuditAccountUnlocked          = "account.unlocked"
+)
+
+// AuditEvent - запись журнала событий безопасности
+type AuditEvent struct {
+	ID        uuid.UUID         `json:"id" db:"id"`
+	UserID    *uuid.UUID        `json:"user_id" db:"user_id"`
+	EventType string            `json:"event_type" db:"event_type"`
+	Details   map[string]string `json:"details" db:"-"`
+	CreateAt  time.Time         `json:"create_at" db:"create_at"`
+}
+
+// LoginAttempts - неудачные попытки входа по аккаунту или IP адресу
+type LoginAttempts struct {
+	Key           string     `json:"key" db:"key"`
+	Failures      int        `json:"failures" db:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until" db:"locked_until"`
+}
+
+// LoginCode - одноразовый код и ссылка для входа без пароля
+type LoginCode struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"` // SHA-256 от токена из ссылки
+	CodeHash  string     `json:"-" db:"code_hash"`  // SHA-256 от кода вместе с id пользователя
+	Attempts  int        `json:"attempts" db:"attempts"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreateAt  time.Time  `json:"create_at" db:"create_at"`
 }
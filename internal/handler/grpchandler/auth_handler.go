@@ -2,34 +2,54 @@ package grpchandler
 
 import (
 	"context"
+	"crypto/subtle"
+	"strings"
 
 	"auth-service/internal/logger"
 	"auth-service/internal/service"
 	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
 
-
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
 type authHandler struct {
 	pb.UnimplementedAuthServiceServer
 	authService service.AuthService
-	log         logger.Logger
+	keyService  service.KeyService
+	exchange    service.TokenExchangeService
+	// trustForwardedFor - сервис за прокси, IP клиента берется из x-forwarded-for
+	trustForwardedFor bool
+	// apiKey - ключ resource серверов для Introspect; пустой - RPC выключен, как и HTTP endpoint
+	apiKey string
+	log    logger.Logger
 }
 
-func NewAuthHandler(authService service.AuthService, log logger.Logger) *authHandler {
+func NewAuthHandler(
+	authService service.AuthService,
+	keyService service.KeyService,
+	exchange service.TokenExchangeService,
+	trustForwardedFor bool,
+	apiKey string,
+	log logger.Logger,
+) *authHandler {
 	return &authHandler{
-		authService: authService,
-		log:         log,
+		authService:       authService,
+		keyService:        keyService,
+		exchange:          exchange,
+		trustForwardedFor: trustForwardedFor,
+		apiKey:            apiKey,
+		log:               log,
 	}
 }
 
 func (h *authHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	ctx = service.WithClientIP(ctx, clientIP(ctx, h.trustForwardedFor))
 	resp, err := h.authService.Login(ctx, req)
 
 	if err != nil {
-		return nil, err
+		return nil, h.fail(err, "Login failed")
 	}
 
 	return resp, nil
@@ -40,18 +60,218 @@ func (h *authHandler) Register(ctx context.Context, req *pb.RegisterRequest) (*p
 	res, err := h.authService.Register(ctx, req)
 
 	if err != nil {
-		h.log.Error("Registration failed") // Только логируем
-		return nil, err
+		return nil, h.fail(err, "Registration failed")
 	}
 	return res, nil
 }
 
 func (h *authHandler) ValidateToken(ctx context.Context, req *pb.TokenRequest) (*pb.TokenResponse, error) {
-	// TODO: реализация валидации токена
-	return nil, status.Error(codes.Unimplemented, "method ValidateToken not implemented")
+	resp, err := h.authService.ValidateToken(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Token validation failed")
+	}
+	return resp, nil
 }
 
 func (h *authHandler) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.LoginResponse, error) {
-	// TODO: реализация обновления токена
-	return nil, status.Error(codes.Unimplemented, "method RefreshToken not implemented")
+	resp, err := h.authService.RefreshToken(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Refresh token failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*pb.RevokeTokenResponse, error) {
+	resp, err := h.authService.RevokeToken(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Token revocation failed")
+	}
+	return resp, nil
+}
+
+// Introspect раскрывает данные токенов, поэтому доступен только с API ключом (authorization: Bearer <API_KEY>)
+func (h *authHandler) Introspect(ctx context.Context, req *pb.IntrospectRequest) (*pb.IntrospectResponse, error) {
+	if h.apiKey == "" {
+		return nil, status.Error(codes.Unimplemented, "token introspection is disabled")
+	}
+	if !h.authorizedAPIKey(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "invalid api key")
+	}
+
+	resp, err := h.authService.Introspect(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Token introspection failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) ExchangeToken(ctx context.Context, req *pb.TokenExchangeRequest) (*pb.TokenExchangeResponse, error) {
+	resp, err := h.exchange.ExchangeToken(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Token exchange failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+	ctx = service.WithClientIP(ctx, clientIP(ctx, h.trustForwardedFor))
+	resp, err := h.authService.ChangePassword(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Password change failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.RequestPasswordResetResponse, error) {
+	resp, err := h.authService.RequestPasswordReset(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Password reset request failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordResponse, error) {
+	resp, err := h.authService.ResetPassword(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Password reset failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerifyEmailResponse, error) {
+	resp, err := h.authService.VerifyEmail(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Email verification failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) ResendVerification(ctx context.Context, req *pb.ResendVerificationRequest) (*pb.ResendVerificationResponse, error) {
+	resp, err := h.authService.ResendVerification(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Email verification resend failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) EnrollTOTP(ctx context.Context, req *pb.EnrollTOTPRequest) (*pb.EnrollTOTPResponse, error) {
+	resp, err := h.authService.EnrollTOTP(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "TOTP enrollment failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) ConfirmTOTP(ctx context.Context, req *pb.ConfirmTOTPRequest) (*pb.ConfirmTOTPResponse, error) {
+	ctx = service.WithClientIP(ctx, clientIP(ctx, h.trustForwardedFor))
+	resp, err := h.authService.ConfirmTOTP(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "TOTP confirmation failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) VerifyMFA(ctx context.Context, req *pb.VerifyMFARequest) (*pb.LoginResponse, error) {
+	ctx = service.WithClientIP(ctx, clientIP(ctx, h.trustForwardedFor))
+	resp, err := h.authService.VerifyMFA(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "MFA verification failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) RegenerateRecoveryCodes(ctx context.Context, req *pb.RegenerateRecoveryCodesRequest) (*pb.RegenerateRecoveryCodesResponse, error) {
+	ctx = service.WithClientIP(ctx, clientIP(ctx, h.trustForwardedFor))
+	resp, err := h.authService.RegenerateRecoveryCodes(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Recovery codes regeneration failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) UnlockAccount(ctx context.Context, req *pb.UnlockAccountRequest) (*pb.UnlockAccountResponse, error) {
+	resp, err := h.authService.UnlockAccount(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Account unlock failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) RequestLoginCode(ctx context.Context, req *pb.RequestLoginCodeRequest) (*pb.RequestLoginCodeResponse, error) {
+	resp, err := h.authService.RequestLoginCode(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Login code request failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) VerifyLoginCode(ctx context.Context, req *pb.VerifyLoginCodeRequest) (*pb.LoginResponse, error) {
+	ctx = service.WithClientIP(ctx, clientIP(ctx, h.trustForwardedFor))
+	resp, err := h.authService.VerifyLoginCode(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "Login code verification failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) BeginWebAuthnRegistration(ctx context.Context, req *pb.BeginWebAuthnRegistrationRequest) (*pb.BeginWebAuthnRegistrationResponse, error) {
+	resp, err := h.authService.BeginWebAuthnRegistration(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "WebAuthn registration start failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) FinishWebAuthnRegistration(ctx context.Context, req *pb.FinishWebAuthnRegistrationRequest) (*pb.FinishWebAuthnRegistrationResponse, error) {
+	resp, err := h.authService.FinishWebAuthnRegistration(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "WebAuthn registration failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) BeginWebAuthnLogin(ctx context.Context, req *pb.BeginWebAuthnLoginRequest) (*pb.BeginWebAuthnLoginResponse, error) {
+	resp, err := h.authService.BeginWebAuthnLogin(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "WebAuthn login start failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) FinishWebAuthnLogin(ctx context.Context, req *pb.FinishWebAuthnLoginRequest) (*pb.LoginResponse, error) {
+	resp, err := h.authService.FinishWebAuthnLogin(ctx, req)
+	if err != nil {
+		return nil, h.fail(err, "WebAuthn login failed")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*pb.GetJWKSResponse, error) {
+	resp, err := h.keyService.GetJWKS(ctx, req)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to load verification keys")
+	}
+	return resp, nil
+}
+
+func (h *authHandler) authorizedAPIKey(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, value := range md.Get("authorization") {
+		if key, ok := strings.CutPrefix(value, "Bearer "); ok && subtle.ConstantTimeCompare([]byte(key), []byte(h.apiKey)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// fail переводит ошибку сервиса в gRPC статус; неизвестные ошибки логируются, а клиент получает Internal
+func (h *authHandler) fail(err error, msg string) error {
+	st, known := toStatus(err)
+	if !known {
+		h.log.Error(msg, logger.F("error", err))
+	}
+	return st
 }
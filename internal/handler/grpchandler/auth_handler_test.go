@@ -0,0 +1,114 @@
+package grpchandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"auth-service/internal/logger"
+	"auth-service/internal/service"
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// stubAuthService - основа тестовых сервисов: RPC не реализованы, фоновых операций нет
+type stubAuthService struct {
+	pb.UnimplementedAuthServiceServer
+}
+
+func (stubAuthService) Close(ctx context.Context) error { return nil }
+
+// introspectingService отвечает на Introspect активным токеном
+type introspectingService struct {
+	stubAuthService
+}
+
+func (introspectingService) Introspect(ctx context.Context, req *pb.IntrospectRequest) (*pb.IntrospectResponse, error) {
+	return &pb.IntrospectResponse{Active: true}, nil
+}
+
+func TestAuthHandler_IntrospectRequiresAPIKey(t *testing.T) {
+	log, err := logger.New("error")
+	if err != nil {
+		t.Fatalf("logger.New: %v", err)
+	}
+	withKey := func(key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+key))
+	}
+
+	disabled := NewAuthHandler(introspectingService{}, nil, nil, false, "", log)
+	if _, err := disabled.Introspect(withKey(""), &pb.IntrospectRequest{Token: "t"}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("without API_KEY: code = %v, want Unimplemented", status.Code(err))
+	}
+
+	h := NewAuthHandler(introspectingService{}, nil, nil, false, "resource-key", log)
+	if _, err := h.Introspect(context.Background(), &pb.IntrospectRequest{Token: "t"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("no key: code = %v, want Unauthenticated", status.Code(err))
+	}
+	if _, err := h.Introspect(withKey("wrong"), &pb.IntrospectRequest{Token: "t"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("wrong key: code = %v, want Unauthenticated", status.Code(err))
+	}
+	resp, err := h.Introspect(withKey("resource-key"), &pb.IntrospectRequest{Token: "t"})
+	if err != nil || !resp.Active {
+		t.Errorf("valid key: %+v, %v", resp, err)
+	}
+}
+
+// failingService возвращает из Login внутреннюю ошибку, а из Register - занятый email
+type failingService struct {
+	stubAuthService
+}
+
+func (failingService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	return nil, errors.New("dial tcp 10.0.0.5:5432: connection refused")
+}
+
+func (failingService) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	return nil, fmt.Errorf("register: %w", service.ErrUserAlreadyExists)
+}
+
+func TestAuthHandler_LoginAndRegisterMapErrors(t *testing.T) {
+	log, err := logger.New("error")
+	if err != nil {
+		t.Fatalf("logger.New: %v", err)
+	}
+	h := NewAuthHandler(failingService{}, nil, nil, false, "", log)
+
+	// Внутренняя ошибка не уходит клиенту как есть
+	_, err = h.Login(context.Background(), &pb.LoginRequest{Email: "alice@example.com", Password: "secret"})
+	if st := status.Convert(err); st.Code() != codes.Internal || strings.Contains(st.Message(), "10.0.0.5") {
+		t.Errorf("Login: code = %v, message = %q; want Internal without details", st.Code(), st.Message())
+	}
+
+	_, err = h.Register(context.Background(), &pb.RegisterRequest{Email: "alice@example.com"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Register: code = %v, want AlreadyExists", status.Code(err))
+	}
+}
+
+// unknownEmailService отвечает на Login как сервис без HardenedErrors для неизвестного email
+type unknownEmailService struct {
+	stubAuthService
+}
+
+func (unknownEmailService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	return nil, service.ErrUserNotFound
+}
+
+func TestAuthHandler_LoginUnknownEmailWithoutHardenedErrors(t *testing.T) {
+	log, err := logger.New("error")
+	if err != nil {
+		t.Fatalf("logger.New: %v", err)
+	}
+	h := NewAuthHandler(unknownEmailService{}, nil, nil, false, "", log)
+
+	_, err = h.Login(context.Background(), &pb.LoginRequest{Email: "nobody@example.com", Password: "secret"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Login: code = %v, want NotFound", status.Code(err))
+	}
+}
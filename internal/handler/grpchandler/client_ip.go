@@ -0,0 +1,50 @@
+package grpchandler
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// clientIP - адрес клиента для защиты входа. x-forwarded-for и x-real-ip учитываются только
+// за доверенным прокси: иначе клиент подставит в заголовок любой адрес и обойдет лимит по IP.
+func clientIP(ctx context.Context, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			// Берется последний адрес цепочки - его дописал доверенный прокси. Начало цепочки
+			// присылает сам клиент, и новый адрес в каждом запросе обходил бы лимит по IP.
+			if ip := lastForwardedFor(md.Get("x-forwarded-for")); ip != "" {
+				return ip
+			}
+			if values := md.Get("x-real-ip"); len(values) > 0 && values[0] != "" {
+				return strings.TrimSpace(values[0])
+			}
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// lastForwardedFor - последний непустой адрес из всех заголовков x-forwarded-for
+func lastForwardedFor(values []string) string {
+	for i := len(values) - 1; i >= 0; i-- {
+		hops := strings.Split(values[i], ",")
+		for j := len(hops) - 1; j >= 0; j-- {
+			if ip := strings.TrimSpace(hops[j]); ip != "" {
+				return ip
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,45 @@
+package grpchandler
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestClientIP(t *testing.T) {
+	proxy := &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 443}}
+
+	tests := []struct {
+		name    string
+		trust   bool
+		headers []string
+		want    string
+	}{
+		{"peer address", false, nil, "10.0.0.1"},
+		{"untrusted header ignored", false, []string{"x-forwarded-for", "203.0.113.7"}, "10.0.0.1"},
+		{"single hop", true, []string{"x-forwarded-for", "203.0.113.7"}, "203.0.113.7"},
+		{"spoofed first hop", true, []string{"x-forwarded-for", "198.51.100.99, 203.0.113.7"}, "203.0.113.7"},
+		{"spoofed header before proxy header", true, []string{"x-forwarded-for", "198.51.100.99", "x-forwarded-for", "203.0.113.7"}, "203.0.113.7"},
+		{"x-real-ip fallback", true, []string{"x-real-ip", "203.0.113.8"}, "203.0.113.8"},
+	}
+
+	for _, tt := range tests {
+		ctx := peer.NewContext(context.Background(), proxy)
+		if tt.headers != nil {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(tt.headers...))
+		}
+		if got := clientIP(ctx, tt.trust); got != tt.want {
+			t.Errorf("%s: clientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Разные подставленные клиентом адреса не меняют ключ защиты входа
+	first := metadata.NewIncomingContext(peer.NewContext(context.Background(), proxy), metadata.Pairs("x-forwarded-for", "1.1.1.1, 203.0.113.7"))
+	second := metadata.NewIncomingContext(peer.NewContext(context.Background(), proxy), metadata.Pairs("x-forwarded-for", "2.2.2.2, 203.0.113.7"))
+	if clientIP(first, true) != clientIP(second, true) {
+		t.Error("spoofed first hop changed client ip")
+	}
+}
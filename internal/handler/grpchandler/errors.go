@@ -0,0 +1,190 @@
+package grpchandler
+
+import (
+	"auth-service/internal/service"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain - домен в ErrorInfo, по нему клиенты отличают наши причины ошибок
+const errorDomain = "auth-service"
+
+// Причины ошибок (ErrorInfo.Reason)
+const (
+	reasonRefreshTokenInvalid = "REFRESH_TOKEN_INVALID"
+	reasonRefreshTokenExpired = "REFRESH_TOKEN_EXPIRED"
+	reasonRefreshTokenRevoked = "REFRESH_TOKEN_REVOKED"
+	reasonRefreshTokenReused  = "REFRESH_TOKEN_REUSED"
+	reasonDPoPProofInvalid    = "INVALID_DPOP_PROOF"
+	reasonDPoPProofRequired   = "DPOP_PROOF_REQUIRED"
+	reasonInvalidClient       = "INVALID_CLIENT"
+	reasonInvalidGrant        = "INVALID_GRANT"
+	reasonInvalidTarget       = "INVALID_TARGET"
+	reasonInvalidScope        = "INVALID_SCOPE"
+	reasonUnsupportedToken    = "UNSUPPORTED_TOKEN_TYPE"
+	reasonPasswordPolicy      = "PASSWORD_POLICY_VIOLATION"
+	reasonInvalidCredentials  = "INVALID_CREDENTIALS"
+	reasonAccessTokenInvalid  = "ACCESS_TOKEN_INVALID"
+	reasonAccessTokenExpired  = "ACCESS_TOKEN_EXPIRED"
+	reasonAccessTokenRevoked  = "ACCESS_TOKEN_REVOKED"
+	reasonResetTokenInvalid   = "RESET_TOKEN_INVALID"
+	reasonVerificationInvalid = "VERIFICATION_TOKEN_INVALID"
+	reasonEmailNotVerified    = "EMAIL_NOT_VERIFIED"
+	reasonMFAAlreadyEnabled   = "MFA_ALREADY_ENABLED"
+	reasonMFANotEnrolled      = "MFA_NOT_ENROLLED"
+	reasonMFACodeInvalid      = "MFA_CODE_INVALID"
+	reasonMFAChallenge        = "MFA_TOKEN_INVALID"
+	reasonWebAuthnDisabled    = "WEBAUTHN_DISABLED"
+	reasonWebAuthnSession     = "WEBAUTHN_SESSION_INVALID"
+	reasonWebAuthnResponse    = "WEBAUTHN_RESPONSE_INVALID"
+	reasonWebAuthnExists      = "WEBAUTHN_CREDENTIAL_EXISTS"
+	reasonWebAuthnSignCount   = "WEBAUTHN_SIGN_COUNT_REGRESSION"
+	reasonLoginThrottled      = "LOGIN_THROTTLED"
+	reasonAccountLocked       = "ACCOUNT_LOCKED"
+	reasonAdminRequired       = "ADMIN_REQUIRED"
+	reasonLoginCodeInvalid    = "LOGIN_CODE_INVALID"
+	reasonUserAlreadyExists   = "USER_ALREADY_EXISTS"
+	reasonUserNotFound        = "USER_NOT_FOUND"
+)
+
+// toStatus переводит ошибки сервисного слоя в gRPC статусы.
+// ok = false означает, что ошибка неизвестна и ее нужно залогировать как внутреннюю.
+func toStatus(err error) (error, bool) {
+	var policyErr *service.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		return passwordPolicyStatus(policyErr), true
+	}
+	var retryErr *service.RetryAfterError
+	if errors.As(err, &retryErr) {
+		return retryAfterStatus(retryErr), true
+	}
+
+	switch {
+	case errors.Is(err, service.ErrBadRequest):
+		return status.Error(codes.InvalidArgument, "bad request"), true
+	case errors.Is(err, service.ErrRefreshTokenInvalid):
+		return statusWithReason(codes.InvalidArgument, "invalid refresh token", reasonRefreshTokenInvalid), true
+	case errors.Is(err, service.ErrRefreshTokenExpired):
+		return statusWithReason(codes.Unauthenticated, "refresh token has expired", reasonRefreshTokenExpired), true
+	case errors.Is(err, service.ErrRefreshTokenRevoked):
+		return statusWithReason(codes.PermissionDenied, "refresh token has been revoked", reasonRefreshTokenRevoked), true
+	case errors.Is(err, service.ErrRefreshTokenReused):
+		return statusWithReason(codes.FailedPrecondition, "refresh token reuse detected, please log in again", reasonRefreshTokenReused), true
+	case errors.Is(err, service.ErrDPoPProofInvalid):
+		return statusWithReason(codes.InvalidArgument, "invalid DPoP proof", reasonDPoPProofInvalid), true
+	case errors.Is(err, service.ErrDPoPProofRequired):
+		return statusWithReason(codes.Unauthenticated, "DPoP proof is required for this token", reasonDPoPProofRequired), true
+	case errors.Is(err, service.ErrUserAlreadyExists):
+		return statusWithReason(codes.AlreadyExists, "user already exists", reasonUserAlreadyExists), true
+	case errors.Is(err, service.ErrUserNotFound):
+		// Только без HardenedErrors: в этом режиме сервис сам отличает неизвестный email
+		return statusWithReason(codes.NotFound, "user not found", reasonUserNotFound), true
+	case errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, service.ErrPasswordBad):
+		return statusWithReason(codes.Unauthenticated, "invalid credentials", reasonInvalidCredentials), true
+	case errors.Is(err, service.ErrAccessTokenInvalid):
+		return statusWithReason(codes.Unauthenticated, "invalid access token", reasonAccessTokenInvalid), true
+	case errors.Is(err, service.ErrAccessTokenExpired):
+		return statusWithReason(codes.Unauthenticated, "access token has expired", reasonAccessTokenExpired), true
+	case errors.Is(err, service.ErrAccessTokenRevoked):
+		return statusWithReason(codes.Unauthenticated, "access token has been revoked", reasonAccessTokenRevoked), true
+	case errors.Is(err, service.ErrResetTokenInvalid):
+		return statusWithReason(codes.InvalidArgument, "password reset link is invalid or has expired", reasonResetTokenInvalid), true
+	case errors.Is(err, service.ErrVerificationTokenInvalid):
+		return statusWithReason(codes.InvalidArgument, "email verification link is invalid or has expired", reasonVerificationInvalid), true
+	case errors.Is(err, service.ErrEmailNotVerified):
+		return statusWithReason(codes.FailedPrecondition, "email address is not verified", reasonEmailNotVerified), true
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		return statusWithReason(codes.FailedPrecondition, "mfa is already enabled", reasonMFAAlreadyEnabled), true
+	case errors.Is(err, service.ErrMFANotEnrolled):
+		return statusWithReason(codes.FailedPrecondition, "mfa enrollment was not started", reasonMFANotEnrolled), true
+	case errors.Is(err, service.ErrMFACodeInvalid):
+		return statusWithReason(codes.Unauthenticated, "invalid mfa code", reasonMFACodeInvalid), true
+	case errors.Is(err, service.ErrMFAChallengeInvalid):
+		return statusWithReason(codes.Unauthenticated, "mfa token is invalid or has expired, please log in again", reasonMFAChallenge), true
+	case errors.Is(err, service.ErrWebAuthnDisabled):
+		return statusWithReason(codes.FailedPrecondition, "webauthn is not enabled", reasonWebAuthnDisabled), true
+	case errors.Is(err, service.ErrWebAuthnSessionInvalid):
+		return statusWithReason(codes.InvalidArgument, "webauthn session is invalid or has expired", reasonWebAuthnSession), true
+	case errors.Is(err, service.ErrWebAuthnResponseInvalid):
+		return statusWithReason(codes.Unauthenticated, "authenticator response was rejected", reasonWebAuthnResponse), true
+	case errors.Is(err, service.ErrWebAuthnCredentialExists):
+		return statusWithReason(codes.AlreadyExists, "credential is already registered", reasonWebAuthnExists), true
+	case errors.Is(err, service.ErrWebAuthnSignCount):
+		return statusWithReason(codes.PermissionDenied, "authenticator counter regressed, credential may be cloned", reasonWebAuthnSignCount), true
+	case errors.Is(err, service.ErrAdminRequired):
+		return statusWithReason(codes.PermissionDenied, "administrator role is required", reasonAdminRequired), true
+	case errors.Is(err, service.ErrLoginCodeInvalid):
+		return statusWithReason(codes.Unauthenticated, "login code is invalid or has expired", reasonLoginCodeInvalid), true
+	case errors.Is(err, service.ErrExchangeInvalidClient):
+		return statusWithReason(codes.Unauthenticated, "client authentication failed", reasonInvalidClient), true
+	case errors.Is(err, service.ErrExchangeInvalidGrant):
+		return statusWithReason(codes.InvalidArgument, "invalid subject token", reasonInvalidGrant), true
+	case errors.Is(err, service.ErrExchangeInvalidTarget):
+		return statusWithReason(codes.PermissionDenied, "requested audience is not allowed", reasonInvalidTarget), true
+	case errors.Is(err, service.ErrExchangeInvalidScope):
+		return statusWithReason(codes.PermissionDenied, "requested scope is not allowed", reasonInvalidScope), true
+	case errors.Is(err, service.ErrExchangeUnsupportedTokenType):
+		return statusWithReason(codes.InvalidArgument, "unsupported token type", reasonUnsupportedToken), true
+	case errors.Is(err, service.ErrBadToken):
+		// Сервис уже залогировал причину, повторно не логируем
+		return status.Error(codes.Internal, "failed to issue tokens"), true
+	default:
+		return status.Error(codes.Internal, "internal error"), false
+	}
+}
+
+func statusWithReason(code codes.Code, msg, reason string) error {
+	st := status.New(code, msg)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: errorDomain,
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// retryAfterStatus - ResourceExhausted с RetryInfo: клиент знает, когда повторить вход
+func retryAfterStatus(err *service.RetryAfterError) error {
+	msg, reason := "too many failed login attempts, try again later", reasonLoginThrottled
+	if errors.Is(err, service.ErrAccountLocked) {
+		msg, reason = "account is temporarily locked after failed login attempts", reasonAccountLocked
+	}
+
+	st := status.New(codes.ResourceExhausted, msg)
+	detailed, detailErr := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(err.RetryAfter)},
+	)
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// passwordPolicyStatus - InvalidArgument с BadRequest: по FieldViolation на каждое нарушение
+func passwordPolicyStatus(err *service.PasswordPolicyError) error {
+	badRequest := &errdetails.BadRequest{}
+	for _, violation := range err.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       violation.Field,
+			Description: violation.Message,
+			Reason:      violation.Code,
+		})
+	}
+
+	st := status.New(codes.InvalidArgument, "password does not meet the password policy")
+	detailed, detailsErr := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: reasonPasswordPolicy, Domain: errorDomain},
+		badRequest,
+	)
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,120 @@
+package grpchandler
+
+import (
+	"auth-service/internal/service"
+	"auth-service/internal/util/password"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatus_RefreshTokenErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{service.ErrRefreshTokenExpired, codes.Unauthenticated, reasonRefreshTokenExpired},
+		{service.ErrRefreshTokenRevoked, codes.PermissionDenied, reasonRefreshTokenRevoked},
+		{service.ErrRefreshTokenReused, codes.FailedPrecondition, reasonRefreshTokenReused},
+		{fmt.Errorf("wrapped: %w", service.ErrRefreshTokenInvalid), codes.InvalidArgument, reasonRefreshTokenInvalid},
+	}
+
+	for _, tt := range tests {
+		err, known := toStatus(tt.err)
+		if !known {
+			t.Errorf("%v: reported as unknown", tt.err)
+		}
+
+		st := status.Convert(err)
+		if st.Code() != tt.code {
+			t.Errorf("%v: code = %v, want %v", tt.err, st.Code(), tt.code)
+		}
+
+		var reason string
+		for _, detail := range st.Details() {
+			if info, ok := detail.(*errdetails.ErrorInfo); ok {
+				reason = info.Reason
+			}
+		}
+		if reason != tt.reason {
+			t.Errorf("%v: reason = %q, want %q", tt.err, reason, tt.reason)
+		}
+	}
+
+	// Ошибки Login без HardenedErrors - ожидаемые ответы, а не внутренние сбои
+	for _, loginErr := range []error{service.ErrUserNotFound, service.ErrPasswordBad, service.ErrBadToken} {
+		if _, known := toStatus(loginErr); !known {
+			t.Errorf("%v: reported as unknown", loginErr)
+		}
+	}
+	if err, _ := toStatus(service.ErrPasswordBad); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("ErrPasswordBad: code = %v, want Unauthenticated", status.Code(err))
+	}
+
+	if _, known := toStatus(fmt.Errorf("db is down")); known {
+		t.Error("unexpected error reported as known")
+	}
+}
+
+func TestToStatus_PasswordPolicyViolations(t *testing.T) {
+	err, known := toStatus(fmt.Errorf("register: %w", &service.PasswordPolicyError{Violations: []password.Violation{
+		{Field: "password", Code: password.ViolationTooShort, Message: "password must be at least 8 characters"},
+		{Field: "password", Code: password.ViolationBreached, Message: "password has appeared in a data breach"},
+	}}))
+	if !known {
+		t.Fatal("policy error reported as unknown")
+	}
+
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument", st.Code())
+	}
+
+	var reasons []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.FieldViolations {
+				if violation.Field != "password" || violation.Description == "" {
+					t.Errorf("incomplete field violation %+v", violation)
+				}
+				reasons = append(reasons, violation.Reason)
+			}
+		}
+	}
+	if len(reasons) != 2 || reasons[0] != password.ViolationTooShort || reasons[1] != password.ViolationBreached {
+		t.Errorf("field violation reasons = %v", reasons)
+	}
+}
+
+func TestToStatus_RetryAfter(t *testing.T) {
+	err, known := toStatus(fmt.Errorf("login: %w", &service.RetryAfterError{Err: service.ErrAccountLocked, RetryAfter: 90 * time.Second}))
+	if !known {
+		t.Fatal("retry after error reported as unknown")
+	}
+
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Errorf("code = %v, want ResourceExhausted", st.Code())
+	}
+
+	var (
+		reason string
+		delay  time.Duration
+	)
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			reason = detail.Reason
+		case *errdetails.RetryInfo:
+			delay = detail.RetryDelay.AsDuration()
+		}
+	}
+	if reason != reasonAccountLocked || delay != 90*time.Second {
+		t.Errorf("reason = %q, retry delay = %v", reason, delay)
+	}
+}
@@ -0,0 +1,128 @@
+package httphandler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"auth-service/internal/logger"
+	"auth-service/internal/service"
+	"auth-service/internal/util/jwt"
+)
+
+// introspectionResponse - ответ introspection endpoint (RFC 7662, раздел 2.2)
+type introspectionResponse struct {
+	Active    bool       `json:"active"`
+	Scope     string     `json:"scope,omitempty"`
+	ClientID  string     `json:"client_id,omitempty"`
+	Username  string     `json:"username,omitempty"`
+	TokenType string     `json:"token_type,omitempty"`
+	Exp       int64      `json:"exp,omitempty"`
+	Iat       int64      `json:"iat,omitempty"`
+	Nbf       int64      `json:"nbf,omitempty"`
+	Sub       string     `json:"sub,omitempty"`
+	Aud       []string   `json:"aud,omitempty"`
+	Iss       string     `json:"iss,omitempty"`
+	Jti       string     `json:"jti,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	Roles     []string   `json:"roles,omitempty"`
+	Act       *jwt.Actor `json:"act,omitempty"` // цепочка делегирования (RFC 8693)
+}
+
+type introspectionHandler struct {
+	tokens service.TokenService
+	apiKey string
+	log    logger.Logger
+}
+
+// NewIntrospectionHandler создает обработчик POST /oauth2/introspect.
+// Клиенты endpoint'а (resource servers) авторизуются API ключом: Authorization: Bearer <API_KEY>.
+func NewIntrospectionHandler(tokens service.TokenService, apiKey string, log logger.Logger) http.Handler {
+	return &introspectionHandler{
+		tokens: tokens,
+		apiKey: apiKey,
+		log:    log.With(logger.F("layer", "handler"), logger.F("component", "introspection_handler")),
+	}
+}
+
+func (h *introspectionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="introspection"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	result, err := h.tokens.Introspect(r.Context(), token, r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		h.log.Error("token introspection failed", logger.F("error", err))
+		writeOAuthError(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+
+	resp := introspectionResponse{Active: result.Active}
+	if result.Active {
+		claims := result.Claims
+		resp.Scope = claims.Scope
+		resp.Username = claims.Email
+		resp.TokenType = result.TokenType
+		resp.Sub = claims.Subject
+		resp.Aud = claims.Audience
+		resp.Iss = claims.Issuer
+		resp.Jti = claims.ID
+		resp.Email = claims.Email
+		resp.Roles = claims.Roles
+		if claims.Actor != nil {
+			resp.Act = claims.Actor
+			resp.ClientID = claims.Actor.Subject
+		}
+		if claims.ExpiresAt != nil {
+			resp.Exp = claims.ExpiresAt.Unix()
+		}
+		if claims.IssuedAt != nil {
+			resp.Iat = claims.IssuedAt.Unix()
+		}
+		if claims.NotBefore != nil {
+			resp.Nbf = claims.NotBefore.Unix()
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// authorized сверяет API ключ за постоянное время
+func (h *introspectionHandler) authorized(r *http.Request) bool {
+	if h.apiKey == "" {
+		return false
+	}
+
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(key), []byte(h.apiKey)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeOAuthError пишет ошибку в формате OAuth 2.0 (RFC 6749, раздел 5.2)
+func writeOAuthError(w http.ResponseWriter, code int, errorCode string) {
+	writeJSON(w, code, map[string]string{"error": errorCode})
+}
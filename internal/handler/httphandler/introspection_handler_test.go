@@ -0,0 +1,83 @@
+package httphandler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/logger"
+	"auth-service/internal/service"
+	"auth-service/internal/util/jwt"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// fakeTokenService отвечает на introspection только для одного токена
+type fakeTokenService struct {
+	service.TokenService
+	active string
+}
+
+func (f *fakeTokenService) Introspect(ctx context.Context, token, hint string) (*service.TokenIntrospection, error) {
+	if token != f.active {
+		return &service.TokenIntrospection{}, nil
+	}
+	return &service.TokenIntrospection{
+		Active:    true,
+		TokenType: service.TokenTypeAccess,
+		Claims: &jwt.Claims{
+			Email: "user@example.com",
+			Scope: "orders:read",
+			RegisteredClaims: gojwt.RegisteredClaims{
+				Subject:   "user-1",
+				ExpiresAt: gojwt.NewNumericDate(time.Unix(1700000000, 0)),
+			},
+		},
+	}, nil
+}
+
+func TestIntrospectionHandler(t *testing.T) {
+	log, err := logger.New("error")
+	if err != nil {
+		t.Fatalf("logger.New: %v", err)
+	}
+
+	router := NewRouter(http.NotFoundHandler(), NewIntrospectionHandler(&fakeTokenService{active: "good"}, "secret", log))
+
+	introspect := func(apiKey, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/oauth2/introspect", strings.NewReader(url.Values{"token": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := introspect("wrong", "good"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong api key: status = %d, want 401", rec.Code)
+	}
+
+	rec := introspect("secret", "good")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body["active"] != true || body["sub"] != "user-1" || body["scope"] != "orders:read" || body["exp"] != float64(1700000000) {
+		t.Errorf("active token response = %v", body)
+	}
+
+	rec = introspect("secret", "bad")
+	if strings.TrimSpace(rec.Body.String()) != `{"active":false}` {
+		t.Errorf("inactive token response = %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,75 @@
+package httphandler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"auth-service/internal/logger"
+	"auth-service/internal/util/jwt"
+)
+
+type jwksHandler struct {
+	keys   jwt.KeySetProvider
+	maxAge time.Duration
+	log    logger.Logger
+}
+
+// NewJWKSHandler создает обработчик /.well-known/jwks.json.
+// maxAge должен быть меньше интервала между публикацией нового ключа и началом подписи им.
+func NewJWKSHandler(keys jwt.KeySetProvider, maxAge time.Duration, log logger.Logger) http.Handler {
+	return &jwksHandler{
+		keys:   keys,
+		maxAge: maxAge,
+		log:    log.With(logger.F("layer", "handler"), logger.F("component", "jwks_handler")),
+	}
+}
+
+func (h *jwksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	set, err := h.keys.JWKS()
+	if err != nil {
+		h.log.Error("failed to build jwks", logger.F("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(set)
+	if err != nil {
+		h.log.Error("failed to encode jwks", logger.F("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	// ETag меняется при каждой ротации, поэтому клиенты могут дешево перепроверять набор
+	sum := sha256.Sum256(body)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, must-revalidate", int(h.maxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+// matchETag проверяет заголовок If-None-Match (список или "*")
+func matchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"auth-service/internal/logger"
+	"auth-service/internal/util/jwt"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	log, err := logger.New("error")
+	if err != nil {
+		t.Fatalf("logger.New: %v", err)
+	}
+
+	key, err := jwt.GenerateSigningKey("key-1", jwt.AlgorithmES256)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	manager := jwt.NewManager(jwt.Config{SigningKey: key})
+	router := NewRouter(NewJWKSHandler(manager, time.Minute, log), nil)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=60, must-revalidate" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	var set jwt.JWKS
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].Kid != "key-1" || set.Keys[0].Kty != "EC" || set.Keys[0].Crv != "P-256" {
+		t.Errorf("keys = %+v", set.Keys)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional request status = %d, want 304", rec.Code)
+	}
+}
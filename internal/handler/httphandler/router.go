@@ -0,0 +1,15 @@
+package httphandler
+
+import (
+	"net/http"
+)
+
+// NewRouter собирает HTTP маршруты сервиса. introspection может быть nil (endpoint выключен).
+func NewRouter(jwks, introspection http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /.well-known/jwks.json", jwks)
+	if introspection != nil {
+		mux.Handle("POST /oauth2/introspect", introspection)
+	}
+	return mux
+}
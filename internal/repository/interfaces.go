@@ -4,11 +4,45 @@ import (
 	"auth-service/internal/domain"
 	"context"
 	"errors"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 var (
 	ErrUserExists = errors.New("User Exists exception")
 	ErrNotFound   = errors.New("User Not Found exception")
+
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenUsed     = errors.New("refresh token already used")
+
+	ErrSigningKeyExists = errors.New("signing key already exists")
+	ErrRotationConflict = errors.New("signing keys were rotated concurrently")
+
+	ErrResetTokenNotFound = errors.New("password reset token not found")
+	ErrResetTokenUsed     = errors.New("password reset token already used")
+	ErrResetTokenCooldown = errors.New("password reset token was issued recently")
+
+	ErrVerificationTokenNotFound = errors.New("email verification token not found")
+	ErrVerificationTokenUsed     = errors.New("email verification token already used")
+
+	ErrMFANotFound         = errors.New("mfa factor not found")
+	ErrMFAEnabled          = errors.New("mfa factor already enabled")
+	ErrTOTPStepUsed        = errors.New("totp code already used")
+	ErrChallengeNotFound   = errors.New("mfa challenge not found")
+	ErrChallengeUsed       = errors.New("mfa challenge already used")
+	ErrRecoveryCodeInvalid = errors.New("recovery code not found or already used")
+
+	ErrLoginAttemptsNotFound = errors.New("no failed login attempts")
+
+	ErrLoginCodeNotFound = errors.New("login code not found")
+	ErrLoginCodeUsed     = errors.New("login code already used")
+	ErrLoginCodeCooldown = errors.New("login code was issued recently")
+
+	ErrWebAuthnCredentialExists   = errors.New("webauthn credential already registered")
+	ErrWebAuthnCredentialNotFound = errors.New("webauthn credential not found")
+	ErrWebAuthnSignCount          = errors.New("webauthn sign count did not increase")
+	ErrWebAuthnSessionNotFound    = errors.New("webauthn session not found")
 )
 
 type UserRepository interface {
@@ -18,12 +52,188 @@ type UserRepository interface {
 	GetByEmail(ctx context.Context, email string) (*domain.User, error)
 	Update(ctx context.Context, user *domain.User) error
 	Delete(ctx context.Context, id string) error
+	// UpdatePasswordHash заменяет хеш пароля (смена пароля или перехеширование)
+	UpdatePasswordHash(ctx context.Context, id uuid.UUID, passwordHash string) error
+	// IncrementTokenVersion увеличивает версию токенов пользователя и возвращает новую
+	IncrementTokenVersion(ctx context.Context, id uuid.UUID) (int64, error)
+	// GetTokenVersion возвращает текущую версию токенов, ErrNotFound если пользователя нет
+	GetTokenVersion(ctx context.Context, id string) (int64, error)
+	// MarkEmailVerified отмечает email подтвержденным
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error
 
 	// TODO дальше query реализовать
 }
 
 type RefreshTokenRepository interface {
-	Create(ctx context.Context, refreshtoken *domain.RefreshToken)
-	GetByToken(ctx context.Context, token string) (*domain.RefreshToken, error)
-	DeleteByToken(ctx context.Context, token string) error
+	Create(ctx context.Context, refreshtoken *domain.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error)
+	// Rotate атомарно помечает usedID использованным и сохраняет next.
+	// ErrRefreshTokenUsed, если токен уже был использован или отозван.
+	Rotate(ctx context.Context, usedID uuid.UUID, next *domain.RefreshToken, at time.Time) error
+	// RevokeFamily отзывает все токены семейства
+	RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error
+	// RevokeByUser отзывает все семейства пользователя
+	RevokeByUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+	// IsFamilyRevoked сообщает, отозвано ли семейство (сессия)
+	IsFamilyRevoked(ctx context.Context, familyID uuid.UUID) (bool, error)
+}
+
+// SigningKeyRepository хранит версионированные ключи подписи токенов
+type SigningKeyRepository interface {
+	// Create сохраняет новый ключ, ErrSigningKeyExists если active/next уже есть
+	Create(ctx context.Context, key *domain.SigningKey) error
+	// ListValid возвращает все ключи, кроме отозванных
+	ListValid(ctx context.Context) ([]*domain.SigningKey, error)
+	// Rotate атомарно: active -> retiring, next -> active, next = новый ключ.
+	// Если активный ключ уже не activeID - ErrRotationConflict.
+	Rotate(ctx context.Context, activeID string, next *domain.SigningKey, now time.Time) error
+	// RevokeRetired отзывает retiring ключи, выведенные раньше before
+	RevokeRetired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// TokenRevocationRepository - denylist отозванных access токенов по jti.
+// Запись нужна только до exp токена.
+type TokenRevocationRepository interface {
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OpaqueTokenRepository хранит claims opaque токенов по хешу токена
+type OpaqueTokenRepository interface {
+	Save(ctx context.Context, tokenHash string, data []byte, expiresAt time.Time) error
+	// Load возвращает ok = false, если токен не найден
+	Load(ctx context.Context, tokenHash string) (data []byte, ok bool, err error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PasswordHistoryRepository хранит прежние хеши паролей пользователей
+type PasswordHistoryRepository interface {
+	// Add сохраняет запись и оставляет у пользователя не больше keep последних записей
+	Add(ctx context.Context, entry *domain.PasswordHistoryEntry, keep int) error
+	// ListRecent возвращает до limit последних хешей пользователя, новые первыми
+	ListRecent(ctx context.Context, userID uuid.UUID, limit int) ([]string, error)
+	// DeleteOlderThan удаляет записи, сделанные раньше before (срок хранения)
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PasswordResetRepository хранит одноразовые токены сброса пароля по хешу токена
+type PasswordResetRepository interface {
+	// Issue атомарно сохраняет новый токен и помечает прежние использованными.
+	// ErrResetTokenCooldown, если пользователю уже выдан токен позже notBefore
+	Issue(ctx context.Context, token *domain.PasswordResetToken, notBefore time.Time) error
+	// GetByHash возвращает ErrResetTokenNotFound, если токена нет
+	GetByHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error)
+	// MarkUsed атомарно помечает токен использованным, ErrResetTokenUsed если он уже использован
+	MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+	// InvalidateUser помечает использованными все неиспользованные токены пользователя
+	InvalidateUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// EmailVerificationRepository хранит одноразовые токены подтверждения email по хешу токена
+type EmailVerificationRepository interface {
+	Create(ctx context.Context, token *domain.EmailVerificationToken) error
+	// GetByHash возвращает ErrVerificationTokenNotFound, если токена нет
+	GetByHash(ctx context.Context, tokenHash string) (*domain.EmailVerificationToken, error)
+	// MarkUsed атомарно помечает токен использованным, ErrVerificationTokenUsed если он уже использован
+	MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+	// InvalidateUser помечает использованными все неиспользованные токены пользователя
+	InvalidateUser(ctx context.Context, userID uuid.UUID, at time.Time) error
+	// LastIssuedAt - когда пользователю последний раз выдан токен, nil если ни разу
+	LastIssuedAt(ctx context.Context, userID uuid.UUID) (*time.Time, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// MailOutboxRepository - очередь исходящих писем
+type MailOutboxRepository interface {
+	Enqueue(ctx context.Context, msg *domain.OutboxMessage) error
+	// ClaimPending забирает до limit писем, чья попытка наступила к now, увеличивает attempts
+	// и откладывает их на lease, чтобы параллельные экземпляры не отправили письмо дважды
+	ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.OutboxMessage, error)
+	// MarkSent отмечает отправку и стирает тело письма
+	MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error
+	// MarkFailed сохраняет ошибку; nextAttemptAt = nil - попытки исчерпаны
+	MarkFailed(ctx context.Context, id uuid.UUID, lastError string, nextAttemptAt *time.Time, at time.Time) error
+}
+
+// MFARepository хранит вторые факторы пользователей и незавершенные входы
+type MFARepository interface {
+	// GetTOTP возвращает ErrMFANotFound, если фактор не заводился
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*domain.TOTPFactor, error)
+	// SaveTOTP заменяет неподтвержденный фактор новым секретом; подтвержденный не трогает (ErrMFAEnabled)
+	SaveTOTP(ctx context.Context, factor *domain.TOTPFactor) error
+	// ConfirmTOTP включает фактор и запоминает шаг первого кода
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, at time.Time) error
+	// UseTOTPStep атомарно сдвигает последний принятый шаг, ErrTOTPStepUsed если шаг не новее
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error
+
+	CreateChallenge(ctx context.Context, challenge *domain.MFAChallenge) error
+	// GetChallenge возвращает ErrChallengeNotFound, если токена нет
+	GetChallenge(ctx context.Context, tokenHash string) (*domain.MFAChallenge, error)
+	// RecordChallengeFailure увеличивает счетчик неверных кодов и возвращает его
+	RecordChallengeFailure(ctx context.Context, id uuid.UUID) (int, error)
+	// CompleteChallenge атомарно помечает вход завершенным, ErrChallengeUsed если уже завершен
+	CompleteChallenge(ctx context.Context, id uuid.UUID, at time.Time) error
+	DeleteExpiredChallenges(ctx context.Context, before time.Time) (int64, error)
+
+	// ReplaceRecoveryCodes удаляет прежний набор кодов восстановления и сохраняет новый
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string, at time.Time) error
+	// UseRecoveryCode атомарно гасит код, ErrRecoveryCodeInvalid если его нет или он использован
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) error
+	// CountRecoveryCodes - число неиспользованных кодов
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// WebAuthnRepository хранит учетные данные WebAuthn и незавершенные церемонии
+type WebAuthnRepository interface {
+	// CreateCredential возвращает ErrWebAuthnCredentialExists, если credential id уже зарегистрирован
+	CreateCredential(ctx context.Context, credential *domain.WebAuthnCredential) error
+	ListCredentials(ctx context.Context, userID uuid.UUID) ([]*domain.WebAuthnCredential, error)
+	// GetCredential возвращает ErrWebAuthnCredentialNotFound, если учетных данных нет
+	GetCredential(ctx context.Context, credentialID []byte) (*domain.WebAuthnCredential, error)
+	// UpdateSignCount атомарно сдвигает счетчик подписей, ErrWebAuthnSignCount если сохраненный не меньше
+	// (оба нуля допустимы - аутентификатор без счетчика)
+	UpdateSignCount(ctx context.Context, id uuid.UUID, signCount int64, at time.Time) error
+
+	CreateSession(ctx context.Context, session *domain.WebAuthnSession) error
+	// ConsumeSession удаляет и возвращает неистекшую сессию, ErrWebAuthnSessionNotFound если ее нет
+	ConsumeSession(ctx context.Context, tokenHash string, now time.Time) (*domain.WebAuthnSession, error)
+	DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AuditRepository - журнал событий безопасности
+type AuditRepository interface {
+	Record(ctx context.Context, event *domain.AuditEvent) error
+	// ListByUser возвращает последние события пользователя, новые первыми
+	ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.AuditEvent, error)
+}
+
+// LoginAttemptRepository хранит неудачные попытки входа по ключу (аккаунт или IP)
+type LoginAttemptRepository interface {
+	// Get возвращает ErrLoginAttemptsNotFound, если неудач не было
+	Get(ctx context.Context, key string) (*domain.LoginAttempts, error)
+	// RecordFailure атомарно учитывает неудачу и возвращает их число; неудачи старше window забываются
+	RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (int, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset забывает неудачи и снимает блокировку
+	Reset(ctx context.Context, key string) error
+	// DeleteStale удаляет записи без неудач и блокировок позже before
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+}
+
+// LoginCodeRepository хранит одноразовые коды и ссылки для входа без пароля
+type LoginCodeRepository interface {
+	// Issue атомарно сохраняет новый код и помечает прежние использованными.
+	// ErrLoginCodeCooldown, если пользователю уже выдан код позже notBefore
+	Issue(ctx context.Context, code *domain.LoginCode, notBefore time.Time) error
+	// GetByTokenHash ищет код по токену из ссылки, ErrLoginCodeNotFound если его нет
+	GetByTokenHash(ctx context.Context, tokenHash string) (*domain.LoginCode, error)
+	// GetLatest возвращает последний выданный пользователю код, ErrLoginCodeNotFound если их не было
+	GetLatest(ctx context.Context, userID uuid.UUID) (*domain.LoginCode, error)
+	// RecordFailure увеличивает счетчик неверных кодов и возвращает его
+	RecordFailure(ctx context.Context, id uuid.UUID) (int, error)
+	// MarkUsed атомарно помечает код использованным, ErrLoginCodeUsed если он уже использован
+	MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
 }
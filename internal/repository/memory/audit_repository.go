@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type auditRepository struct {
+	mu     sync.Mutex
+	events []*domain.AuditEvent
+}
+
+func NewAuditRepository() repository.AuditRepository {
+	return &auditRepository{}
+}
+
+func (r *auditRepository) Record(ctx context.Context, event *domain.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *event
+	stored.Details = maps.Clone(event.Details)
+	r.events = append(r.events, &stored)
+	return nil
+}
+
+func (r *auditRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []*domain.AuditEvent
+	for i := len(r.events) - 1; i >= 0 && len(events) < limit; i-- {
+		if event := r.events[i]; event.UserID != nil && *event.UserID == userID {
+			copied := *event
+			events = append(events, &copied)
+		}
+	}
+	return events, nil
+}
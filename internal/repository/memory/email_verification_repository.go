@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type emailVerificationRepository struct {
+	mu     sync.Mutex
+	tokens map[uuid.UUID]*domain.EmailVerificationToken
+}
+
+func NewEmailVerificationRepository() repository.EmailVerificationRepository {
+	return &emailVerificationRepository{
+		tokens: make(map[uuid.UUID]*domain.EmailVerificationToken),
+	}
+}
+
+func (r *emailVerificationRepository) Create(ctx context.Context, token *domain.EmailVerificationToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *token
+	r.tokens[token.ID] = &stored
+	return nil
+}
+
+func (r *emailVerificationRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.EmailVerificationToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, repository.ErrVerificationTokenNotFound
+}
+
+func (r *emailVerificationRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil {
+		return repository.ErrVerificationTokenUsed
+	}
+	token.UsedAt = &at
+	return nil
+}
+
+func (r *emailVerificationRepository) InvalidateUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *emailVerificationRepository) LastIssuedAt(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var last *time.Time
+	for _, token := range r.tokens {
+		if token.UserID == userID && (last == nil || token.CreateAt.After(*last)) {
+			createAt := token.CreateAt
+			last = &createAt
+		}
+	}
+	return last, nil
+}
+
+func (r *emailVerificationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, token := range r.tokens {
+		if token.ExpiresAt.Before(before) {
+			delete(r.tokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
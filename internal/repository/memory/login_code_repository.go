@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type loginCodeRepository struct {
+	mu    sync.Mutex
+	codes map[uuid.UUID]*domain.LoginCode
+}
+
+func NewLoginCodeRepository() repository.LoginCodeRepository {
+	return &loginCodeRepository{
+		codes: make(map[uuid.UUID]*domain.LoginCode),
+	}
+}
+
+func (r *loginCodeRepository) Issue(ctx context.Context, code *domain.LoginCode, notBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.codes {
+		if existing.UserID == code.UserID && existing.CreateAt.After(notBefore) {
+			return repository.ErrLoginCodeCooldown
+		}
+	}
+	for _, existing := range r.codes {
+		if existing.UserID == code.UserID && existing.UsedAt == nil {
+			usedAt := code.CreateAt
+			existing.UsedAt = &usedAt
+		}
+	}
+
+	stored := *code
+	r.codes[code.ID] = &stored
+	return nil
+}
+
+func (r *loginCodeRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*domain.LoginCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, code := range r.codes {
+		if code.TokenHash == tokenHash {
+			found := *code
+			return &found, nil
+		}
+	}
+	return nil, repository.ErrLoginCodeNotFound
+}
+
+func (r *loginCodeRepository) GetLatest(ctx context.Context, userID uuid.UUID) (*domain.LoginCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var latest *domain.LoginCode
+	for _, code := range r.codes {
+		if code.UserID == userID && (latest == nil || code.CreateAt.After(latest.CreateAt)) {
+			latest = code
+		}
+	}
+	if latest == nil {
+		return nil, repository.ErrLoginCodeNotFound
+	}
+	found := *latest
+	return &found, nil
+}
+
+func (r *loginCodeRepository) RecordFailure(ctx context.Context, id uuid.UUID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code, ok := r.codes[id]
+	if !ok {
+		return 0, repository.ErrLoginCodeNotFound
+	}
+	code.Attempts++
+	return code.Attempts, nil
+}
+
+func (r *loginCodeRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code, ok := r.codes[id]
+	if !ok || code.UsedAt != nil {
+		return repository.ErrLoginCodeUsed
+	}
+	code.UsedAt = &at
+	return nil
+}
+
+func (r *loginCodeRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, code := range r.codes {
+		if code.ExpiresAt.Before(before) {
+			delete(r.codes, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type mailOutboxRepository struct {
+	mu       sync.Mutex
+	messages map[uuid.UUID]*domain.OutboxMessage
+}
+
+// NewMailOutboxRepository - очередь писем в памяти (один экземпляр сервиса, тесты)
+func NewMailOutboxRepository() repository.MailOutboxRepository {
+	return &mailOutboxRepository{
+		messages: make(map[uuid.UUID]*domain.OutboxMessage),
+	}
+}
+
+func (r *mailOutboxRepository) Enqueue(ctx context.Context, msg *domain.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *msg
+	r.messages[msg.ID] = &stored
+	return nil
+}
+
+func (r *mailOutboxRepository) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending []*domain.OutboxMessage
+	for _, msg := range r.messages {
+		if msg.SentAt == nil && msg.FailedAt == nil && !msg.NextAttemptAt.After(now) {
+			pending = append(pending, msg)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].NextAttemptAt.Before(pending[j].NextAttemptAt)
+	})
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	claimed := make([]*domain.OutboxMessage, 0, len(pending))
+	for _, msg := range pending {
+		msg.Attempts++
+		msg.NextAttemptAt = now.Add(lease)
+		copied := *msg
+		claimed = append(claimed, &copied)
+	}
+	return claimed, nil
+}
+
+func (r *mailOutboxRepository) MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if msg, ok := r.messages[id]; ok {
+		msg.SentAt = &at
+		msg.Body = ""
+		msg.LastError = nil
+	}
+	return nil
+}
+
+func (r *mailOutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, lastError string, nextAttemptAt *time.Time, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	msg, ok := r.messages[id]
+	if !ok {
+		return nil
+	}
+	msg.LastError = &lastError
+	if nextAttemptAt != nil {
+		msg.NextAttemptAt = *nextAttemptAt
+	} else {
+		msg.FailedAt = &at
+		msg.Body = ""
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type mfaRepository struct {
+	mu         sync.Mutex
+	totp       map[uuid.UUID]*domain.TOTPFactor
+	challenges map[uuid.UUID]*domain.MFAChallenge
+	recovery   map[uuid.UUID]map[string]*time.Time // user_id -> code_hash -> used_at
+}
+
+func NewMFARepository() repository.MFARepository {
+	return &mfaRepository{
+		totp:       make(map[uuid.UUID]*domain.TOTPFactor),
+		challenges: make(map[uuid.UUID]*domain.MFAChallenge),
+		recovery:   make(map[uuid.UUID]map[string]*time.Time),
+	}
+}
+
+func (r *mfaRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*domain.TOTPFactor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	factor, ok := r.totp[userID]
+	if !ok {
+		return nil, repository.ErrMFANotFound
+	}
+	copied := *factor
+	return &copied, nil
+}
+
+func (r *mfaRepository) SaveTOTP(ctx context.Context, factor *domain.TOTPFactor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.totp[factor.UserID]; ok && existing.ConfirmedAt != nil {
+		return repository.ErrMFAEnabled
+	}
+	r.totp[factor.UserID] = &domain.TOTPFactor{
+		UserID:   factor.UserID,
+		Secret:   factor.Secret,
+		CreateAt: factor.CreateAt,
+		UpdateAt: factor.CreateAt,
+	}
+	return nil
+}
+
+func (r *mfaRepository) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	factor, ok := r.totp[userID]
+	if !ok || factor.ConfirmedAt != nil {
+		return repository.ErrMFAEnabled
+	}
+	factor.ConfirmedAt = &at
+	factor.LastUsedStep = step
+	factor.UpdateAt = at
+	return nil
+}
+
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	factor, ok := r.totp[userID]
+	if !ok || factor.LastUsedStep >= step {
+		return repository.ErrTOTPStepUsed
+	}
+	factor.LastUsedStep = step
+	return nil
+}
+
+func (r *mfaRepository) CreateChallenge(ctx context.Context, challenge *domain.MFAChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *challenge
+	r.challenges[challenge.ID] = &stored
+	return nil
+}
+
+func (r *mfaRepository) GetChallenge(ctx context.Context, tokenHash string) (*domain.MFAChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, challenge := range r.challenges {
+		if challenge.TokenHash == tokenHash {
+			copied := *challenge
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrChallengeNotFound
+}
+
+func (r *mfaRepository) RecordChallengeFailure(ctx context.Context, id uuid.UUID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.challenges[id]
+	if !ok {
+		return 0, repository.ErrChallengeNotFound
+	}
+	challenge.Attempts++
+	return challenge.Attempts, nil
+}
+
+func (r *mfaRepository) CompleteChallenge(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.challenges[id]
+	if !ok || challenge.UsedAt != nil {
+		return repository.ErrChallengeUsed
+	}
+	challenge.UsedAt = &at
+	return nil
+}
+
+func (r *mfaRepository) DeleteExpiredChallenges(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, challenge := range r.challenges {
+		if challenge.ExpiresAt.Before(before) {
+			delete(r.challenges, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	codes := make(map[string]*time.Time, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes[hash] = nil
+	}
+	r.recovery[userID] = codes
+	return nil
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	usedAt, ok := r.recovery[userID][codeHash]
+	if !ok || usedAt != nil {
+		return repository.ErrRecoveryCodeInvalid
+	}
+	r.recovery[userID][codeHash] = &at
+	return nil
+}
+
+func (r *mfaRepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int
+	for _, usedAt := range r.recovery[userID] {
+		if usedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
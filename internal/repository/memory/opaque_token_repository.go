@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"auth-service/internal/repository"
+	"context"
+	"sync"
+	"time"
+)
+
+type opaqueToken struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+type opaqueTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]opaqueToken
+}
+
+func NewOpaqueTokenRepository() repository.OpaqueTokenRepository {
+	return &opaqueTokenRepository{
+		tokens: make(map[string]opaqueToken),
+	}
+}
+
+func (r *opaqueTokenRepository) Save(ctx context.Context, tokenHash string, data []byte, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.tokens[tokenHash] = opaqueToken{data: append([]byte(nil), data...), expiresAt: expiresAt}
+	return nil
+}
+
+func (r *opaqueTokenRepository) Load(ctx context.Context, tokenHash string) ([]byte, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenHash]
+	if !ok {
+		return nil, false, nil
+	}
+
+	return append([]byte(nil), token.data...), true, nil
+}
+
+func (r *opaqueTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for tokenHash, token := range r.tokens {
+		if !token.expiresAt.After(before) {
+			delete(r.tokens, tokenHash)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type passwordHistoryRepository struct {
+	mu      sync.Mutex
+	entries map[uuid.UUID][]domain.PasswordHistoryEntry // новые первыми
+}
+
+func NewPasswordHistoryRepository() repository.PasswordHistoryRepository {
+	return &passwordHistoryRepository{
+		entries: make(map[uuid.UUID][]domain.PasswordHistoryEntry),
+	}
+}
+
+func (r *passwordHistoryRepository) Add(ctx context.Context, entry *domain.PasswordHistoryEntry, keep int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := append([]domain.PasswordHistoryEntry{*entry}, r.entries[entry.UserID]...)
+	if len(entries) > keep {
+		entries = entries[:keep]
+	}
+	r.entries[entry.UserID] = entries
+	return nil
+}
+
+func (r *passwordHistoryRepository) ListRecent(ctx context.Context, userID uuid.UUID, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.entries[userID]
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	hashes := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		hashes = append(hashes, entry.PasswordHash)
+	}
+	return hashes, nil
+}
+
+func (r *passwordHistoryRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for userID, entries := range r.entries {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry.CreateAt.Before(before) {
+				deleted++
+				continue
+			}
+			kept = append(kept, entry)
+		}
+		r.entries[userID] = kept
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type passwordResetRepository struct {
+	mu     sync.Mutex
+	tokens map[uuid.UUID]*domain.PasswordResetToken
+}
+
+func NewPasswordResetRepository() repository.PasswordResetRepository {
+	return &passwordResetRepository{
+		tokens: make(map[uuid.UUID]*domain.PasswordResetToken),
+	}
+}
+
+func (r *passwordResetRepository) Issue(ctx context.Context, token *domain.PasswordResetToken, notBefore time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tokens {
+		if existing.UserID == token.UserID && existing.CreateAt.After(notBefore) {
+			return repository.ErrResetTokenCooldown
+		}
+	}
+	for _, existing := range r.tokens {
+		if existing.UserID == token.UserID && existing.UsedAt == nil {
+			usedAt := token.CreateAt
+			existing.UsedAt = &usedAt
+		}
+	}
+
+	stored := *token
+	r.tokens[token.ID] = &stored
+	return nil
+}
+
+func (r *passwordResetRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, repository.ErrResetTokenNotFound
+}
+
+func (r *passwordResetRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil {
+		return repository.ErrResetTokenUsed
+	}
+	token.UsedAt = &at
+	return nil
+}
+
+func (r *passwordResetRepository) InvalidateUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *passwordResetRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, token := range r.tokens {
+		if token.ExpiresAt.Before(before) {
+			delete(r.tokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
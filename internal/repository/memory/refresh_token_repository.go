@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type refreshTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[uuid.UUID]*domain.RefreshToken
+}
+
+func NewRefreshTokenRepository() repository.RefreshTokenRepository {
+	return &refreshTokenRepository{
+		tokens: make(map[uuid.UUID]*domain.RefreshToken),
+	}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *token
+	r.tokens[token.ID] = &stored
+	return nil
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+
+	return nil, repository.ErrRefreshTokenNotFound
+}
+
+func (r *refreshTokenRepository) Rotate(ctx context.Context, usedID uuid.UUID, next *domain.RefreshToken, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	used, ok := r.tokens[usedID]
+	if !ok || used.UsedAt != nil || used.RevokedAt != nil {
+		return repository.ErrRefreshTokenUsed
+	}
+
+	used.UsedAt = timePtr(at)
+	used.ReplacedBy = &next.ID
+
+	stored := *next
+	r.tokens[next.ID] = &stored
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = timePtr(at)
+		}
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeByUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.AuthId == userID && token.RevokedAt == nil {
+			token.RevokedAt = timePtr(at)
+		}
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) IsFamilyRevoked(ctx context.Context, familyID uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID && token.RevokedAt != nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// signingKeyRepository - хранилище ключей в памяти для одного инстанса и тестов
+type signingKeyRepository struct {
+	mu      sync.Mutex
+	keys    map[string]*domain.SigningKey
+	version int64
+}
+
+func NewSigningKeyRepository() repository.SigningKeyRepository {
+	return &signingKeyRepository{
+		keys: make(map[string]*domain.SigningKey),
+	}
+}
+
+func (r *signingKeyRepository) Create(ctx context.Context, key *domain.SigningKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[key.ID]; ok {
+		return repository.ErrSigningKeyExists
+	}
+	if key.State == domain.SigningKeyActive || key.State == domain.SigningKeyNext {
+		if r.findByState(key.State) != nil {
+			return repository.ErrSigningKeyExists
+		}
+	}
+
+	r.version++
+	key.Version = r.version
+
+	stored := *key
+	r.keys[key.ID] = &stored
+	return nil
+}
+
+func (r *signingKeyRepository) ListValid(ctx context.Context) ([]*domain.SigningKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make([]*domain.SigningKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		if key.State == domain.SigningKeyRevoked {
+			continue
+		}
+		copied := *key
+		keys = append(keys, &copied)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Version < keys[j].Version })
+	return keys, nil
+}
+
+func (r *signingKeyRepository) Rotate(ctx context.Context, activeID string, next *domain.SigningKey, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	active := r.findByState(domain.SigningKeyActive)
+	currentID := ""
+	if active != nil {
+		currentID = active.ID
+	}
+	if currentID != activeID {
+		return repository.ErrRotationConflict
+	}
+
+	promoted := r.findByState(domain.SigningKeyNext)
+	if promoted == nil {
+		return repository.ErrRotationConflict
+	}
+	if _, ok := r.keys[next.ID]; ok {
+		return repository.ErrSigningKeyExists
+	}
+
+	if active != nil {
+		active.State = domain.SigningKeyRetiring
+		active.RetiredAt = timePtr(now)
+	}
+	promoted.State = domain.SigningKeyActive
+	promoted.ActivatedAt = timePtr(now)
+
+	r.version++
+	next.Version = r.version
+	next.State = domain.SigningKeyNext
+
+	stored := *next
+	r.keys[next.ID] = &stored
+	return nil
+}
+
+func (r *signingKeyRepository) RevokeRetired(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var revoked int64
+	for _, key := range r.keys {
+		if key.State == domain.SigningKeyRetiring && key.RetiredAt != nil && !key.RetiredAt.After(before) {
+			key.State = domain.SigningKeyRevoked
+			key.RevokedAt = timePtr(time.Now())
+			revoked++
+		}
+	}
+
+	return revoked, nil
+}
+
+func (r *signingKeyRepository) findByState(state domain.SigningKeyState) *domain.SigningKey {
+	for _, key := range r.keys {
+		if key.State == state {
+			return key
+		}
+	}
+	return nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
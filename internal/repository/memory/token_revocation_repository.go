@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"auth-service/internal/repository"
+	"context"
+	"sync"
+	"time"
+)
+
+type tokenRevocationRepository struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time // jti -> exp
+	now     func() time.Time
+}
+
+func NewTokenRevocationRepository() repository.TokenRevocationRepository {
+	return &tokenRevocationRepository{
+		revoked: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (r *tokenRevocationRepository) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.revoked[tokenID] = expiresAt
+	return nil
+}
+
+func (r *tokenRevocationRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expiresAt, ok := r.revoked[tokenID]
+	if !ok {
+		return false, nil
+	}
+	if !expiresAt.After(r.now()) {
+		delete(r.revoked, tokenID)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (r *tokenRevocationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for tokenID, expiresAt := range r.revoked {
+		if !expiresAt.After(before) {
+			delete(r.revoked, tokenID)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
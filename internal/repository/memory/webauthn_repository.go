@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type webAuthnRepository struct {
+	mu          sync.Mutex
+	credentials map[uuid.UUID]*domain.WebAuthnCredential
+	sessions    map[string]*domain.WebAuthnSession // по token_hash
+}
+
+func NewWebAuthnRepository() repository.WebAuthnRepository {
+	return &webAuthnRepository{
+		credentials: make(map[uuid.UUID]*domain.WebAuthnCredential),
+		sessions:    make(map[string]*domain.WebAuthnSession),
+	}
+}
+
+func (r *webAuthnRepository) CreateCredential(ctx context.Context, credential *domain.WebAuthnCredential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.credentials {
+		if bytes.Equal(existing.CredentialID, credential.CredentialID) {
+			return repository.ErrWebAuthnCredentialExists
+		}
+	}
+	stored := *credential
+	r.credentials[credential.ID] = &stored
+	return nil
+}
+
+func (r *webAuthnRepository) ListCredentials(ctx context.Context, userID uuid.UUID) ([]*domain.WebAuthnCredential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var credentials []*domain.WebAuthnCredential
+	for _, credential := range r.credentials {
+		if credential.UserID == userID {
+			copied := *credential
+			credentials = append(credentials, &copied)
+		}
+	}
+	slices.SortFunc(credentials, func(a, b *domain.WebAuthnCredential) int {
+		return a.CreateAt.Compare(b.CreateAt)
+	})
+	return credentials, nil
+}
+
+func (r *webAuthnRepository) GetCredential(ctx context.Context, credentialID []byte) (*domain.WebAuthnCredential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, credential := range r.credentials {
+		if bytes.Equal(credential.CredentialID, credentialID) {
+			copied := *credential
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrWebAuthnCredentialNotFound
+}
+
+func (r *webAuthnRepository) UpdateSignCount(ctx context.Context, id uuid.UUID, signCount int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	credential, ok := r.credentials[id]
+	if !ok || (credential.SignCount >= signCount && (credential.SignCount != 0 || signCount != 0)) {
+		return repository.ErrWebAuthnSignCount
+	}
+	credential.SignCount = signCount
+	credential.LastUsedAt = &at
+	return nil
+}
+
+func (r *webAuthnRepository) CreateSession(ctx context.Context, session *domain.WebAuthnSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *session
+	r.sessions[session.TokenHash] = &stored
+	return nil
+}
+
+func (r *webAuthnRepository) ConsumeSession(ctx context.Context, tokenHash string, now time.Time) (*domain.WebAuthnSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[tokenHash]
+	if !ok || !session.ExpiresAt.After(now) {
+		return nil, repository.ErrWebAuthnSessionNotFound
+	}
+	delete(r.sessions, tokenHash)
+	return session, nil
+}
+
+func (r *webAuthnRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for hash, session := range r.sessions {
+		if session.ExpiresAt.Before(before) {
+			delete(r.sessions, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
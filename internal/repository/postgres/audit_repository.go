@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type auditRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewAuditRepository(db *sqlx.DB, log logger.Logger) repository.AuditRepository {
+	return &auditRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "audit_repository")),
+	}
+}
+
+// auditRow - details хранится в JSONB
+type auditRow struct {
+	ID        uuid.UUID  `db:"id"`
+	UserID    *uuid.UUID `db:"user_id"`
+	EventType string     `db:"event_type"`
+	Details   []byte     `db:"details"`
+	CreateAt  time.Time  `db:"create_at"`
+}
+
+func (r *auditRepository) Record(ctx context.Context, event *domain.AuditEvent) error {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return fmt.Errorf("marshal audit details: %w", err)
+	}
+	if event.Details == nil {
+		details = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO t_audit_events (id, user_id, event_type, details, create_at)
+			VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := r.db.ExecContext(ctx, query, event.ID, event.UserID, event.EventType, details, event.CreateAt); err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+
+	return nil
+}
+
+func (r *auditRepository) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]*domain.AuditEvent, error) {
+	query := `
+		SELECT id, user_id, event_type, details, create_at
+		FROM t_audit_events
+		WHERE user_id = $1
+		ORDER BY create_at DESC
+		LIMIT $2
+	`
+
+	var rows []auditRow
+	if err := r.db.SelectContext(ctx, &rows, query, userID, limit); err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+
+	events := make([]*domain.AuditEvent, 0, len(rows))
+	for _, row := range rows {
+		event := &domain.AuditEvent{ID: row.ID, UserID: row.UserID, EventType: row.EventType, CreateAt: row.CreateAt}
+		if err := json.Unmarshal(row.Details, &event.Details); err != nil {
+			return nil, fmt.Errorf("unmarshal audit details: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type emailVerificationRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewEmailVerificationRepository(db *sqlx.DB, log logger.Logger) repository.EmailVerificationRepository {
+	return &emailVerificationRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "email_verification_repository")),
+	}
+}
+
+func (r *emailVerificationRepository) Create(ctx context.Context, token *domain.EmailVerificationToken) error {
+	r.log.Debug("creating email verification token", logger.F("user_id", token.UserID))
+
+	query := `
+		INSERT INTO t_email_verification_tokens (id, user_id, token_hash, expires_at, create_at)
+			VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := r.db.ExecContext(ctx, query, token.ID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreateAt); err != nil {
+		return fmt.Errorf("create email verification token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *emailVerificationRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.EmailVerificationToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, create_at
+		FROM t_email_verification_tokens
+		WHERE token_hash = $1
+	`
+
+	var token domain.EmailVerificationToken
+	if err := r.db.GetContext(ctx, &token, query, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrVerificationTokenNotFound
+		}
+		return nil, fmt.Errorf("get email verification token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (r *emailVerificationRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	// Условие по used_at гарантирует, что из двух параллельных запросов пройдет только один
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE t_email_verification_tokens
+		SET used_at = $1
+		WHERE id = $2 AND used_at IS NULL`,
+		at, id,
+	)
+	if err != nil {
+		return fmt.Errorf("mark email verification token used: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrVerificationTokenUsed
+	}
+
+	return nil
+}
+
+func (r *emailVerificationRepository) InvalidateUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	r.log.Debug("invalidating email verification tokens of user", logger.F("user_id", userID))
+
+	query := `
+		UPDATE t_email_verification_tokens
+		SET used_at = $1
+		WHERE user_id = $2 AND used_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, at, userID); err != nil {
+		return fmt.Errorf("invalidate email verification tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *emailVerificationRepository) LastIssuedAt(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	query := `SELECT MAX(create_at) FROM t_email_verification_tokens WHERE user_id = $1`
+
+	var issuedAt sql.NullTime
+	if err := r.db.GetContext(ctx, &issuedAt, query, userID); err != nil {
+		return nil, fmt.Errorf("get last email verification token: %w", err)
+	}
+	if !issuedAt.Valid {
+		return nil, nil
+	}
+
+	return &issuedAt.Time, nil
+}
+
+func (r *emailVerificationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM t_email_verification_tokens WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired email verification tokens: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
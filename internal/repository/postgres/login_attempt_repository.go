@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type loginAttemptRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewLoginAttemptRepository(db *sqlx.DB, log logger.Logger) repository.LoginAttemptRepository {
+	return &loginAttemptRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "login_attempt_repository")),
+	}
+}
+
+func (r *loginAttemptRepository) Get(ctx context.Context, key string) (*domain.LoginAttempts, error) {
+	query := `
+		SELECT key, failures, last_failure_at, locked_until
+		FROM t_login_attempts
+		WHERE key = $1
+	`
+
+	var attempts domain.LoginAttempts
+	if err := r.db.GetContext(ctx, &attempts, query, key); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrLoginAttemptsNotFound
+		}
+		return nil, fmt.Errorf("get login attempts: %w", err)
+	}
+
+	return &attempts, nil
+}
+
+func (r *loginAttemptRepository) RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (int, error) {
+	// Счетчик начинается заново, если прошлая неудача вышла за окно
+	query := `
+		INSERT INTO t_login_attempts (key, failures, last_failure_at)
+			VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+			SET failures = CASE
+					WHEN t_login_attempts.last_failure_at < $3 THEN 1
+					ELSE t_login_attempts.failures + 1
+				END,
+				last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures
+	`
+
+	var failures int
+	if err := r.db.GetContext(ctx, &failures, query, key, at, at.Add(-window)); err != nil {
+		return 0, fmt.Errorf("record login failure: %w", err)
+	}
+
+	return failures, nil
+}
+
+func (r *loginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE t_login_attempts SET locked_until = $1 WHERE key = $2`, until, key); err != nil {
+		return fmt.Errorf("lock login key: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, key string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM t_login_attempts WHERE key = $1`, key); err != nil {
+		return fmt.Errorf("reset login attempts: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM t_login_attempts
+		WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < $1)`,
+		before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("delete stale login attempts: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// loginCodesLockID - пространство advisory lock для выдачи кодов, второй ключ - хеш пользователя
+const loginCodesLockID = 7_340_002
+
+type loginCodeRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewLoginCodeRepository(db *sqlx.DB, log logger.Logger) repository.LoginCodeRepository {
+	return &loginCodeRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "login_code_repository")),
+	}
+}
+
+func (r *loginCodeRepository) Issue(ctx context.Context, code *domain.LoginCode, notBefore time.Time) error {
+	r.log.Debug("issuing login code", logger.F("user_id", code.UserID))
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin login code issue: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Параллельные запросы одного пользователя выполняются по очереди: cooldown проверяет только один
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, loginCodesLockID, code.UserID.String()); err != nil {
+		return fmt.Errorf("lock login codes: %w", err)
+	}
+
+	var recent bool
+	if err := tx.GetContext(ctx, &recent, `
+		SELECT EXISTS (
+			SELECT 1 FROM t_login_codes WHERE user_id = $1 AND create_at > $2
+		)`, code.UserID, notBefore); err != nil {
+		return fmt.Errorf("check login code cooldown: %w", err)
+	}
+	if recent {
+		return repository.ErrLoginCodeCooldown
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE t_login_codes
+		SET used_at = $1
+		WHERE user_id = $2 AND used_at IS NULL`,
+		code.CreateAt, code.UserID,
+	); err != nil {
+		return fmt.Errorf("invalidate login codes: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO t_login_codes (id, user_id, token_hash, code_hash, expires_at, create_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+		code.ID, code.UserID, code.TokenHash, code.CodeHash, code.ExpiresAt, code.CreateAt,
+	); err != nil {
+		return fmt.Errorf("create login code: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit login code issue: %w", err)
+	}
+
+	return nil
+}
+
+func (r *loginCodeRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*domain.LoginCode, error) {
+	query := `
+		SELECT id, user_id, token_hash, code_hash, attempts, expires_at, used_at, create_at
+		FROM t_login_codes
+		WHERE token_hash = $1
+	`
+
+	var code domain.LoginCode
+	if err := r.db.GetContext(ctx, &code, query, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrLoginCodeNotFound
+		}
+		return nil, fmt.Errorf("get login code: %w", err)
+	}
+
+	return &code, nil
+}
+
+func (r *loginCodeRepository) GetLatest(ctx context.Context, userID uuid.UUID) (*domain.LoginCode, error) {
+	query := `
+		SELECT id, user_id, token_hash, code_hash, attempts, expires_at, used_at, create_at
+		FROM t_login_codes
+		WHERE user_id = $1
+		ORDER BY create_at DESC
+		LIMIT 1
+	`
+
+	var code domain.LoginCode
+	if err := r.db.GetContext(ctx, &code, query, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrLoginCodeNotFound
+		}
+		return nil, fmt.Errorf("get latest login code: %w", err)
+	}
+
+	return &code, nil
+}
+
+func (r *loginCodeRepository) RecordFailure(ctx context.Context, id uuid.UUID) (int, error) {
+	query := `
+		UPDATE t_login_codes
+		SET attempts = attempts + 1
+		WHERE id = $1
+		RETURNING attempts
+	`
+
+	var attempts int
+	if err := r.db.GetContext(ctx, &attempts, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, repository.ErrLoginCodeNotFound
+		}
+		return 0, fmt.Errorf("record login code failure: %w", err)
+	}
+
+	return attempts, nil
+}
+
+func (r *loginCodeRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	// Условие по used_at гарантирует, что из двух параллельных запросов пройдет только один
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE t_login_codes
+		SET used_at = $1
+		WHERE id = $2 AND used_at IS NULL`,
+		at, id,
+	)
+	if err != nil {
+		return fmt.Errorf("mark login code used: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrLoginCodeUsed
+	}
+
+	return nil
+}
+
+func (r *loginCodeRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM t_login_codes WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired login codes: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type mailOutboxRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewMailOutboxRepository(db *sqlx.DB, log logger.Logger) repository.MailOutboxRepository {
+	return &mailOutboxRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "mail_outbox_repository")),
+	}
+}
+
+func (r *mailOutboxRepository) Enqueue(ctx context.Context, msg *domain.OutboxMessage) error {
+	r.log.Debug("enqueueing mail", logger.F("message_id", msg.ID))
+
+	query := `
+		INSERT INTO t_mail_outbox (id, recipient, subject, body, next_attempt_at, create_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if _, err := r.db.ExecContext(ctx, query, msg.ID, msg.Recipient, msg.Subject, msg.Body, msg.NextAttemptAt, msg.CreateAt); err != nil {
+		return fmt.Errorf("enqueue mail: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mailOutboxRepository) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*domain.OutboxMessage, error) {
+	// SKIP LOCKED: параллельные экземпляры сервиса забирают разные письма
+	query := `
+		UPDATE t_mail_outbox
+		SET attempts = attempts + 1, next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM t_mail_outbox
+			WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, subject, body, attempts, last_error, next_attempt_at, sent_at, failed_at, create_at
+	`
+
+	var messages []*domain.OutboxMessage
+	if err := r.db.SelectContext(ctx, &messages, query, now, now.Add(lease), limit); err != nil {
+		return nil, fmt.Errorf("claim pending mail: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (r *mailOutboxRepository) MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `
+		UPDATE t_mail_outbox
+		SET sent_at = $1, body = '', last_error = NULL
+		WHERE id = $2
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, at, id); err != nil {
+		return fmt.Errorf("mark mail sent: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mailOutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, lastError string, nextAttemptAt *time.Time, at time.Time) error {
+	var query string
+	args := []any{lastError, id}
+	if nextAttemptAt != nil {
+		query = `UPDATE t_mail_outbox SET last_error = $1, next_attempt_at = $3 WHERE id = $2`
+		args = append(args, *nextAttemptAt)
+	} else {
+		// Письмо больше не отправим, тело с токенами хранить незачем
+		query = `UPDATE t_mail_outbox SET last_error = $1, failed_at = $3, body = '' WHERE id = $2`
+		args = append(args, at)
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("mark mail failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type mfaRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewMFARepository(db *sqlx.DB, log logger.Logger) repository.MFARepository {
+	return &mfaRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "mfa_repository")),
+	}
+}
+
+func (r *mfaRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*domain.TOTPFactor, error) {
+	query := `
+		SELECT user_id, secret, confirmed_at, last_used_step, create_at, update_at
+		FROM t_user_totp
+		WHERE user_id = $1
+	`
+
+	var factor domain.TOTPFactor
+	if err := r.db.GetContext(ctx, &factor, query, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrMFANotFound
+		}
+		return nil, fmt.Errorf("get totp factor: %w", err)
+	}
+
+	return &factor, nil
+}
+
+func (r *mfaRepository) SaveTOTP(ctx context.Context, factor *domain.TOTPFactor) error {
+	r.log.Debug("saving totp factor", logger.F("user_id", factor.UserID))
+
+	// Подтвержденный фактор перезаписать нельзя: WHERE отсекает обновление
+	query := `
+		INSERT INTO t_user_totp (user_id, secret, create_at, update_at)
+			VALUES ($1, $2, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE
+			SET secret = EXCLUDED.secret, last_used_step = 0, update_at = EXCLUDED.update_at
+			WHERE t_user_totp.confirmed_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, factor.UserID, factor.Secret, factor.CreateAt)
+	if err != nil {
+		return fmt.Errorf("save totp factor: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrMFAEnabled
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64, at time.Time) error {
+	r.log.Debug("confirming totp factor", logger.F("user_id", userID))
+
+	query := `
+		UPDATE t_user_totp
+		SET confirmed_at = $1, last_used_step = $2, update_at = $1
+		WHERE user_id = $3 AND confirmed_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, at, step, userID)
+	if err != nil {
+		return fmt.Errorf("confirm totp factor: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrMFAEnabled
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	// Из двух запросов с одним кодом пройдет только первый
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE t_user_totp
+		SET last_used_step = $1
+		WHERE user_id = $2 AND last_used_step < $1`,
+		step, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("use totp step: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrTOTPStepUsed
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) CreateChallenge(ctx context.Context, challenge *domain.MFAChallenge) error {
+	r.log.Debug("creating mfa challenge", logger.F("user_id", challenge.UserID))
+
+	query := `
+		INSERT INTO t_mfa_challenges (id, user_id, token_hash, jkt, expires_at, create_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if _, err := r.db.ExecContext(ctx, query,
+		challenge.ID,
+		challenge.UserID,
+		challenge.TokenHash,
+		challenge.JKT,
+		challenge.ExpiresAt,
+		challenge.CreateAt,
+	); err != nil {
+		return fmt.Errorf("create mfa challenge: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) GetChallenge(ctx context.Context, tokenHash string) (*domain.MFAChallenge, error) {
+	query := `
+		SELECT id, user_id, token_hash, jkt, attempts, expires_at, used_at, create_at
+		FROM t_mfa_challenges
+		WHERE token_hash = $1
+	`
+
+	var challenge domain.MFAChallenge
+	if err := r.db.GetContext(ctx, &challenge, query, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrChallengeNotFound
+		}
+		return nil, fmt.Errorf("get mfa challenge: %w", err)
+	}
+
+	return &challenge, nil
+}
+
+func (r *mfaRepository) RecordChallengeFailure(ctx context.Context, id uuid.UUID) (int, error) {
+	query := `
+		UPDATE t_mfa_challenges
+		SET attempts = attempts + 1
+		WHERE id = $1
+		RETURNING attempts
+	`
+
+	var attempts int
+	if err := r.db.GetContext(ctx, &attempts, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, repository.ErrChallengeNotFound
+		}
+		return 0, fmt.Errorf("record mfa challenge failure: %w", err)
+	}
+
+	return attempts, nil
+}
+
+func (r *mfaRepository) CompleteChallenge(ctx context.Context, id uuid.UUID, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE t_mfa_challenges
+		SET used_at = $1
+		WHERE id = $2 AND used_at IS NULL`,
+		at, id,
+	)
+	if err != nil {
+		return fmt.Errorf("complete mfa challenge: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrChallengeUsed
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) DeleteExpiredChallenges(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM t_mfa_challenges WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired mfa challenges: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string, at time.Time) error {
+	r.log.Debug("replacing recovery codes", logger.F("user_id", userID), logger.F("count", len(codeHashes)))
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin recovery codes update: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM t_mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("delete recovery codes: %w", err)
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO t_mfa_recovery_codes (id, user_id, code_hash, create_at)
+				VALUES ($1, $2, $3, $4)`,
+			uuid.New(), userID, hash, at,
+		); err != nil {
+			return fmt.Errorf("insert recovery code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit recovery codes update: %w", err)
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE t_mfa_recovery_codes
+		SET used_at = $1
+		WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL`,
+		at, userID, codeHash,
+	)
+	if err != nil {
+		return fmt.Errorf("use recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrRecoveryCodeInvalid
+	}
+
+	return nil
+}
+
+func (r *mfaRepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, `
+		SELECT COUNT(*) FROM t_mfa_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL`,
+		userID,
+	); err != nil {
+		return 0, fmt.Errorf("count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type opaqueTokenRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewOpaqueTokenRepository(db *sqlx.DB, log logger.Logger) repository.OpaqueTokenRepository {
+	return &opaqueTokenRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "opaque_token_repository")),
+	}
+}
+
+func (r *opaqueTokenRepository) Save(ctx context.Context, tokenHash string, data []byte, expiresAt time.Time) error {
+	query := `
+		INSERT INTO t_opaque_tokens (token_hash, data, expires_at)
+			VALUES ($1, $2, $3)`
+
+	if _, err := r.db.ExecContext(ctx, query, tokenHash, data, expiresAt); err != nil {
+		return fmt.Errorf("save opaque token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *opaqueTokenRepository) Load(ctx context.Context, tokenHash string) ([]byte, bool, error) {
+	var data []byte
+	err := r.db.GetContext(ctx, &data, `SELECT data FROM t_opaque_tokens WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("load opaque token: %w", err)
+	}
+
+	return data, true, nil
+}
+
+func (r *opaqueTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM t_opaque_tokens WHERE expires_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired opaque tokens: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type passwordHistoryRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewPasswordHistoryRepository(db *sqlx.DB, log logger.Logger) repository.PasswordHistoryRepository {
+	return &passwordHistoryRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "password_history_repository")),
+	}
+}
+
+func (r *passwordHistoryRepository) Add(ctx context.Context, entry *domain.PasswordHistoryEntry, keep int) error {
+	r.log.Debug("adding password history entry", logger.F("user_id", entry.UserID))
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin password history update: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO t_password_history (id, user_id, password_hash, create_at)
+			VALUES ($1, $2, $3, $4)`,
+		entry.ID, entry.UserID, entry.PasswordHash, entry.CreateAt,
+	); err != nil {
+		return fmt.Errorf("add password history entry: %w", err)
+	}
+
+	// Старше keep последних записей проверять не нужно
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM t_password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM t_password_history
+			WHERE user_id = $1
+			ORDER BY create_at DESC
+			LIMIT $2
+		)`,
+		entry.UserID, keep,
+	); err != nil {
+		return fmt.Errorf("trim password history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit password history update: %w", err)
+	}
+
+	return nil
+}
+
+func (r *passwordHistoryRepository) ListRecent(ctx context.Context, userID uuid.UUID, limit int) ([]string, error) {
+	query := `
+		SELECT password_hash
+		FROM t_password_history
+		WHERE user_id = $1
+		ORDER BY create_at DESC
+		LIMIT $2
+	`
+
+	var hashes []string
+	if err := r.db.SelectContext(ctx, &hashes, query, userID, limit); err != nil {
+		return nil, fmt.Errorf("list password history: %w", err)
+	}
+
+	return hashes, nil
+}
+
+func (r *passwordHistoryRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM t_password_history WHERE create_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete old password history: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
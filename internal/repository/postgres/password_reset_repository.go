@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// passwordResetLockID - пространство advisory lock для выдачи ссылок сброса, второй ключ - хеш пользователя
+const passwordResetLockID = 7_340_003
+
+type passwordResetRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewPasswordResetRepository(db *sqlx.DB, log logger.Logger) repository.PasswordResetRepository {
+	return &passwordResetRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "password_reset_repository")),
+	}
+}
+
+func (r *passwordResetRepository) Issue(ctx context.Context, token *domain.PasswordResetToken, notBefore time.Time) error {
+	r.log.Debug("issuing password reset token", logger.F("user_id", token.UserID))
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin password reset token issue: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Параллельные запросы одного пользователя выполняются по очереди: cooldown проверяет только один
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2))`, passwordResetLockID, token.UserID.String()); err != nil {
+		return fmt.Errorf("lock password reset tokens: %w", err)
+	}
+
+	var recent bool
+	if err := tx.GetContext(ctx, &recent, `
+		SELECT EXISTS (
+			SELECT 1 FROM t_password_reset_tokens WHERE user_id = $1 AND create_at > $2
+		)`, token.UserID, notBefore); err != nil {
+		return fmt.Errorf("check password reset cooldown: %w", err)
+	}
+	if recent {
+		return repository.ErrResetTokenCooldown
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE t_password_reset_tokens
+		SET used_at = $1
+		WHERE user_id = $2 AND used_at IS NULL`,
+		token.CreateAt, token.UserID,
+	); err != nil {
+		return fmt.Errorf("invalidate password reset tokens: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO t_password_reset_tokens (id, user_id, token_hash, expires_at, create_at)
+			VALUES ($1, $2, $3, $4, $5)`,
+		token.ID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreateAt,
+	); err != nil {
+		return fmt.Errorf("create password reset token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit password reset token issue: %w", err)
+	}
+
+	return nil
+}
+
+func (r *passwordResetRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, create_at
+		FROM t_password_reset_tokens
+		WHERE token_hash = $1
+	`
+
+	var token domain.PasswordResetToken
+	if err := r.db.GetContext(ctx, &token, query, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrResetTokenNotFound
+		}
+		return nil, fmt.Errorf("get password reset token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (r *passwordResetRepository) MarkUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	// Условие по used_at гарантирует, что из двух параллельных запросов пройдет только один
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE t_password_reset_tokens
+		SET used_at = $1
+		WHERE id = $2 AND used_at IS NULL`,
+		at, id,
+	)
+	if err != nil {
+		return fmt.Errorf("mark password reset token used: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrResetTokenUsed
+	}
+
+	return nil
+}
+
+func (r *passwordResetRepository) InvalidateUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	r.log.Debug("invalidating password reset tokens of user", logger.F("user_id", userID))
+
+	query := `
+		UPDATE t_password_reset_tokens
+		SET used_at = $1
+		WHERE user_id = $2 AND used_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, at, userID); err != nil {
+		return fmt.Errorf("invalidate password reset tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *passwordResetRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM t_password_reset_tokens WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired password reset tokens: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type refreshTokenRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewRefreshTokenRepository(db *sqlx.DB, log logger.Logger) repository.RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "refresh_token_repository")),
+	}
+}
+
+const insertRefreshTokenQuery = `
+	INSERT INTO t_refresh_tokens (id, auth_id, family_id, token_hash, jkt, token_version, expires_at, create_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *domain.RefreshToken) error {
+	r.log.Debug("creating refresh token",
+		logger.F("user_id", token.AuthId),
+		logger.F("family_id", token.FamilyID),
+	)
+
+	_, err := r.db.ExecContext(ctx, insertRefreshTokenQuery,
+		token.ID,
+		token.AuthId,
+		token.FamilyID,
+		token.TokenHash,
+		token.JKT,
+		token.Version,
+		token.ExpiresAt,
+		token.CreateAt,
+	)
+	if err != nil {
+		return fmt.Errorf("create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	query := `
+		SELECT id, auth_id, family_id, token_hash, jkt, token_version, expires_at, create_at, used_at, revoked_at, replaced_by
+		FROM t_refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	var token domain.RefreshToken
+	if err := r.db.GetContext(ctx, &token, query, tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("get refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (r *refreshTokenRepository) Rotate(ctx context.Context, usedID uuid.UUID, next *domain.RefreshToken, at time.Time) error {
+	r.log.Debug("rotating refresh token",
+		logger.F("used_id", usedID),
+		logger.F("family_id", next.FamilyID),
+	)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin refresh token rotation: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Условие по used_at гарантирует, что из двух параллельных запросов пройдет только один
+	result, err := tx.ExecContext(ctx, `
+		UPDATE t_refresh_tokens
+		SET used_at = $1, replaced_by = $2
+		WHERE id = $3 AND used_at IS NULL AND revoked_at IS NULL`,
+		at, next.ID, usedID,
+	)
+	if err != nil {
+		return fmt.Errorf("mark refresh token used: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrRefreshTokenUsed
+	}
+
+	if _, err := tx.ExecContext(ctx, insertRefreshTokenQuery,
+		next.ID,
+		next.AuthId,
+		next.FamilyID,
+		next.TokenHash,
+		next.JKT,
+		next.Version,
+		next.ExpiresAt,
+		next.CreateAt,
+	); err != nil {
+		return fmt.Errorf("create rotated refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit refresh token rotation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID, at time.Time) error {
+	r.log.Debug("revoking refresh token family", logger.F("family_id", familyID))
+
+	query := `
+		UPDATE t_refresh_tokens
+		SET revoked_at = $1
+		WHERE family_id = $2 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, at, familyID); err != nil {
+		return fmt.Errorf("revoke refresh token family: %w", err)
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeByUser(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	r.log.Debug("revoking refresh tokens of user", logger.F("user_id", userID))
+
+	query := `
+		UPDATE t_refresh_tokens
+		SET revoked_at = $1
+		WHERE auth_id = $2 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, at, userID); err != nil {
+		return fmt.Errorf("revoke refresh tokens of user: %w", err)
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) IsFamilyRevoked(ctx context.Context, familyID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM t_refresh_tokens WHERE family_id = $1 AND revoked_at IS NOT NULL
+		)
+	`
+
+	var revoked bool
+	if err := r.db.GetContext(ctx, &revoked, query, familyID); err != nil {
+		return false, fmt.Errorf("check refresh token family: %w", err)
+	}
+
+	return revoked, nil
+}
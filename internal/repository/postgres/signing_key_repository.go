@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// signingKeysLockID - ключ advisory lock, под которым выполняется ротация
+const signingKeysLockID = 7_340_001
+
+type signingKeyRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewSigningKeyRepository(db *sqlx.DB, log logger.Logger) repository.SigningKeyRepository {
+	return &signingKeyRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "signing_key_repository")),
+	}
+}
+
+func (r *signingKeyRepository) Create(ctx context.Context, key *domain.SigningKey) error {
+	r.log.Debug("creating signing key",
+		logger.F("kid", key.ID),
+		logger.F("state", key.State),
+	)
+
+	query := `
+		INSERT INTO t_signing_keys (id, algorithm, private_key, state, create_at, activated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING version`
+
+	if err := r.db.GetContext(ctx, &key.Version, query,
+		key.ID,
+		key.Algorithm,
+		key.PrivateKey,
+		key.State,
+		key.CreateAt,
+		key.ActivatedAt,
+	); err != nil {
+		if isUniqueConstraintViolation(err) {
+			return repository.ErrSigningKeyExists
+		}
+		return fmt.Errorf("create signing key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *signingKeyRepository) ListValid(ctx context.Context) ([]*domain.SigningKey, error) {
+	query := `
+		SELECT id, version, algorithm, private_key, state, create_at, activated_at, retired_at, revoked_at
+		FROM t_signing_keys
+		WHERE state <> 'revoked'
+		ORDER BY version
+	`
+
+	var keys []*domain.SigningKey
+	if err := r.db.SelectContext(ctx, &keys, query); err != nil {
+		return nil, fmt.Errorf("list signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *signingKeyRepository) Rotate(ctx context.Context, activeID string, next *domain.SigningKey, now time.Time) error {
+	r.log.Debug("rotating signing keys",
+		logger.F("active_kid", activeID),
+		logger.F("next_kid", next.ID),
+	)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin rotation: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Несколько инстансов могут решить ротировать одновременно
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, signingKeysLockID); err != nil {
+		return fmt.Errorf("lock signing keys: %w", err)
+	}
+
+	var currentID string
+	err = tx.GetContext(ctx, &currentID, `SELECT id FROM t_signing_keys WHERE state = 'active'`)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("get active signing key: %w", err)
+	}
+	if currentID != activeID {
+		return repository.ErrRotationConflict
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE t_signing_keys SET state = 'retiring', retired_at = $1 WHERE state = 'active'`, now,
+	); err != nil {
+		return fmt.Errorf("retire active signing key: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE t_signing_keys SET state = 'active', activated_at = $1 WHERE state = 'next'`, now,
+	)
+	if err != nil {
+		return fmt.Errorf("activate next signing key: %w", err)
+	}
+	if promoted, err := result.RowsAffected(); err != nil || promoted != 1 {
+		return repository.ErrRotationConflict
+	}
+
+	if err := tx.GetContext(ctx, &next.Version, `
+		INSERT INTO t_signing_keys (id, algorithm, private_key, state, create_at)
+			VALUES ($1, $2, $3, 'next', $4)
+		RETURNING version`,
+		next.ID,
+		next.Algorithm,
+		next.PrivateKey,
+		next.CreateAt,
+	); err != nil {
+		return fmt.Errorf("create next signing key: %w", err)
+	}
+	next.State = domain.SigningKeyNext
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit rotation: %w", err)
+	}
+
+	return nil
+}
+
+func (r *signingKeyRepository) RevokeRetired(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		UPDATE t_signing_keys
+		SET state = 'revoked', revoked_at = NOW()
+		WHERE state = 'retiring' AND retired_at <= $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("revoke retired signing keys: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type tokenRevocationRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewTokenRevocationRepository(db *sqlx.DB, log logger.Logger) repository.TokenRevocationRepository {
+	return &tokenRevocationRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "token_revocation_repository")),
+	}
+}
+
+func (r *tokenRevocationRepository) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	r.log.Debug("revoking access token", logger.F("jti", tokenID))
+
+	query := `
+		INSERT INTO t_revoked_tokens (jti, expires_at)
+			VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`
+
+	if _, err := r.db.ExecContext(ctx, query, tokenID, expiresAt); err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *tokenRevocationRepository) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM t_revoked_tokens WHERE jti = $1 AND expires_at > NOW()
+		)
+	`
+
+	var revoked bool
+	if err := r.db.GetContext(ctx, &revoked, query, tokenID); err != nil {
+		return false, fmt.Errorf("check revoked token: %w", err)
+	}
+
+	return revoked, nil
+}
+
+func (r *tokenRevocationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM t_revoked_tokens WHERE expires_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired revoked tokens: %w", err)
+	}
+
+	return result.RowsAffected()
+}
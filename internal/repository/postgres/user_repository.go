@@ -12,6 +12,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type userRepository struct {
@@ -42,13 +43,21 @@ func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
 	)
 
 	query := `
-		INSERT INTO t_users (id, username, email, password_hash, create_at, update_at) 
-			VALUES ($1, $2, $3, $4, $5, $6)`
+		INSERT INTO t_users (id, username, email, password_hash, roles, scopes, create_at, update_at) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
 	fmt.Print(query)
 
 	//* генерация нового айдишника для пользотеля
 	user.ID = uuid.New()
 
+	//* роли и scopes не могут быть NULL
+	if user.Roles == nil {
+		user.Roles = pq.StringArray{domain.RoleUser}
+	}
+	if user.Scopes == nil {
+		user.Scopes = pq.StringArray{}
+	}
+
 	//* НАСТРОИВАЕМ ДАТУ
 	now := time.Now()
 	user.Create_at = now
@@ -59,6 +68,8 @@ func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
 		user.UserName,
 		user.Email,
 		user.PasswordHash,
+		user.Roles,
+		user.Scopes,
 		user.Create_at,
 		user.Update_at,
 	)
@@ -88,7 +99,7 @@ func (r *userRepository) GetByID(ctx context.Context, id string) (*domain.User,
 	)
 
 	query := `
-		SELECT id, username, email, password_hash, create_at, update_at
+		SELECT id, username, email, password_hash, roles, scopes, token_version, email_verified_at, create_at, update_at
 		FROM t_users
 		WHERE id = $1
 	`
@@ -114,7 +125,7 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*domain.
 	)
 
 	query := `
-		SELECT id, username, email, password_hash, create_at, update_at
+		SELECT id, username, email, password_hash, roles, scopes, token_version, email_verified_at, create_at, update_at
 		FROM t_users
 		WHERE email = $1
 	`
@@ -162,6 +173,100 @@ func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
 	return nil
 }
 
+// UpdatePasswordHash заменяет хеш пароля пользователя
+func (r *userRepository) UpdatePasswordHash(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	r.log.Debug("update password hash",
+		logger.F("user_id", id),
+	)
+
+	query := `
+		UPDATE t_users
+		SET password_hash = $1, update_at = $2
+		WHERE id = $3
+	`
+
+	result, err := r.db.ExecContext(ctx, query, passwordHash, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("update password hash: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("update password hash: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// IncrementTokenVersion делает недействительными все выпущенные токены пользователя
+func (r *userRepository) IncrementTokenVersion(ctx context.Context, id uuid.UUID) (int64, error) {
+	r.log.Debug("increment token version",
+		logger.F("user_id", id),
+	)
+
+	query := `
+		UPDATE t_users
+		SET token_version = token_version + 1, update_at = $1
+		WHERE id = $2
+		RETURNING token_version
+	`
+
+	var version int64
+	if err := r.db.GetContext(ctx, &version, query, time.Now(), id); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, repository.ErrNotFound
+		}
+		return 0, fmt.Errorf("increment token version: %w", err)
+	}
+
+	return version, nil
+}
+
+func (r *userRepository) GetTokenVersion(ctx context.Context, id string) (int64, error) {
+	query := `SELECT token_version FROM t_users WHERE id = $1`
+
+	var version int64
+	if err := r.db.GetContext(ctx, &version, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, repository.ErrNotFound
+		}
+		return 0, fmt.Errorf("get token version: %w", err)
+	}
+
+	return version, nil
+}
+
+// MarkEmailVerified отмечает email пользователя подтвержденным; повторное подтверждение не меняет дату
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.log.Debug("mark email verified",
+		logger.F("user_id", id),
+	)
+
+	query := `
+		UPDATE t_users
+		SET email_verified_at = COALESCE(email_verified_at, $1), update_at = $1
+		WHERE id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return fmt.Errorf("mark email verified: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("mark email verified: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
 // TODO реализация
 // * Реализован
 func (r *userRepository) Delete(ctx context.Context, id string) error {
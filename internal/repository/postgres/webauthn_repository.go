@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type webAuthnRepository struct {
+	db  *sqlx.DB
+	log logger.Logger
+}
+
+func NewWebAuthnRepository(db *sqlx.DB, log logger.Logger) repository.WebAuthnRepository {
+	return &webAuthnRepository{
+		db:  db,
+		log: log.With(logger.F("layer", "repository"), logger.F("component", "webauthn_repository")),
+	}
+}
+
+func (r *webAuthnRepository) CreateCredential(ctx context.Context, credential *domain.WebAuthnCredential) error {
+	r.log.Debug("creating webauthn credential", logger.F("user_id", credential.UserID))
+
+	if credential.Transports == nil {
+		credential.Transports = pq.StringArray{}
+	}
+
+	query := `
+		INSERT INTO t_webauthn_credentials (id, user_id, credential_id, public_key, sign_count, transports, name, create_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	if _, err := r.db.ExecContext(ctx, query,
+		credential.ID,
+		credential.UserID,
+		credential.CredentialID,
+		credential.PublicKey,
+		credential.SignCount,
+		credential.Transports,
+		credential.Name,
+		credential.CreateAt,
+	); err != nil {
+		if isUniqueConstraintViolation(err) {
+			return repository.ErrWebAuthnCredentialExists
+		}
+		return fmt.Errorf("create webauthn credential: %w", err)
+	}
+
+	return nil
+}
+
+func (r *webAuthnRepository) ListCredentials(ctx context.Context, userID uuid.UUID) ([]*domain.WebAuthnCredential, error) {
+	query := `
+		SELECT id, user_id, credential_id, public_key, sign_count, transports, name, create_at, last_used_at
+		FROM t_webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY create_at
+	`
+
+	var credentials []*domain.WebAuthnCredential
+	if err := r.db.SelectContext(ctx, &credentials, query, userID); err != nil {
+		return nil, fmt.Errorf("list webauthn credentials: %w", err)
+	}
+
+	return credentials, nil
+}
+
+func (r *webAuthnRepository) GetCredential(ctx context.Context, credentialID []byte) (*domain.WebAuthnCredential, error) {
+	query := `
+		SELECT id, user_id, credential_id, public_key, sign_count, transports, name, create_at, last_used_at
+		FROM t_webauthn_credentials
+		WHERE credential_id = $1
+	`
+
+	var credential domain.WebAuthnCredential
+	if err := r.db.GetContext(ctx, &credential, query, credentialID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrWebAuthnCredentialNotFound
+		}
+		return nil, fmt.Errorf("get webauthn credential: %w", err)
+	}
+
+	return &credential, nil
+}
+
+func (r *webAuthnRepository) UpdateSignCount(ctx context.Context, id uuid.UUID, signCount int64, at time.Time) error {
+	// Условие повторяет проверку счетчика: параллельный вход с тем же значением не пройдет
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE t_webauthn_credentials
+		SET sign_count = $1, last_used_at = $2
+		WHERE id = $3 AND (sign_count < $1 OR (sign_count = 0 AND $1 = 0))`,
+		signCount, at, id,
+	)
+	if err != nil {
+		return fmt.Errorf("update webauthn sign count: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return repository.ErrWebAuthnSignCount
+	}
+
+	return nil
+}
+
+func (r *webAuthnRepository) CreateSession(ctx context.Context, session *domain.WebAuthnSession) error {
+	query := `
+		INSERT INTO t_webauthn_sessions (id, user_id, token_hash, challenge, purpose, expires_at, create_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	if _, err := r.db.ExecContext(ctx, query,
+		session.ID,
+		session.UserID,
+		session.TokenHash,
+		session.Challenge,
+		session.Purpose,
+		session.ExpiresAt,
+		session.CreateAt,
+	); err != nil {
+		return fmt.Errorf("create webauthn session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *webAuthnRepository) ConsumeSession(ctx context.Context, tokenHash string, now time.Time) (*domain.WebAuthnSession, error) {
+	// Challenge одноразовый: удаление и чтение одним запросом
+	query := `
+		DELETE FROM t_webauthn_sessions
+		WHERE token_hash = $1 AND expires_at > $2
+		RETURNING id, user_id, token_hash, challenge, purpose, expires_at, create_at
+	`
+
+	var session domain.WebAuthnSession
+	if err := r.db.GetContext(ctx, &session, query, tokenHash, now); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, repository.ErrWebAuthnSessionNotFound
+		}
+		return nil, fmt.Errorf("consume webauthn session: %w", err)
+	}
+
+	return &session, nil
+}
+
+func (r *webAuthnRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM t_webauthn_sessions WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired webauthn sessions: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"auth-service/internal/logger"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Server struct {
+	server *http.Server
+	log    logger.Logger
+}
+
+func NewServer(handler http.Handler, log logger.Logger) *Server {
+	return &Server{
+		server: &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+		log: log,
+	}
+}
+
+func (s *Server) Start(port int) error {
+	lis, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("HTTP server starting", logger.F("port", port))
+
+	go func() {
+		if err := s.server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Fatal("HTTP server failed", logger.F("error", err))
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrLoginThrottled = errors.New("too many failed login attempts")
+	ErrAccountLocked  = errors.New("account is temporarily locked")
+)
+
+// RetryAfterError - вход временно запрещен; повторить можно через RetryAfter
+type RetryAfterError struct {
+	Err        error // ErrLoginThrottled или ErrAccountLocked
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Err, e.RetryAfter)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// AttemptTrackerConfig - пороги защиты входа от перебора. Нулевой порог отключает свою проверку.
+type AttemptTrackerConfig struct {
+	AccountThreshold int           // неудач по аккаунту, после которых каждая следующая попытка ждет
+	IPThreshold      int           // то же по IP адресу клиента
+	BaseDelay        time.Duration // задержка на пороге, дальше удваивается с каждой неудачей
+	MaxDelay         time.Duration // верхняя граница задержки
+	LockoutThreshold int           // неудач по аккаунту до временной блокировки
+	LockoutDuration  time.Duration
+	Window           time.Duration // неудачи старше окна забываются
+}
+
+type attemptTracker struct {
+	repo   repository.LoginAttemptRepository
+	config AttemptTrackerConfig
+	now    func() time.Time
+	log    logger.Logger
+}
+
+func NewAttemptTracker(repo repository.LoginAttemptRepository, config AttemptTrackerConfig, log logger.Logger) AttemptTracker {
+	return &attemptTracker{
+		repo:   repo,
+		config: config,
+		now:    time.Now,
+		log:    log.With(logger.F("layer", "service"), logger.F("component", "attempt_tracker")),
+	}
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (t *attemptTracker) Check(ctx context.Context, email, ip string) error {
+	now := t.now()
+
+	if err := t.checkKey(ctx, accountKey(email), now, true); err != nil {
+		return err
+	}
+	if ip != "" {
+		return t.checkKey(ctx, ipKey(ip), now, false)
+	}
+	return nil
+}
+
+func (t *attemptTracker) checkKey(ctx context.Context, key string, now time.Time, account bool) error {
+	attempts, err := t.repo.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, repository.ErrLoginAttemptsNotFound) {
+			return nil
+		}
+		return err
+	}
+	if attempts.LockedUntil == nil || !now.Before(*attempts.LockedUntil) {
+		return nil
+	}
+
+	reason := ErrLoginThrottled
+	if account && t.config.LockoutThreshold > 0 && attempts.Failures >= t.config.LockoutThreshold {
+		reason = ErrAccountLocked
+	}
+	// Округление вверх до секунды: клиент, повторивший запрос ровно через RetryAfter, уже пройдет
+	retry := (attempts.LockedUntil.Sub(now) + time.Second - 1).Truncate(time.Second)
+	return &RetryAfterError{Err: reason, RetryAfter: retry}
+}
+
+func (t *attemptTracker) Failure(ctx context.Context, email, ip string) error {
+	now := t.now()
+
+	key := accountKey(email)
+	failures, err := t.repo.RecordFailure(ctx, key, now, t.config.Window)
+	if err != nil {
+		return err
+	}
+	switch {
+	case t.config.LockoutThreshold > 0 && failures >= t.config.LockoutThreshold:
+		t.log.Warn("account locked after failed logins", logger.F("key", key), logger.F("failures", failures))
+		if err := t.repo.Lock(ctx, key, now.Add(t.config.LockoutDuration)); err != nil {
+			return err
+		}
+	case t.config.AccountThreshold > 0 && failures >= t.config.AccountThreshold:
+		if err := t.repo.Lock(ctx, key, now.Add(t.delay(failures-t.config.AccountThreshold))); err != nil {
+			return err
+		}
+	}
+
+	if ip == "" {
+		return nil
+	}
+	key = ipKey(ip)
+	failures, err = t.repo.RecordFailure(ctx, key, now, t.config.Window)
+	if err != nil {
+		return err
+	}
+	if t.config.IPThreshold > 0 && failures >= t.config.IPThreshold {
+		if failures == t.config.IPThreshold {
+			t.log.Warn("client throttled after failed logins", logger.F("key", key))
+		}
+		return t.repo.Lock(ctx, key, now.Add(t.delay(failures-t.config.IPThreshold)))
+	}
+	return nil
+}
+
+// Success сбрасывает только счетчик аккаунта: удачный вход в свой аккаунт
+// не должен обнулять перебор чужих паролей с того же адреса
+func (t *attemptTracker) Success(ctx context.Context, email string) error {
+	return t.repo.Reset(ctx, accountKey(email))
+}
+
+func (t *attemptTracker) Unlock(ctx context.Context, email string) error {
+	return t.repo.Reset(ctx, accountKey(email))
+}
+
+// delay - BaseDelay * 2^excess, не больше MaxDelay
+func (t *attemptTracker) delay(excess int) time.Duration {
+	delay := t.config.BaseDelay
+	for i := 0; i < excess && delay < t.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, t.config.MaxDelay)
+}
+
+type clientIPKey struct{}
+
+// WithClientIP кладет адрес клиента в контекст запроса; его выставляет транспортный слой
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+func clientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
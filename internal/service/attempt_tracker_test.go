@@ -0,0 +1,167 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository/memory"
+	"auth-service/internal/util/password"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+	"github.com/google/uuid"
+)
+
+// newTestAttemptTracker - трекер без порогов: вход не ограничивается
+func newTestAttemptTracker(t *testing.T) AttemptTracker {
+	t.Helper()
+	return NewAttemptTracker(memory.NewLoginAttemptRepository(), AttemptTrackerConfig{Window: time.Hour}, newTestLogger(t))
+}
+
+func retryAfter(t *testing.T, err error, want error) time.Duration {
+	t.Helper()
+	var retryErr *RetryAfterError
+	if !errors.As(err, &retryErr) || !errors.Is(err, want) {
+		t.Fatalf("err = %v, want RetryAfterError wrapping %v", err, want)
+	}
+	return retryErr.RetryAfter
+}
+
+func TestAttemptTracker_BackoffAndLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+
+	tracker := NewAttemptTracker(memory.NewLoginAttemptRepository(), AttemptTrackerConfig{
+		AccountThreshold: 3,
+		IPThreshold:      100,
+		BaseDelay:        time.Second,
+		MaxDelay:         4 * time.Second,
+		LockoutThreshold: 6,
+		LockoutDuration:  time.Hour,
+		Window:           time.Hour,
+	}, newTestLogger(t)).(*attemptTracker)
+	tracker.now = func() time.Time { return now }
+
+	fail := func() {
+		t.Helper()
+		if err := tracker.Failure(ctx, "Alice@example.com", "10.0.0.1"); err != nil {
+			t.Fatalf("Failure: %v", err)
+		}
+	}
+
+	fail()
+	fail()
+	if err := tracker.Check(ctx, "alice@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("below threshold: %v", err)
+	}
+
+	// На пороге задержка BaseDelay, дальше удваивается до MaxDelay
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		fail()
+		if got := retryAfter(t, tracker.Check(ctx, "alice@example.com", ""), ErrLoginThrottled); got != want {
+			t.Errorf("retry after = %v, want %v", got, want)
+		}
+		now = now.Add(want)
+	}
+
+	fail()
+	if got := retryAfter(t, tracker.Check(ctx, "alice@example.com", ""), ErrAccountLocked); got != time.Hour {
+		t.Errorf("lockout retry after = %v, want 1h", got)
+	}
+
+	// Другой аккаунт с того же адреса не затронут
+	if err := tracker.Check(ctx, "bob@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("other account: %v", err)
+	}
+
+	if err := tracker.Unlock(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := tracker.Check(ctx, "alice@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("after unlock: %v", err)
+	}
+}
+
+func TestAttemptTracker_IPThrottling(t *testing.T) {
+	ctx := context.Background()
+
+	tracker := NewAttemptTracker(memory.NewLoginAttemptRepository(), AttemptTrackerConfig{
+		IPThreshold: 3,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Hour,
+		Window:      time.Hour,
+	}, newTestLogger(t))
+
+	// Перебор разных адресов с одного IP
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if err := tracker.Failure(ctx, email, "10.0.0.2"); err != nil {
+			t.Fatalf("Failure: %v", err)
+		}
+	}
+	retryAfter(t, tracker.Check(ctx, "d@example.com", "10.0.0.2"), ErrLoginThrottled)
+	if err := tracker.Check(ctx, "d@example.com", "10.0.0.3"); err != nil {
+		t.Errorf("other ip: %v", err)
+	}
+}
+
+func TestAuthService_LoginLockoutAndAdminUnlock(t *testing.T) {
+	ctx := WithClientIP(context.Background(), "192.0.2.10")
+
+	hasher := password.NewBcrypt(4)
+	passwordHash, err := hasher.Hash("secret-password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Email: "carol@example.com", PasswordHash: passwordHash}
+	admin := &domain.User{ID: uuid.New(), Email: "admin@example.com", PasswordHash: passwordHash, Roles: []string{domain.RoleAdmin}}
+	audit := memory.NewAuditRepository()
+	attempts := NewAttemptTracker(memory.NewLoginAttemptRepository(), AttemptTrackerConfig{
+		LockoutThreshold: 2,
+		LockoutDuration:  time.Hour,
+		Window:           time.Hour,
+	}, newTestLogger(t))
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:    newFakeUserRepository(user, admin),
+		Audit:    audit,
+		Attempts: attempts,
+		Tokens:   newTestTokenService(t, user, admin),
+		Hasher:   hasher,
+	}, AuthConfig{})
+
+	userSession, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "wrong-password"}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidCredentials", i, err)
+		}
+	}
+	// Заблокированный аккаунт не пускает и с верным паролем
+	_, err = s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"})
+	retryAfter(t, err, ErrAccountLocked)
+
+	// Обычный пользователь не может снимать блокировку
+	if _, err := s.UnlockAccount(ctx, &pb.UnlockAccountRequest{AccessToken: userSession.AccessToken, Email: user.Email}); !errors.Is(err, ErrAdminRequired) {
+		t.Fatalf("unlock by user: err = %v, want ErrAdminRequired", err)
+	}
+
+	adminSession, err := s.Login(ctx, &pb.LoginRequest{Email: admin.Email, Password: "secret-password"})
+	if err != nil {
+		t.Fatalf("admin Login: %v", err)
+	}
+	if _, err := s.UnlockAccount(ctx, &pb.UnlockAccountRequest{AccessToken: adminSession.AccessToken, Email: user.Email}); err != nil {
+		t.Fatalf("UnlockAccount: %v", err)
+	}
+	if _, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"}); err != nil {
+		t.Fatalf("Login after unlock: %v", err)
+	}
+
+	events, _ := audit.ListByUser(ctx, admin.ID, 10)
+	if len(events) != 1 || events[0].EventType != domain.AuditAccountUnlocked {
+		t.Errorf("audit events = %+v", events)
+	}
+}
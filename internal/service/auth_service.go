@@ -4,10 +4,25 @@ import (
 	"auth-service/internal/domain"
 	"auth-service/internal/logger"
 	"auth-service/internal/repository"
-	"auth-service/internal/util/bcrypt"
+	"auth-service/internal/util/dpop"
 	"auth-service/internal/util/jwt"
+	"auth-service/internal/util/password"
+	"auth-service/internal/util/secretbox"
+	"auth-service/internal/util/webauthn"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
 )
@@ -20,21 +35,156 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserAlreadyExists  = errors.New("user already exists")
 	ErrTokenGeneration    = errors.New("token generation failed")
+	ErrPasswordPolicy     = errors.New("password does not meet the password policy")
+	ErrResetTokenInvalid  = errors.New("password reset token is invalid or has expired")
+	ErrEmailNotVerified   = errors.New("email address is not verified")
+	ErrAdminRequired      = errors.New("administrator role is required")
+)
+
+// PasswordPolicyError - пароль отклонен политикой, Violations - по одному на нарушение
+type PasswordPolicyError struct {
+	Violations []password.Violation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	codes := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		codes = append(codes, violation.Code)
+	}
+	return fmt.Sprintf("%s: %s", ErrPasswordPolicy, strings.Join(codes, ", "))
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
+
+// Типы выдаваемых токенов (token_type в ответе)
+const (
+	tokenTypeBearer = "Bearer"
+	tokenTypeDPoP   = "DPoP"
+)
+
+// AuthConfig - настройки сценариев аутентификации
+type AuthConfig struct {
+	// Audience собственных токенов сервиса. Операции над аккаунтом принимают только их:
+	// токены, выпущенные обменом для других сервисов, годятся лишь для ValidateToken и Introspect.
+	Audience []string
+
+	DPoPTokenEndpoint string        // htu, который клиент указывает в DPoP proof для Login и RefreshToken
+	PasswordResetTTL  time.Duration // срок жизни ссылки сброса пароля
+	PasswordResetURL  string        // страница сброса, токен добавляется параметром token
+
+	PasswordResetCooldown time.Duration // не чаще одного письма сброса за интервал
+
+	UnverifiedLoginMode        string        // UnverifiedLogin*: что делать при входе с неподтвержденным email
+	EmailVerificationTTL       time.Duration // срок жизни ссылки подтверждения
+	EmailVerificationURL       string        // страница подтверждения, токен добавляется параметром token
+	VerificationResendInterval time.Duration // не чаще одного письма подтверждения за интервал
+
+	TOTPIssuer        string        // имя сервиса в приложении-аутентификаторе
+	MFAChallengeTTL   time.Duration // сколько действует mfa токен после проверки пароля
+	MFAMaxAttempts    int           // неверных кодов на один mfa токен
+	RecoveryCodeCount int           // кодов восстановления в наборе, выдается при включении TOTP
+
+	WebAuthnSessionTTL time.Duration // сколько действует challenge регистрации или входа по passkey
+
+	LoginCodeTTL         time.Duration // срок жизни кода и ссылки для входа без пароля
+	LoginCodeURL         string        // страница входа по ссылке, токен добавляется параметром token
+	LoginCodeMaxAttempts int           // неверных кодов, после которых код и ссылка перестают действовать
+	LoginCodeCooldown    time.Duration // не чаще одного письма с кодом за интервал
+
+	// HardenedErrors - Login и Register отвечают одинаково независимо от того, есть ли аккаунт с таким email
+	HardenedErrors bool
+}
+
+// detachedTimeout - сколько может выполняться операция, запущенная после ответа клиенту
+const detachedTimeout = 30 * time.Second
+
+// maxDetached - сколько операций detach выполняется одновременно; сверх этого они отбрасываются
+const maxDetached = 64
+
+// Режимы входа пользователя с неподтвержденным email (UnverifiedLoginMode)
+const (
+	UnverifiedLoginAllow      = "allow"      // обычные токены
+	UnverifiedLoginRestricted = "restricted" // токены только со scope domain.ScopeVerifyEmail
+	UnverifiedLoginDeny       = "deny"       // вход запрещен до подтверждения
 )
 
 type authService struct {
-	userRepo   repository.UserRepository
-	jwtManager jwt.TokenManager
-	log        logger.Logger
+	userRepo     repository.UserRepository
+	historyRepo  repository.PasswordHistoryRepository
+	resetRepo    repository.PasswordResetRepository
+	verifyRepo   repository.EmailVerificationRepository
+	outbox       repository.MailOutboxRepository
+	mfaRepo      repository.MFARepository
+	secrets      *secretbox.Box
+	webauthnRepo repository.WebAuthnRepository
+	relyingParty *webauthn.RelyingParty // nil - WebAuthn выключен
+	loginCodes   repository.LoginCodeRepository
+	audit        repository.AuditRepository
+	attempts     AttemptTracker
+	tokenService TokenService
+	dummyHash    string         // хеш для сравнения, когда пользователя нет (HardenedErrors)
+	pending      sync.WaitGroup // операции, запущенные detach
+	detached     chan struct{}  // семафор на maxDetached операций
+	hasher       password.PasswordHasher
+	policy       *password.Policy
+	dpop         dpop.Verifier
+	config       AuthConfig
+	log          logger.Logger
 	pb.UnimplementedAuthServiceServer
 }
 
-func NewAuthService(UserRepo repository.UserRepository, jwtManager jwt.TokenManager, log logger.Logger) AuthService {
-	return &authService{
-		userRepo:   UserRepo,
-		jwtManager: jwtManager,
-		log:        log.With(logger.F("layer", "service"), logger.F("component", "user_service")),
+// AuthDependencies - хранилища и компоненты AuthService. Поля именованные, чтобы перепутать
+// однотипные репозитории при сборке было нельзя.
+type AuthDependencies struct {
+	Users           repository.UserRepository
+	PasswordHistory repository.PasswordHistoryRepository
+	PasswordResets  repository.PasswordResetRepository
+	Verifications   repository.EmailVerificationRepository
+	Outbox          repository.MailOutboxRepository
+	MFA             repository.MFARepository
+	Secrets         *secretbox.Box // шифрование TOTP секретов
+	WebAuthn        repository.WebAuthnRepository
+	RelyingParty    *webauthn.RelyingParty // nil - WebAuthn выключен
+	LoginCodes      repository.LoginCodeRepository
+	Audit           repository.AuditRepository
+	Attempts        AttemptTracker
+	Tokens          TokenService
+	Hasher          password.PasswordHasher
+	Policy          *password.Policy
+	DPoP            dpop.Verifier
+}
+
+func NewAuthService(deps AuthDependencies, config AuthConfig, log logger.Logger) (AuthService, error) {
+	// Фиктивный хеш считается заранее: первый вход с неизвестным email не должен выделяться по времени
+	dummyHash, err := dummyPasswordHash(deps.Hasher)
+	if err != nil {
+		return nil, err
 	}
+
+	return &authService{
+		userRepo:     deps.Users,
+		historyRepo:  deps.PasswordHistory,
+		resetRepo:    deps.PasswordResets,
+		verifyRepo:   deps.Verifications,
+		outbox:       deps.Outbox,
+		mfaRepo:      deps.MFA,
+		secrets:      deps.Secrets,
+		webauthnRepo: deps.WebAuthn,
+		relyingParty: deps.RelyingParty,
+		loginCodes:   deps.LoginCodes,
+		audit:        deps.Audit,
+		attempts:     deps.Attempts,
+		tokenService: deps.Tokens,
+		hasher:       deps.Hasher,
+		policy:       deps.Policy,
+		dpop:         deps.DPoP,
+		config:       config,
+		dummyHash:    dummyHash,
+		detached:     make(chan struct{}, maxDetached),
+		log:          log.With(logger.F("layer", "service"), logger.F("component", "user_service")),
+	}, nil
 }
 
 // ID           uuid.UUID `json:"id" db:"id"`
@@ -50,6 +200,10 @@ func (s *authService) Register(ctx context.Context, registerRequest *pb.Register
 	email := registerRequest.Email
 	password := registerRequest.Password
 
+	if s.config.HardenedErrors {
+		return s.registerHardened(ctx, registerRequest)
+	}
+
 	existingUser, err := s.userRepo.GetByEmail(ctx, email)
 
 	if existingUser != nil && err == nil {
@@ -60,7 +214,11 @@ func (s *authService) Register(ctx context.Context, registerRequest *pb.Register
 		return nil, ErrBadRequest
 	}
 
-	passwordHash, err := bcrypt.Hash(password)
+	if err := s.checkPasswordPolicy(password, username, email); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := s.hasher.Hash(password)
 
 	if err != nil {
 		return nil, err
@@ -70,15 +228,77 @@ func (s *authService) Register(ctx context.Context, registerRequest *pb.Register
 		UserName:     username,
 		Email:        email,
 		PasswordHash: passwordHash,
+		Roles:        pq.StringArray{domain.RoleUser},
+		Scopes:       pq.StringArray{},
 	}
 
-	s.userRepo.Create(ctx, user)
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrUserExists) {
+			return nil, ErrUserAlreadyExists
+		}
+		return nil, err
+	}
+
+	// Аккаунт уже создан: если письмо не поставилось в очередь, пользователь запросит его повторно
+	if err := s.sendVerification(ctx, user); err != nil {
+		s.log.Error("failed to send email verification", logger.F("user_id", user.ID), logger.F("error", err))
+	}
 
 	return &pb.RegisterResponse{
 		UserId: user.ID.String(),
 	}, nil
 }
 
+// registerHardened - регистрация без раскрытия занятых email: ответ одинаковый, пароль хешируется
+// в обоих случаях, а владелец существующего аккаунта получает письмо о попытке
+func (s *authService) registerHardened(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	if req.UserName == "" || req.Email == "" || req.Password == "" {
+		return nil, ErrBadRequest
+	}
+	if err := s.checkPasswordPolicy(req.Password, req.UserName, req.Email); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := s.hasher.Hash(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := &pb.RegisterResponse{Message: registerAcceptedMessage}
+
+	existing, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+	if existing != nil {
+		if err := enqueueMail(ctx, s.outbox, existing.Email, accountExistsMailSubject, accountExistsMailBody); err != nil {
+			s.log.Error("failed to notify account owner", logger.F("user_id", existing.ID), logger.F("error", err))
+		}
+		s.log.Info("registration attempt for existing account", logger.F("user_id", existing.ID))
+		return accepted, nil
+	}
+
+	user := &domain.User{
+		UserName:     req.UserName,
+		Email:        req.Email,
+		PasswordHash: passwordHash,
+		Roles:        pq.StringArray{domain.RoleUser},
+		Scopes:       pq.StringArray{},
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		// Параллельная регистрация того же email: второй запрос получает тот же ответ
+		if errors.Is(err, repository.ErrUserExists) {
+			return accepted, nil
+		}
+		return nil, err
+	}
+
+	if err := s.sendVerification(ctx, user); err != nil {
+		s.log.Error("failed to send email verification", logger.F("user_id", user.ID), logger.F("error", err))
+	}
+	return accepted, nil
+}
+
 func (s *authService) Login(ctx context.Context, loginRequest *pb.LoginRequest) (*pb.LoginResponse, error) {
 	email := loginRequest.Email
 	pass := loginRequest.Password
@@ -87,25 +307,65 @@ func (s *authService) Login(ctx context.Context, loginRequest *pb.LoginRequest)
 		return nil, ErrBadRequest
 	}
 
-	user, err := s.userRepo.GetByEmail(ctx, loginRequest.Email)
+	jkt, err := s.tokenEndpointKey(loginRequest.DpopProof)
 	if err != nil {
-		return nil, ErrUserNotFound
+		return nil, err
+	}
+
+	ip := clientIP(ctx)
+	if err := s.attempts.Check(ctx, email, ip); err != nil {
+		return nil, err
 	}
-	if user == nil {
+
+	user, err := s.userRepo.GetByEmail(ctx, loginRequest.Email)
+	if err != nil || user == nil {
+		// Неизвестный email тоже считается неудачей: перебор адресов с одного IP замедляется
+		s.recordLoginFailure(ctx, email, ip)
+		if s.config.HardenedErrors {
+			// Сравнение с фиктивным хешем выравнивает время ответа с неверным паролем
+			s.hasher.Verify(pass, s.dummyHash)
+			return nil, ErrInvalidCredentials
+		}
 		return nil, ErrUserNotFound
 	}
 
-	isValid, err := bcrypt.Check(loginRequest.Password, user.PasswordHash)
+	isValid, err := s.hasher.Verify(loginRequest.Password, user.PasswordHash)
 	if err != nil {
+		// Битый хеш тоже неудача: иначе этот путь не замедлялся бы и отличался по времени
+		s.log.Error("failed to verify password hash", logger.F("user_id", user.ID), logger.F("error", err))
+		s.recordLoginFailure(ctx, email, ip)
+		if s.config.HardenedErrors {
+			return nil, ErrInvalidCredentials
+		}
 		return nil, ErrPasswordBad
 	}
 
 	if !isValid {
+		s.recordLoginFailure(ctx, email, ip)
 		return nil, ErrInvalidCredentials
 	}
 
-	tokenPair, err := s.jwtManager.GenerateTokens(user.ID.String(), user.Email)
+	// Проверяется после пароля, чтобы ответ не раскрывал состояние чужого аккаунта
+	if user.EmailVerifiedAt == nil && s.config.UnverifiedLoginMode == UnverifiedLoginDeny {
+		return nil, ErrEmailNotVerified
+	}
+
+	s.rehashPassword(ctx, user, loginRequest.Password)
+
+	methods, err := s.mfaMethods(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) > 0 {
+		// Неудачи по аккаунту сбрасываются только после второго фактора: иначе каждый новый вход
+		// по паролю обнулял бы счетчик неверных кодов
+		return s.startMFAChallenge(ctx, user, jkt, methods)
+	}
+	s.resetLoginAttempts(ctx, user)
+
+	tokenPair, err := s.tokenService.IssueTokens(ctx, user, jkt)
 	if err != nil {
+		s.log.Error("failed to issue tokens", logger.F("user_id", user.ID), logger.F("error", err))
 		return nil, ErrBadToken
 	}
 
@@ -113,10 +373,581 @@ func (s *authService) Login(ctx context.Context, loginRequest *pb.LoginRequest)
 	return &pb.LoginResponse{
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
+		TokenType:    tokenType(jkt),
 	}, nil
 
 }
 
+// recordLoginFailure учитывает неудачный вход; сбой хранилища не меняет ответ клиенту
+func (s *authService) recordLoginFailure(ctx context.Context, email, ip string) {
+	if err := s.attempts.Failure(ctx, email, ip); err != nil {
+		s.log.Error("failed to record login failure", logger.F("error", err))
+	}
+}
+
+func (s *authService) resetLoginAttempts(ctx context.Context, user *domain.User) {
+	if err := s.attempts.Success(ctx, user.Email); err != nil {
+		s.log.Error("failed to reset login attempts", logger.F("user_id", user.ID), logger.F("error", err))
+	}
+}
+
+// UnlockAccount снимает блокировку входа после перебора пароля; доступно только администратору
+func (s *authService) UnlockAccount(ctx context.Context, req *pb.UnlockAccountRequest) (*pb.UnlockAccountResponse, error) {
+	if req.AccessToken == "" || req.Email == "" {
+		return nil, ErrBadRequest
+	}
+
+	admin, err := s.authenticatedUser(ctx, req.AccessToken, req.DpopProof, dpop.Request{Method: req.HttpMethod, URI: req.HttpUri})
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(admin.Roles, domain.RoleAdmin) {
+		return nil, ErrAdminRequired
+	}
+
+	if err := s.attempts.Unlock(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("account unlocked", logger.F("admin_id", admin.ID))
+	s.recordAudit(ctx, admin.ID, domain.AuditAccountUnlocked, map[string]string{"email": req.Email})
+	return &pb.UnlockAccountResponse{}, nil
+}
+
+// checkPasswordPolicy проверяет новый пароль (регистрация, смена, сброс)
+func (s *authService) checkPasswordPolicy(plain, username, email string) error {
+	violations, err := s.policy.Validate(plain, password.Subject{UserName: username, Email: email})
+	if err != nil {
+		s.log.Error("failed to check password policy", logger.F("error", err))
+		return err
+	}
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// checkPasswordReuse запрещает текущий пароль и HistorySize предыдущих
+func (s *authService) checkPasswordReuse(ctx context.Context, user *domain.User, plain string) error {
+	previous := []string{user.PasswordHash}
+	if size := s.policy.HistorySize(); size > 0 {
+		history, err := s.historyRepo.ListRecent(ctx, user.ID, size)
+		if err != nil {
+			s.log.Error("failed to load password history", logger.F("user_id", user.ID), logger.F("error", err))
+			return err
+		}
+		previous = append(previous, history...)
+	}
+
+	if violations := s.policy.CheckHistory(s.hasher, plain, previous); len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// setPassword сохраняет новый хеш пароля, а прежний переносит в историю.
+// Используется при смене и сбросе пароля; перехеширование при входе историю не пополняет.
+func (s *authService) setPassword(ctx context.Context, user *domain.User, passwordHash string) error {
+	if size := s.policy.HistorySize(); size > 0 && user.PasswordHash != "" {
+		entry := &domain.PasswordHistoryEntry{
+			ID:           uuid.New(),
+			UserID:       user.ID,
+			PasswordHash: user.PasswordHash,
+			CreateAt:     time.Now(),
+		}
+		if err := s.historyRepo.Add(ctx, entry, size); err != nil {
+			return fmt.Errorf("record password history: %w", err)
+		}
+	}
+
+	if err := s.userRepo.UpdatePasswordHash(ctx, user.ID, passwordHash); err != nil {
+		return fmt.Errorf("update password hash: %w", err)
+	}
+
+	user.PasswordHash = passwordHash
+	return nil
+}
+
+// rehashPassword переводит хеш на текущий алгоритм и параметры, пока пароль известен.
+// Ошибка не мешает входу: перехешируем при следующем логине.
+func (s *authService) rehashPassword(ctx context.Context, user *domain.User, plain string) {
+	if !s.hasher.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	passwordHash, err := s.hasher.Hash(plain)
+	if err != nil {
+		s.log.Error("failed to rehash password", logger.F("user_id", user.ID), logger.F("error", err))
+		return
+	}
+
+	if err := s.userRepo.UpdatePasswordHash(ctx, user.ID, passwordHash); err != nil {
+		s.log.Error("failed to store rehashed password", logger.F("user_id", user.ID), logger.F("error", err))
+		return
+	}
+
+	s.log.Info("password rehashed", logger.F("user_id", user.ID), logger.F("algorithm", s.hasher.Algorithm()))
+	user.PasswordHash = passwordHash
+}
+
+func (s *authService) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.LoginResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, ErrBadRequest
+	}
+
+	jkt, err := s.tokenEndpointKey(req.DpopProof)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenPair, err := s.tokenService.RefreshTokens(ctx, req.RefreshToken, jkt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.LoginResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		TokenType:    tokenType(jkt),
+	}, nil
+}
+
+func (s *authService) ValidateToken(ctx context.Context, req *pb.TokenRequest) (*pb.TokenResponse, error) {
+	if req.Token == "" {
+		return nil, ErrBadRequest
+	}
+
+	claims, err := s.tokenService.ValidateAccessToken(ctx, req.Token)
+	if err != nil {
+		if errors.Is(err, ErrAccessTokenInvalid) || errors.Is(err, ErrAccessTokenExpired) || errors.Is(err, ErrAccessTokenRevoked) {
+			return &pb.TokenResponse{Valid: false}, nil
+		}
+		return nil, err
+	}
+
+	if err := s.checkBoundKey(claims, req.Token, req.DpopProof, dpop.Request{Method: req.HttpMethod, URI: req.HttpUri}); err != nil {
+		return nil, err
+	}
+
+	return &pb.TokenResponse{
+		Valid:     true,
+		UserId:    claims.UserID,
+		Email:     claims.Email,
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		Roles:     claims.Roles,
+		Scopes:    claims.Scopes(),
+		TokenId:   claims.ID,
+		Audience:  claims.Audience,
+	}, nil
+}
+
+func (s *authService) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*pb.RevokeTokenResponse, error) {
+	if req.Token == "" {
+		return nil, ErrBadRequest
+	}
+
+	if err := s.tokenService.RevokeToken(ctx, req.Token); err != nil {
+		return nil, err
+	}
+
+	return &pb.RevokeTokenResponse{}, nil
+}
+
+// Introspect - OAuth 2.0 token introspection (RFC 7662) для access и refresh токенов
+func (s *authService) Introspect(ctx context.Context, req *pb.IntrospectRequest) (*pb.IntrospectResponse, error) {
+	if req.Token == "" {
+		return nil, ErrBadRequest
+	}
+
+	result, err := s.tokenService.Introspect(ctx, req.Token, req.TokenTypeHint)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Active {
+		return &pb.IntrospectResponse{Active: false}, nil
+	}
+
+	claims := result.Claims
+	resp := &pb.IntrospectResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		Username:  claims.Email,
+		TokenType: result.TokenType,
+		Sub:       claims.Subject,
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		Email:     claims.Email,
+		Roles:     claims.Roles,
+	}
+	if claims.Actor != nil {
+		resp.ClientId = claims.Actor.Subject
+	}
+	if claims.ExpiresAt != nil {
+		resp.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		resp.Iat = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		resp.Nbf = claims.NotBefore.Unix()
+	}
+
+	return resp, nil
+}
+
+// ChangePassword меняет пароль пользователя по access токену и текущему паролю.
+// Все сессии пользователя, включая текущую, завершаются.
+func (s *authService) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+	if req.AccessToken == "" || req.CurrentPassword == "" || req.NewPassword == "" {
+		return nil, ErrBadRequest
+	}
+
+	user, err := s.authenticatedUser(ctx, req.AccessToken, req.DpopProof, dpop.Request{Method: req.HttpMethod, URI: req.HttpUri})
+	if err != nil {
+		return nil, err
+	}
+
+	// Подбор текущего пароля по украденному токену ограничивается тем же счетчиком, что и Login
+	ip := clientIP(ctx)
+	if err := s.attempts.Check(ctx, user.Email, ip); err != nil {
+		return nil, err
+	}
+
+	isValid, err := s.hasher.Verify(req.CurrentPassword, user.PasswordHash)
+	if err != nil {
+		s.log.Error("failed to verify password hash", logger.F("user_id", user.ID), logger.F("error", err))
+		s.recordLoginFailure(ctx, user.Email, ip)
+		return nil, ErrInvalidCredentials
+	}
+	if !isValid {
+		s.recordLoginFailure(ctx, user.Email, ip)
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.checkPasswordPolicy(req.NewPassword, user.UserName, user.Email); err != nil {
+		return nil, err
+	}
+	if err := s.checkPasswordReuse(ctx, user, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := s.hasher.Hash(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setPassword(ctx, user, passwordHash); err != nil {
+		return nil, err
+	}
+
+	if err := s.tokenService.RevokeUserTokens(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("password changed", logger.F("user_id", user.ID))
+	return &pb.ChangePasswordResponse{}, nil
+}
+
+// RequestPasswordReset отправляет ссылку сброса пароля. Ответ одинаковый, есть такой email или нет,
+// чтобы по нему нельзя было перебирать зарегистрированные адреса.
+func (s *authService) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.RequestPasswordResetResponse, error) {
+	if req.Email == "" {
+		return nil, ErrBadRequest
+	}
+
+	// Поиск аккаунта и письмо - после ответа: ни время, ни ошибки не выдают, зарегистрирован ли email
+	email := req.Email
+	s.detach(ctx, "password_reset", func(ctx context.Context) error {
+		return s.sendPasswordReset(ctx, email)
+	})
+
+	return &pb.RequestPasswordResetResponse{}, nil
+}
+
+// sendPasswordReset выпускает ссылку сброса для существующего аккаунта и ставит письмо в очередь
+func (s *authService) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.log.Debug("password reset requested for unknown email")
+			return nil
+		}
+		return err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	// Действует только последняя ссылка; в пределах cooldown новая не выпускается
+	now := time.Now()
+	if err := s.resetRepo.Issue(ctx, &domain.PasswordResetToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(s.config.PasswordResetTTL),
+		CreateAt:  now,
+	}, now.Add(-s.config.PasswordResetCooldown)); err != nil {
+		if errors.Is(err, repository.ErrResetTokenCooldown) {
+			s.log.Debug("password reset request throttled", logger.F("user_id", user.ID))
+			return nil
+		}
+		return err
+	}
+
+	body := fmt.Sprintf(passwordResetMailBody, linkWithToken(s.config.PasswordResetURL, token), s.config.PasswordResetTTL)
+	if err := enqueueMail(ctx, s.outbox, user.Email, passwordResetMailSubject, body); err != nil {
+		return err
+	}
+
+	s.log.Info("password reset requested", logger.F("user_id", user.ID))
+	return nil
+}
+
+// detach выполняет fn после ответа клиенту с собственным таймаутом; ошибки только логируются.
+// Если уже выполняется maxDetached операций, fn отбрасывается: поток запросов не копит горутины.
+func (s *authService) detach(ctx context.Context, operation string, fn func(ctx context.Context) error) {
+	select {
+	case s.detached <- struct{}{}:
+	default:
+		s.log.Warn("background operation dropped, too many in progress", logger.F("operation", operation))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), detachedTimeout)
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		defer func() { <-s.detached }()
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			s.log.Error("background operation failed", logger.F("operation", operation), logger.F("error", err))
+		}
+	}()
+}
+
+// Close дожидается операций, запущенных detach
+func (s *authService) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait for background operations: %w", ctx.Err())
+	}
+}
+
+// ResetPassword задает новый пароль по токену из письма. Токен одноразовый,
+// после сброса все сессии пользователя завершаются.
+func (s *authService) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordResponse, error) {
+	if req.Token == "" || req.NewPassword == "" {
+		return nil, ErrBadRequest
+	}
+
+	now := time.Now()
+	stored, err := s.resetRepo.GetByHash(ctx, hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrResetTokenNotFound) {
+			return nil, ErrResetTokenInvalid
+		}
+		return nil, err
+	}
+	if stored.UsedAt != nil || !now.Before(stored.ExpiresAt) {
+		return nil, ErrResetTokenInvalid
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID.String())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrResetTokenInvalid
+		}
+		return nil, err
+	}
+
+	// Токен не тратится на пароль, который политика все равно отклонит
+	if err := s.checkPasswordPolicy(req.NewPassword, user.UserName, user.Email); err != nil {
+		return nil, err
+	}
+	if err := s.checkPasswordReuse(ctx, user, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := s.hasher.Hash(req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	// Токен тратится только после смены пароля: сбой записи не сжигает единственную ссылку
+	if err := s.setPassword(ctx, user, passwordHash); err != nil {
+		return nil, err
+	}
+	if err := s.resetRepo.MarkUsed(ctx, stored.ID, now); err != nil {
+		if errors.Is(err, repository.ErrResetTokenUsed) {
+			return nil, ErrResetTokenInvalid
+		}
+		return nil, err
+	}
+	if err := s.resetRepo.InvalidateUser(ctx, user.ID, now); err != nil {
+		return nil, err
+	}
+	if err := s.tokenService.RevokeUserTokens(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("password reset", logger.F("user_id", user.ID))
+	return &pb.ResetPasswordResponse{}, nil
+}
+
+// authenticatedUser проверяет access токен (и DPoP proof для привязанного токена) и загружает его владельца
+func (s *authService) authenticatedUser(ctx context.Context, accessToken, proof string, req dpop.Request) (*domain.User, error) {
+	claims, err := s.tokenService.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	// Делегированный токен (act) или токен другого сервиса не дает управлять аккаунтом пользователя
+	if claims.Actor != nil || !s.ownAudience(claims.Audience) {
+		return nil, fmt.Errorf("%w: token is not issued for this service", ErrAccessTokenInvalid)
+	}
+	if err := s.checkBoundKey(claims, accessToken, proof, req); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAccessTokenRevoked
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// ownAudience - токен адресован самому сервису; без настроенного Audience подходит любой
+func (s *authService) ownAudience(audience []string) bool {
+	if len(s.config.Audience) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(audience, func(aud string) bool { return slices.Contains(s.config.Audience, aud) })
+}
+
+// checkBoundKey требует для токена, привязанного к ключу, proof этого ключа (RFC 9449)
+func (s *authService) checkBoundKey(claims *jwt.Claims, accessToken, proof string, req dpop.Request) error {
+	jkt := claims.BoundKey()
+	if jkt == "" {
+		return nil
+	}
+	if proof == "" {
+		return ErrDPoPProofRequired
+	}
+
+	req.AccessToken = accessToken
+	verified, err := s.verifyDPoP(proof, req)
+	if err != nil {
+		return err
+	}
+	if verified.JKT != jkt {
+		return fmt.Errorf("%w: token is bound to a different key", ErrDPoPProofInvalid)
+	}
+	return nil
+}
+
+// tokenEndpointKey проверяет необязательный DPoP proof запроса к Login/RefreshToken
+// и возвращает thumbprint ключа клиента (пустой, если proof нет)
+func (s *authService) tokenEndpointKey(proof string) (string, error) {
+	if proof == "" {
+		return "", nil
+	}
+
+	verified, err := s.verifyDPoP(proof, dpop.Request{
+		Method: http.MethodPost,
+		URI:    s.config.DPoPTokenEndpoint,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return verified.JKT, nil
+}
+
+func (s *authService) verifyDPoP(proof string, req dpop.Request) (*dpop.Proof, error) {
+	verified, err := s.dpop.Verify(proof, req)
+	if err != nil {
+		s.log.Debug("DPoP proof rejected", logger.F("error", err))
+		return nil, fmt.Errorf("%w: %v", ErrDPoPProofInvalid, err)
+	}
+	return verified, nil
+}
+
+// dummyPasswordHash хеширует случайный пароль текущим алгоритмом,
+// чтобы проверка для несуществующего пользователя стоила столько же, сколько настоящая
+func dummyPasswordHash(hasher password.PasswordHasher) (string, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	hash, err := hasher.Hash(secret)
+	if err != nil {
+		return "", fmt.Errorf("hash dummy password: %w", err)
+	}
+	return hash, nil
+}
+
+// Письмо со ссылкой сброса пароля: ссылка, срок действия
+const (
+	passwordResetMailSubject = "Password reset"
+	passwordResetMailBody    = `Someone requested a password reset for your account.
+
+To choose a new password, open this link:
+%s
+
+The link expires in %s and can be used once. If you did not request a reset, ignore this email.
+`
+)
+
+// Ответ Register в режиме HardenedErrors и письмо владельцу уже зарегистрированного email
+const (
+	registerAcceptedMessage  = "Check your email to finish registration."
+	accountExistsMailSubject = "Sign-up attempt with your email address"
+	accountExistsMailBody    = `Someone tried to create a new account with this email address, but you already have one.
+
+If it was you, sign in with your existing account or reset your password. If it was not you, no action is needed.
+`
+)
+
+// randomToken - 256 бит из crypto/rand в base64url
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// linkWithToken добавляет токен к ссылке параметром token
+func linkWithToken(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func tokenType(jkt string) string {
+	if jkt != "" {
+		return tokenTypeDPoP
+	}
+	return tokenTypeBearer
+}
+
 // TODO
 // Create(ctx context.Context, user *domain.User) error
 // GetByID(ctx context.Context, id string) (*domain.User, error)
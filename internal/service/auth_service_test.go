@@ -1 +1,880 @@
 package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"auth-service/internal/repository/memory"
+	"auth-service/internal/util/dpop"
+	"auth-service/internal/util/jwt"
+	"auth-service/internal/util/password"
+	"auth-service/internal/util/secretbox"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+	"github.com/google/uuid"
+)
+
+// fakeUserRepository - пользователи в памяти для тестов сервисов
+type fakeUserRepository struct {
+	mu    sync.Mutex
+	users map[uuid.UUID]*domain.User
+}
+
+func newFakeUserRepository(users ...*domain.User) *fakeUserRepository {
+	repo := &fakeUserRepository{users: make(map[uuid.UUID]*domain.User)}
+	for _, user := range users {
+		repo.users[user.ID] = user
+	}
+	return repo
+}
+
+func (r *fakeUserRepository) Create(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return repository.ErrUserExists
+		}
+	}
+	user.ID = uuid.New()
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.ID.String() == id {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakeUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakeUserRepository) Update(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *fakeUserRepository) UpdatePasswordHash(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	user.PasswordHash = passwordHash
+	return nil
+}
+
+func (r *fakeUserRepository) IncrementTokenVersion(ctx context.Context, id uuid.UUID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return 0, repository.ErrNotFound
+	}
+	user.TokenVersion++
+	return user.TokenVersion, nil
+}
+
+func (r *fakeUserRepository) GetTokenVersion(ctx context.Context, id string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if user.ID.String() == id {
+			return user.TokenVersion, nil
+		}
+	}
+	return 0, repository.ErrNotFound
+}
+
+func (r *fakeUserRepository) MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if user.EmailVerifiedAt == nil {
+		user.EmailVerifiedAt = &at
+	}
+	return nil
+}
+
+func (r *fakeUserRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, user := range r.users {
+		if user.ID.String() == id {
+			delete(r.users, key)
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+// newTestAuthService собирает AuthService; незаданные зависимости заменяются хранилищами в памяти
+func newTestAuthService(t *testing.T, deps AuthDependencies, config AuthConfig) AuthService {
+	t.Helper()
+
+	if deps.PasswordHistory == nil {
+		deps.PasswordHistory = memory.NewPasswordHistoryRepository()
+	}
+	if deps.PasswordResets == nil {
+		deps.PasswordResets = memory.NewPasswordResetRepository()
+	}
+	if deps.Verifications == nil {
+		deps.Verifications = memory.NewEmailVerificationRepository()
+	}
+	if deps.Outbox == nil {
+		deps.Outbox = memory.NewMailOutboxRepository()
+	}
+	if deps.MFA == nil {
+		deps.MFA = memory.NewMFARepository()
+	}
+	if deps.Secrets == nil {
+		deps.Secrets = newTestSecretBox(t)
+	}
+	if deps.WebAuthn == nil {
+		deps.WebAuthn = memory.NewWebAuthnRepository()
+	}
+	if deps.LoginCodes == nil {
+		deps.LoginCodes = memory.NewLoginCodeRepository()
+	}
+	if deps.Audit == nil {
+		deps.Audit = memory.NewAuditRepository()
+	}
+	if deps.Attempts == nil {
+		deps.Attempts = newTestAttemptTracker(t)
+	}
+	if deps.Hasher == nil {
+		deps.Hasher = password.NewBcrypt(4)
+	}
+	if deps.Policy == nil {
+		deps.Policy = password.NewPolicy(password.PolicyConfig{MinLength: 8}, nil)
+	}
+	if deps.DPoP == nil {
+		deps.DPoP = dpop.NewVerifier(dpop.Config{ReplayCacheSize: 16})
+	}
+	s, err := NewAuthService(deps, config, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewAuthService: %v", err)
+	}
+	return s
+}
+
+func newTestSecretBox(t *testing.T) *secretbox.Box {
+	t.Helper()
+
+	box, err := secretbox.New(secretbox.Keys{Current: 1, Keys: map[int][]byte{1: bytes.Repeat([]byte{7}, secretbox.KeySize)}})
+	if err != nil {
+		t.Fatalf("secretbox.New: %v", err)
+	}
+	return box
+}
+
+func TestAuthService_LoginRehashesLegacyPassword(t *testing.T) {
+	ctx := context.Background()
+
+	legacy, err := password.NewBcrypt(4).Hash("secret-password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: legacy}
+	users := newFakeUserRepository(user)
+
+	hasher := password.NewHasher(
+		password.NewArgon2id(password.Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}),
+		password.NewBcrypt(4),
+	)
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  users,
+		Tokens: newTestTokenService(t, user),
+		Hasher: hasher,
+	}, AuthConfig{})
+
+	if _, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "wrong-password"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if stored, _ := users.GetByID(ctx, user.ID.String()); stored.PasswordHash != legacy {
+		t.Fatal("hash must not change after a failed login")
+	}
+
+	if _, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"}); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	stored, _ := users.GetByID(ctx, user.ID.String())
+	if algorithm, _ := password.Identify(stored.PasswordHash); algorithm != password.AlgorithmArgon2id {
+		t.Fatalf("hash algorithm after login = %q, want argon2id", algorithm)
+	}
+
+	// Новый хеш подходит к тому же паролю
+	if _, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"}); err != nil {
+		t.Fatalf("Login after rehash: %v", err)
+	}
+}
+
+func TestAuthService_PasswordHistoryBlocksReuse(t *testing.T) {
+	ctx := context.Background()
+
+	hasher := password.NewBcrypt(4)
+	initial, err := hasher.Hash("password-0")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Email: "user@example.com", PasswordHash: initial}
+	users := newFakeUserRepository(user)
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  users,
+		Tokens: newTestTokenService(t, user),
+		Hasher: hasher,
+		Policy: password.NewPolicy(password.PolicyConfig{MinLength: 8, HistorySize: 2}, nil),
+	}, AuthConfig{}).(*authService)
+
+	change := func(plain string) error {
+		if err := s.checkPasswordReuse(ctx, user, plain); err != nil {
+			return err
+		}
+		passwordHash, err := hasher.Hash(plain)
+		if err != nil {
+			return err
+		}
+		return s.setPassword(ctx, user, passwordHash)
+	}
+
+	for _, plain := range []string{"password-1", "password-2", "password-3"} {
+		if err := change(plain); err != nil {
+			t.Fatalf("change to %q: %v", plain, err)
+		}
+	}
+
+	// Текущий и два предыдущих пароля повторять нельзя
+	for _, plain := range []string{"password-3", "password-2", "password-1"} {
+		err := change(plain)
+		var policyErr *PasswordPolicyError
+		if !errors.As(err, &policyErr) || policyErr.Violations[0].Code != password.ViolationReused {
+			t.Errorf("reuse of %q: err = %v, want PASSWORD_RECENTLY_USED", plain, err)
+		}
+	}
+
+	// Более старый пароль вытеснен из истории
+	if err := change("password-0"); err != nil {
+		t.Errorf("password outside of history: %v", err)
+	}
+	if stored, _ := users.GetByID(ctx, user.ID.String()); stored.PasswordHash != user.PasswordHash {
+		t.Error("new hash was not stored")
+	}
+}
+
+func TestAuthService_ChangePasswordEndsSessions(t *testing.T) {
+	ctx := context.Background()
+
+	hasher := password.NewBcrypt(4)
+	initial, err := hasher.Hash("old-password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com", PasswordHash: initial}
+	users := newFakeUserRepository(user)
+
+	tokens := NewTokenService(users, memory.NewRefreshTokenRepository(), memory.NewTokenRevocationRepository(), jwt.NewManager(jwt.Config{
+		AccessTokenSecret:  "access",
+		RefreshTokenSecret: "refresh",
+		AccessTokenExpiry:  time.Minute,
+		RefreshTokenExpiry: time.Hour,
+	}), ValidationCacheConfig{Size: 16, TTL: time.Minute, NegativeTTL: time.Minute}, newTestLogger(t))
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  users,
+		Tokens: tokens,
+		Hasher: hasher,
+		Policy: password.NewPolicy(password.PolicyConfig{MinLength: 8, HistorySize: 3}, nil),
+	}, AuthConfig{})
+
+	session, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "old-password"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	// Результат проверки попадает в кеш и должен быть сброшен сменой пароля
+	if resp, err := s.ValidateToken(ctx, &pb.TokenRequest{Token: session.AccessToken}); err != nil || !resp.Valid {
+		t.Fatalf("ValidateToken before change = %v, %v", resp, err)
+	}
+
+	if _, err := s.ChangePassword(ctx, &pb.ChangePasswordRequest{
+		AccessToken:     session.AccessToken,
+		CurrentPassword: "wrong-password",
+		NewPassword:     "new-password",
+	}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong current password: err = %v, want ErrInvalidCredentials", err)
+	}
+
+	var policyErr *PasswordPolicyError
+	if _, err := s.ChangePassword(ctx, &pb.ChangePasswordRequest{
+		AccessToken:     session.AccessToken,
+		CurrentPassword: "old-password",
+		NewPassword:     "old-password",
+	}); !errors.As(err, &policyErr) {
+		t.Fatalf("same password: err = %v, want PasswordPolicyError", err)
+	}
+
+	if _, err := s.ChangePassword(ctx, &pb.ChangePasswordRequest{
+		AccessToken:     session.AccessToken,
+		CurrentPassword: "old-password",
+		NewPassword:     "new-password",
+	}); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	if resp, err := s.ValidateToken(ctx, &pb.TokenRequest{Token: session.AccessToken}); err != nil || resp.Valid {
+		t.Errorf("old access token after change = %v, %v; want invalid", resp, err)
+	}
+	if _, err := s.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: session.RefreshToken}); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("old refresh token after change: err = %v, want ErrRefreshTokenRevoked", err)
+	}
+
+	if _, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "old-password"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("login with old password: err = %v, want ErrInvalidCredentials", err)
+	}
+	fresh, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "new-password"})
+	if err != nil {
+		t.Fatalf("login with new password: %v", err)
+	}
+	if resp, err := s.ValidateToken(ctx, &pb.TokenRequest{Token: fresh.AccessToken}); err != nil || !resp.Valid {
+		t.Errorf("new access token = %v, %v; want valid", resp, err)
+	}
+}
+
+func TestAuthService_ChangePasswordLockout(t *testing.T) {
+	ctx := context.Background()
+
+	hasher := password.NewBcrypt(4)
+	initial, err := hasher.Hash("old-password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com", PasswordHash: initial}
+	attempts := NewAttemptTracker(memory.NewLoginAttemptRepository(), AttemptTrackerConfig{
+		LockoutThreshold: 3,
+		LockoutDuration:  time.Hour,
+		Window:           time.Hour,
+	}, newTestLogger(t))
+	tokens := newTestTokenService(t, user)
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:    newFakeUserRepository(user),
+		Attempts: attempts,
+		Tokens:   tokens,
+		Hasher:   hasher,
+	}, AuthConfig{})
+
+	// Украденный access токен: подбирать текущий пароль можно только до блокировки
+	session, err := tokens.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.ChangePassword(ctx, &pb.ChangePasswordRequest{
+			AccessToken:     session.AccessToken,
+			CurrentPassword: "wrong-password",
+			NewPassword:     "new-password",
+		}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("wrong current password #%d: err = %v, want ErrInvalidCredentials", i+1, err)
+		}
+	}
+	_, err = s.ChangePassword(ctx, &pb.ChangePasswordRequest{
+		AccessToken:     session.AccessToken,
+		CurrentPassword: "old-password",
+		NewPassword:     "new-password",
+	})
+	retryAfter(t, err, ErrAccountLocked)
+
+	// Блокировка общая с Login
+	_, err = s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "old-password"})
+	retryAfter(t, err, ErrAccountLocked)
+}
+
+func TestAuthService_ChangePasswordCorruptedHash(t *testing.T) {
+	ctx := context.Background()
+
+	user := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com", PasswordHash: "not-a-password-hash"}
+	tokens := newTestTokenService(t, user)
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  newFakeUserRepository(user),
+		Tokens: tokens,
+	}, AuthConfig{})
+
+	session, err := tokens.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	if _, err := s.ChangePassword(ctx, &pb.ChangePasswordRequest{
+		AccessToken:     session.AccessToken,
+		CurrentPassword: "old-password",
+		NewPassword:     "new-password",
+	}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("corrupted hash: err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+// linkTokenFromOutbox достает токен из ссылки в единственном письме очереди
+func linkTokenFromOutbox(t *testing.T, outbox repository.MailOutboxRepository) (string, string) {
+	t.Helper()
+
+	messages, err := outbox.ClaimPending(context.Background(), time.Now(), time.Minute, 10)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("ClaimPending = %d messages, %v; want 1", len(messages), err)
+	}
+	_, rest, ok := strings.Cut(messages[0].Body, "?token=")
+	if !ok {
+		t.Fatalf("no reset link in mail body:\n%s", messages[0].Body)
+	}
+	token, _, _ := strings.Cut(rest, "\n")
+	return messages[0].Recipient, token
+}
+
+func TestAuthService_PasswordReset(t *testing.T) {
+	ctx := context.Background()
+
+	hasher := password.NewBcrypt(4)
+	initial, err := hasher.Hash("old-password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com", PasswordHash: initial}
+	users := newFakeUserRepository(user)
+	resets := memory.NewPasswordResetRepository()
+	outbox := memory.NewMailOutboxRepository()
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:          users,
+		PasswordResets: resets,
+		Outbox:         outbox,
+		Tokens:         newTestTokenService(t, user),
+		Hasher:         hasher,
+	}, AuthConfig{
+		PasswordResetTTL: time.Minute,
+		PasswordResetURL: "https://app.example.com/reset",
+	})
+
+	// Неизвестный email: тот же ответ, письма нет
+	resp, err := s.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: "nobody@example.com"})
+	if err != nil || resp == nil {
+		t.Fatalf("unknown email = %v, %v; want empty response", resp, err)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if pending, _ := outbox.ClaimPending(ctx, time.Now(), time.Minute, 10); len(pending) != 0 {
+		t.Fatalf("mail queued for unknown email")
+	}
+
+	session, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "old-password"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	if _, err := s.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: user.Email}); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	recipient, token := linkTokenFromOutbox(t, outbox)
+	if recipient != user.Email {
+		t.Fatalf("mail recipient = %q, want %q", recipient, user.Email)
+	}
+
+	if _, err := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: "forged", NewPassword: "new-password"}); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Fatalf("forged token: err = %v, want ErrResetTokenInvalid", err)
+	}
+	// Отклоненный политикой пароль не тратит токен
+	var policyErr *PasswordPolicyError
+	if _, err := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: token, NewPassword: "short"}); !errors.As(err, &policyErr) {
+		t.Fatalf("short password: err = %v, want PasswordPolicyError", err)
+	}
+
+	if _, err := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: token, NewPassword: "new-password"}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if _, err := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: token, NewPassword: "another-password"}); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("second use: err = %v, want ErrResetTokenInvalid", err)
+	}
+
+	if _, err := s.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: session.RefreshToken}); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("old refresh token after reset: err = %v, want ErrRefreshTokenRevoked", err)
+	}
+	if _, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "new-password"}); err != nil {
+		t.Errorf("login with new password: %v", err)
+	}
+
+	// Просроченный токен: ссылка выпущена сервисом с TTL короче любого запроса
+	expiring := newTestAuthService(t, AuthDependencies{
+		Users:          users,
+		PasswordResets: resets,
+		Outbox:         outbox,
+		Tokens:         newTestTokenService(t, user),
+		Hasher:         hasher,
+	}, AuthConfig{
+		PasswordResetTTL: time.Nanosecond,
+		PasswordResetURL: "https://app.example.com/reset",
+	})
+	if _, err := expiring.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: user.Email}); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	if err := expiring.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	_, expired := linkTokenFromOutbox(t, outbox)
+	if _, err := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: expired, NewPassword: "third-password"}); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("expired token: err = %v, want ErrResetTokenInvalid", err)
+	}
+}
+
+// flakyPasswordStore не сохраняет пароль с первой попытки
+type flakyPasswordStore struct {
+	*fakeUserRepository
+	failed bool
+}
+
+func (r *flakyPasswordStore) UpdatePasswordHash(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	if !r.failed {
+		r.failed = true
+		return errors.New("connection reset")
+	}
+	return r.fakeUserRepository.UpdatePasswordHash(ctx, id, passwordHash)
+}
+
+func TestAuthService_ResetPasswordKeepsTokenOnFailure(t *testing.T) {
+	ctx := context.Background()
+
+	user := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com"}
+	users := &flakyPasswordStore{fakeUserRepository: newFakeUserRepository(user)}
+	outbox := memory.NewMailOutboxRepository()
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  users,
+		Outbox: outbox,
+		Tokens: newTestTokenService(t, user),
+	}, AuthConfig{
+		PasswordResetTTL: time.Hour,
+		PasswordResetURL: "https://app.example.com/reset",
+	})
+
+	if _, err := s.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: user.Email}); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	_, token := linkTokenFromOutbox(t, outbox)
+
+	if _, err := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: token, NewPassword: "new-password"}); err == nil {
+		t.Fatal("ResetPassword succeeded although the password was not stored")
+	}
+	// Ссылка не сгорела: повтор после сбоя проходит
+	if _, err := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: token, NewPassword: "new-password"}); err != nil {
+		t.Fatalf("retry after failure: %v", err)
+	}
+}
+
+func TestAuthService_PasswordResetCooldown(t *testing.T) {
+	ctx := context.Background()
+
+	user := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com"}
+	outbox := memory.NewMailOutboxRepository()
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  newFakeUserRepository(user),
+		Outbox: outbox,
+		Tokens: newTestTokenService(t, user),
+	}, AuthConfig{
+		PasswordResetTTL:      time.Hour,
+		PasswordResetURL:      "https://app.example.com/reset",
+		PasswordResetCooldown: time.Hour,
+	})
+
+	// Поток запросов на один адрес дает одно письмо, а ссылка из него остается действующей
+	for range 20 {
+		if _, err := s.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: user.Email}); err != nil {
+			t.Fatalf("RequestPasswordReset: %v", err)
+		}
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	_, token := linkTokenFromOutbox(t, outbox)
+	if _, err := s.ResetPassword(ctx, &pb.ResetPasswordRequest{Token: token, NewPassword: "new-password"}); err != nil {
+		t.Fatalf("ResetPassword with the first link: %v", err)
+	}
+}
+
+func TestAuthService_DetachIsBounded(t *testing.T) {
+	s := newTestAuthService(t, AuthDependencies{}, AuthConfig{}).(*authService)
+
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(maxDetached)
+	for range maxDetached {
+		s.detach(context.Background(), "blocked", func(ctx context.Context) error {
+			started.Done()
+			<-release
+			return nil
+		})
+	}
+	started.Wait()
+
+	// Все слоты заняты: новая операция отбрасывается, а не ждет и не порождает горутину
+	ran := false
+	s.detach(context.Background(), "dropped", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	close(release)
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if ran {
+		t.Error("operation ran beyond maxDetached")
+	}
+}
+
+func TestAuthService_EmailVerification(t *testing.T) {
+	ctx := context.Background()
+
+	users := newFakeUserRepository()
+	outbox := memory.NewMailOutboxRepository()
+	tokens := NewTokenService(users, memory.NewRefreshTokenRepository(), memory.NewTokenRevocationRepository(), jwt.NewManager(jwt.Config{
+		AccessTokenSecret:  "access",
+		RefreshTokenSecret: "refresh",
+		AccessTokenExpiry:  time.Minute,
+		RefreshTokenExpiry: time.Hour,
+	}), ValidationCacheConfig{}, newTestLogger(t), WithUnverifiedRestriction())
+
+	newService := func(mode string) AuthService {
+		return newTestAuthService(t, AuthDependencies{
+			Users:  users,
+			Outbox: outbox,
+			Tokens: tokens,
+		}, AuthConfig{
+			UnverifiedLoginMode:        mode,
+			EmailVerificationTTL:       time.Hour,
+			EmailVerificationURL:       "https://app.example.com/verify",
+			VerificationResendInterval: time.Hour,
+		})
+	}
+	s := newService(UnverifiedLoginRestricted)
+
+	if _, err := s.Register(ctx, &pb.RegisterRequest{UserName: "alice", Email: "alice@example.com", Password: "secret-password"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	recipient, token := linkTokenFromOutbox(t, outbox)
+	if recipient != "alice@example.com" {
+		t.Fatalf("mail recipient = %q", recipient)
+	}
+
+	// Повторная отправка ограничена интервалом
+	if _, err := s.ResendVerification(ctx, &pb.ResendVerificationRequest{Email: "alice@example.com"}); err != nil {
+		t.Fatalf("ResendVerification: %v", err)
+	}
+	if pending, _ := outbox.ClaimPending(ctx, time.Now(), time.Minute, 10); len(pending) != 0 {
+		t.Fatal("verification mail resent before interval")
+	}
+
+	if _, err := newService(UnverifiedLoginDeny).Login(ctx, &pb.LoginRequest{Email: "alice@example.com", Password: "secret-password"}); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("deny mode: err = %v, want ErrEmailNotVerified", err)
+	}
+
+	session, err := s.Login(ctx, &pb.LoginRequest{Email: "alice@example.com", Password: "secret-password"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	resp, err := s.ValidateToken(ctx, &pb.TokenRequest{Token: session.AccessToken})
+	if err != nil || !resp.Valid || len(resp.Roles) != 0 || len(resp.Scopes) != 1 || resp.Scopes[0] != domain.ScopeVerifyEmail {
+		t.Fatalf("restricted token = %+v, %v; want only %s scope", resp, err, domain.ScopeVerifyEmail)
+	}
+
+	if _, err := s.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: token}); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if _, err := s.VerifyEmail(ctx, &pb.VerifyEmailRequest{Token: token}); !errors.Is(err, ErrVerificationTokenInvalid) {
+		t.Errorf("second use: err = %v, want ErrVerificationTokenInvalid", err)
+	}
+
+	// После подтверждения обновленный токен получает роли пользователя
+	refreshed, err := s.RefreshToken(ctx, &pb.RefreshTokenRequest{RefreshToken: session.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	resp, err = s.ValidateToken(ctx, &pb.TokenRequest{Token: refreshed.AccessToken})
+	if err != nil || !resp.Valid || len(resp.Roles) != 1 || resp.Roles[0] != domain.RoleUser {
+		t.Errorf("token after verification = %+v, %v; want role %s", resp, err, domain.RoleUser)
+	}
+}
+
+// countingHasher считает проверки пароля, чтобы убедиться, что фиктивное сравнение выполняется
+type countingHasher struct {
+	password.PasswordHasher
+	verifies int
+}
+
+func (h *countingHasher) Verify(plain, encoded string) (bool, error) {
+	h.verifies++
+	return h.PasswordHasher.Verify(plain, encoded)
+}
+
+func TestAuthService_HardenedErrors(t *testing.T) {
+	ctx := context.Background()
+
+	hasher := &countingHasher{PasswordHasher: password.NewBcrypt(4)}
+	passwordHash, err := hasher.Hash("secret-password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com", PasswordHash: passwordHash}
+	users := newFakeUserRepository(user)
+	outbox := memory.NewMailOutboxRepository()
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  users,
+		Outbox: outbox,
+		Tokens: newTestTokenService(t, user),
+		Hasher: hasher,
+	}, AuthConfig{
+		EmailVerificationTTL: time.Hour,
+		EmailVerificationURL: "https://app.example.com/verify",
+		HardenedErrors:       true,
+	})
+
+	// Неизвестный email и неверный пароль неотличимы, пароль проверяется в обоих случаях
+	_, unknownErr := s.Login(ctx, &pb.LoginRequest{Email: "nobody@example.com", Password: "secret-password"})
+	_, wrongErr := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "wrong-password"})
+	if !errors.Is(unknownErr, ErrInvalidCredentials) || !errors.Is(wrongErr, ErrInvalidCredentials) {
+		t.Fatalf("unknown email: %v, wrong password: %v; want ErrInvalidCredentials for both", unknownErr, wrongErr)
+	}
+	if hasher.verifies != 2 {
+		t.Errorf("password verifications = %d, want 2", hasher.verifies)
+	}
+
+	taken, err := s.Register(ctx, &pb.RegisterRequest{UserName: "mallory", Email: user.Email, Password: "another-password"})
+	if err != nil {
+		t.Fatalf("Register existing email: %v", err)
+	}
+	messages, _ := outbox.ClaimPending(ctx, time.Now(), time.Minute, 10)
+	if len(messages) != 1 || messages[0].Recipient != user.Email || messages[0].Subject != accountExistsMailSubject {
+		t.Fatalf("owner notification = %+v", messages)
+	}
+
+	fresh, err := s.Register(ctx, &pb.RegisterRequest{UserName: "bob", Email: "bob@example.com", Password: "another-password"})
+	if err != nil {
+		t.Fatalf("Register new email: %v", err)
+	}
+	if taken.UserId != fresh.UserId || taken.Message != fresh.Message || fresh.UserId != "" || fresh.Message == "" {
+		t.Errorf("responses differ: existing = %+v, new = %+v", taken, fresh)
+	}
+	if recipient, _ := linkTokenFromOutbox(t, outbox); recipient != "bob@example.com" {
+		t.Errorf("verification recipient = %q", recipient)
+	}
+}
+
+// brokenHasher не умеет хешировать - как хешер с неверной конфигурацией
+type brokenHasher struct {
+	password.PasswordHasher
+}
+
+func (brokenHasher) Hash(plain string) (string, error) {
+	return "", errors.New("hasher is misconfigured")
+}
+
+func TestNewAuthService_DummyHashFailure(t *testing.T) {
+	_, err := NewAuthService(AuthDependencies{Hasher: brokenHasher{password.NewBcrypt(4)}}, AuthConfig{HardenedErrors: true}, newTestLogger(t))
+	if err == nil {
+		t.Fatal("NewAuthService with failing hasher: want error")
+	}
+}
+
+func TestAuthService_CorruptedHashCountsAsFailure(t *testing.T) {
+	ctx := context.Background()
+
+	user := &domain.User{ID: uuid.New(), Email: "alice@example.com", PasswordHash: "not-a-password-hash"}
+	attempts := NewAttemptTracker(memory.NewLoginAttemptRepository(), AttemptTrackerConfig{
+		AccountThreshold: 1,
+		BaseDelay:        time.Hour,
+		MaxDelay:         time.Hour,
+		Window:           time.Hour,
+	}, newTestLogger(t))
+	s := newTestAuthService(t, AuthDependencies{
+		Users:    newFakeUserRepository(user),
+		Attempts: attempts,
+		Tokens:   newTestTokenService(t, user),
+	}, AuthConfig{HardenedErrors: true})
+
+	if _, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("corrupted hash: err = %v, want ErrInvalidCredentials", err)
+	}
+	_, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"})
+	retryAfter(t, err, ErrLoginThrottled)
+}
+
+// failingOutbox не принимает письма
+type failingOutbox struct {
+	repository.MailOutboxRepository
+}
+
+func (failingOutbox) Enqueue(ctx context.Context, msg *domain.OutboxMessage) error {
+	return errors.New("outbox is unavailable")
+}
+
+// Ошибка записи для существующего email не доходит до клиента и не отличает его от неизвестного
+func TestAuthService_PasswordResetHidesWriteErrors(t *testing.T) {
+	ctx := context.Background()
+
+	user := &domain.User{ID: uuid.New(), Email: "alice@example.com"}
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  newFakeUserRepository(user),
+		Outbox: failingOutbox{},
+		Tokens: newTestTokenService(t, user),
+	}, AuthConfig{PasswordResetTTL: time.Minute, PasswordResetURL: "https://app.example.com/reset"})
+
+	for _, email := range []string{user.Email, "nobody@example.com"} {
+		if _, err := s.RequestPasswordReset(ctx, &pb.RequestPasswordResetRequest{Email: email}); err != nil {
+			t.Errorf("RequestPasswordReset(%q): %v", email, err)
+		}
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
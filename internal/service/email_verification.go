@@ -0,0 +1,127 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+)
+
+var ErrVerificationTokenInvalid = errors.New("email verification token is invalid or has expired")
+
+// Письмо со ссылкой подтверждения email: ссылка, срок действия
+const (
+	verificationMailSubject = "Confirm your email address"
+	verificationMailBody    = `Welcome! Please confirm your email address by opening this link:
+%s
+
+The link expires in %s. If you did not create an account, ignore this email.
+`
+)
+
+// VerifyEmail подтверждает email по токену из письма. Ограниченные токены, выданные до подтверждения,
+// остаются ограниченными; полные права клиент получает обновлением токенов или новым входом.
+func (s *authService) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerifyEmailResponse, error) {
+	if req.Token == "" {
+		return nil, ErrBadRequest
+	}
+
+	now := time.Now()
+	stored, err := s.verifyRepo.GetByHash(ctx, hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrVerificationTokenNotFound) {
+			return nil, ErrVerificationTokenInvalid
+		}
+		return nil, err
+	}
+	if stored.UsedAt != nil || !now.Before(stored.ExpiresAt) {
+		return nil, ErrVerificationTokenInvalid
+	}
+
+	if err := s.verifyRepo.MarkUsed(ctx, stored.ID, now); err != nil {
+		if errors.Is(err, repository.ErrVerificationTokenUsed) {
+			return nil, ErrVerificationTokenInvalid
+		}
+		return nil, err
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, stored.UserID, now); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrVerificationTokenInvalid
+		}
+		return nil, err
+	}
+
+	s.log.Info("email verified", logger.F("user_id", stored.UserID))
+	return &pb.VerifyEmailResponse{}, nil
+}
+
+// ResendVerification повторно отправляет письмо подтверждения. Ответ одинаковый для неизвестного,
+// уже подтвержденного адреса и слишком частых запросов.
+func (s *authService) ResendVerification(ctx context.Context, req *pb.ResendVerificationRequest) (*pb.ResendVerificationResponse, error) {
+	if req.Email == "" {
+		return nil, ErrBadRequest
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return &pb.ResendVerificationResponse{}, nil
+		}
+		return nil, err
+	}
+	if user.EmailVerifiedAt != nil {
+		return &pb.ResendVerificationResponse{}, nil
+	}
+
+	lastIssuedAt, err := s.verifyRepo.LastIssuedAt(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if lastIssuedAt != nil && time.Since(*lastIssuedAt) < s.config.VerificationResendInterval {
+		s.log.Debug("email verification resend throttled", logger.F("user_id", user.ID))
+		return &pb.ResendVerificationResponse{}, nil
+	}
+
+	if err := s.sendVerification(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &pb.ResendVerificationResponse{}, nil
+}
+
+// sendVerification выпускает новый токен подтверждения (прежние перестают действовать) и ставит письмо в очередь
+func (s *authService) sendVerification(ctx context.Context, user *domain.User) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.verifyRepo.InvalidateUser(ctx, user.ID, now); err != nil {
+		return err
+	}
+	if err := s.verifyRepo.Create(ctx, &domain.EmailVerificationToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(s.config.EmailVerificationTTL),
+		CreateAt:  now,
+	}); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(verificationMailBody, linkWithToken(s.config.EmailVerificationURL, token), s.config.EmailVerificationTTL)
+	if err := enqueueMail(ctx, s.outbox, user.Email, verificationMailSubject, body); err != nil {
+		return err
+	}
+
+	s.log.Info("email verification sent", logger.F("user_id", user.ID))
+	return nil
+}
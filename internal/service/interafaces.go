@@ -1,9 +1,61 @@
 package service
 
 import (
+	"auth-service/internal/domain"
+	"auth-service/internal/util/jwt"
+	"context"
+
+	"github.com/google/uuid"
+
 	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
 )
 
 type AuthService interface {
 	pb.AuthServiceServer
+	// Close дожидается операций, запущенных после ответа клиенту (письма), или отмены ctx
+	Close(ctx context.Context) error
+}
+
+// KeyService публикует публичные ключи проверки токенов
+type KeyService interface {
+	GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*pb.GetJWKSResponse, error)
+}
+
+// KeyRotator управляет жизненным циклом ключей подписи (next -> active -> retiring -> revoked)
+type KeyRotator interface {
+	Sync(ctx context.Context) error
+	Rotate(ctx context.Context) error
+	Run(ctx context.Context)
+}
+
+// TokenService выпускает токены и ротирует сохраненные refresh токены.
+// jkt - thumbprint DPoP ключа клиента (пустой для bearer токенов).
+type TokenService interface {
+	IssueTokens(ctx context.Context, user *domain.User, jkt string) (*jwt.TokenPair, error)
+	RefreshTokens(ctx context.Context, refreshToken, jkt string) (*jwt.TokenPair, error)
+	ValidateAccessToken(ctx context.Context, accessToken string) (*jwt.Claims, error)
+	RevokeToken(ctx context.Context, token string) error
+	RevokeUserTokens(ctx context.Context, userID uuid.UUID) error
+	Introspect(ctx context.Context, token, tokenTypeHint string) (*TokenIntrospection, error)
+}
+
+// TokenExchangeService обменивает токены между сервисами (RFC 8693)
+type TokenExchangeService interface {
+	ExchangeToken(ctx context.Context, req *pb.TokenExchangeRequest) (*pb.TokenExchangeResponse, error)
+}
+
+// MailDispatcher отправляет письма из outbox; Dispatch возвращает число отправленных
+type MailDispatcher interface {
+	Dispatch(ctx context.Context) (int, error)
+}
+
+// AttemptTracker защищает вход от перебора паролей: считает неудачи по аккаунту и IP,
+// задерживает попытки с экспоненциальным ростом и временно блокирует аккаунт
+type AttemptTracker interface {
+	// Check возвращает *RetryAfterError, если вход для email или ip сейчас запрещен
+	Check(ctx context.Context, email, ip string) error
+	Failure(ctx context.Context, email, ip string) error
+	Success(ctx context.Context, email string) error
+	// Unlock снимает задержку и блокировку аккаунта (администратором)
+	Unlock(ctx context.Context, email string) error
 }
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"auth-service/internal/util/jwt"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// KeyRotationConfig - параметры ротации ключей подписи
+type KeyRotationConfig struct {
+	Algorithm        string        // алгоритм новых ключей (RS256, ES256, EdDSA)
+	RotationInterval time.Duration // как долго ключ остается active
+	GracePeriod      time.Duration // сколько принимаются токены выведенного ключа, >= времени жизни access токена
+	CheckInterval    time.Duration // как часто проверять хранилище
+}
+
+type keyRotator struct {
+	repo    repository.SigningKeyRepository
+	keyRing jwt.KeyRing
+	config  KeyRotationConfig
+	log     logger.Logger
+	now     func() time.Time
+}
+
+func NewKeyRotator(repo repository.SigningKeyRepository, keyRing jwt.KeyRing, config KeyRotationConfig, log logger.Logger) KeyRotator {
+	return &keyRotator{
+		repo:    repo,
+		keyRing: keyRing,
+		config:  config,
+		log:     log.With(logger.F("layer", "service"), logger.F("component", "key_rotator")),
+		now:     time.Now,
+	}
+}
+
+// Sync загружает ключи из хранилища в менеджер, при первом запуске создает active и next
+func (r *keyRotator) Sync(ctx context.Context) error {
+	keys, err := r.repo.ListValid(ctx)
+	if err != nil {
+		return err
+	}
+
+	created := false
+	for _, state := range []domain.SigningKeyState{domain.SigningKeyActive, domain.SigningKeyNext} {
+		if findSigningKey(keys, state) != nil {
+			continue
+		}
+		if err := r.createKey(ctx, state); err != nil {
+			return err
+		}
+		created = true
+	}
+
+	if created {
+		if keys, err = r.repo.ListValid(ctx); err != nil {
+			return err
+		}
+	}
+
+	return r.load(keys)
+}
+
+// Rotate выводит active ключ, активирует next и создает новый next
+func (r *keyRotator) Rotate(ctx context.Context) error {
+	keys, err := r.repo.ListValid(ctx)
+	if err != nil {
+		return err
+	}
+
+	activeID := ""
+	if active := findSigningKey(keys, domain.SigningKeyActive); active != nil {
+		activeID = active.ID
+	}
+
+	next, err := r.generateKey(domain.SigningKeyNext)
+	if err != nil {
+		return err
+	}
+
+	err = r.repo.Rotate(ctx, activeID, next, r.now())
+	switch {
+	case errors.Is(err, repository.ErrRotationConflict):
+		// Другой инстанс уже выполнил ротацию - просто подтягиваем его ключи
+		r.log.Info("signing keys already rotated by another instance")
+	case err != nil:
+		return err
+	default:
+		r.log.Info("signing keys rotated",
+			logger.F("retired_kid", activeID),
+			logger.F("next_kid", next.ID),
+		)
+	}
+
+	return r.Sync(ctx)
+}
+
+// Run периодически отзывает ключи с истекшим grace периодом и ротирует active ключ
+func (r *keyRotator) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.tick(ctx); err != nil {
+				r.log.Error("signing key rotation check failed", logger.F("error", err))
+			}
+		}
+	}
+}
+
+func (r *keyRotator) tick(ctx context.Context) error {
+	now := r.now()
+
+	revoked, err := r.repo.RevokeRetired(ctx, now.Add(-r.config.GracePeriod))
+	if err != nil {
+		return err
+	}
+	if revoked > 0 {
+		r.log.Info("retired signing keys revoked", logger.F("count", revoked))
+	}
+
+	keys, err := r.repo.ListValid(ctx)
+	if err != nil {
+		return err
+	}
+
+	active := findSigningKey(keys, domain.SigningKeyActive)
+	if active == nil || active.ActivatedAt == nil || !active.ActivatedAt.Add(r.config.RotationInterval).After(now) {
+		return r.Rotate(ctx)
+	}
+
+	return r.Sync(ctx)
+}
+
+// load передает ключи в менеджер: active подписывает, next/active/retiring проверяют
+func (r *keyRotator) load(keys []*domain.SigningKey) error {
+	now := r.now()
+
+	var (
+		signingKey       *jwt.Key
+		verificationKeys []*jwt.Key
+	)
+
+	for _, stored := range keys {
+		if stored.State == domain.SigningKeyRetiring && stored.RetiredAt != nil &&
+			!stored.RetiredAt.Add(r.config.GracePeriod).After(now) {
+			continue
+		}
+
+		privateKey, err := jwt.ParsePrivateKeyPEM([]byte(stored.PrivateKey))
+		if err != nil {
+			return fmt.Errorf("parse signing key %s: %w", stored.ID, err)
+		}
+
+		key, err := jwt.NewSigningKey(stored.ID, privateKey)
+		if err != nil {
+			return fmt.Errorf("load signing key %s: %w", stored.ID, err)
+		}
+
+		if stored.State == domain.SigningKeyActive {
+			signingKey = key
+			continue
+		}
+		verificationKeys = append(verificationKeys, key)
+	}
+
+	if signingKey == nil {
+		return errors.New("no active signing key in store")
+	}
+
+	r.keyRing.SetKeys(signingKey, verificationKeys)
+	return nil
+}
+
+func (r *keyRotator) createKey(ctx context.Context, state domain.SigningKeyState) error {
+	key, err := r.generateKey(state)
+	if err != nil {
+		return err
+	}
+
+	if state == domain.SigningKeyActive {
+		key.ActivatedAt = &key.CreateAt
+	}
+
+	err = r.repo.Create(ctx, key)
+	if errors.Is(err, repository.ErrSigningKeyExists) {
+		// Создан параллельно другим инстансом
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	r.log.Info("signing key created", logger.F("kid", key.ID), logger.F("state", state))
+	return nil
+}
+
+func (r *keyRotator) generateKey(state domain.SigningKeyState) (*domain.SigningKey, error) {
+	key, err := jwt.GenerateSigningKey("", r.config.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := key.EncodePrivateKeyPEM()
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: string(privateKey),
+		State:      state,
+		CreateAt:   r.now(),
+	}, nil
+}
+
+func findSigningKey(keys []*domain.SigningKey, state domain.SigningKeyState) *domain.SigningKey {
+	for _, key := range keys {
+		if key.State == state {
+			return key
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"auth-service/internal/logger"
+	"auth-service/internal/repository/memory"
+	"auth-service/internal/util/jwt"
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestLogger(t *testing.T) logger.Logger {
+	t.Helper()
+	log, err := logger.New("error")
+	if err != nil {
+		t.Fatalf("logger.New: %v", err)
+	}
+	return log
+}
+
+func TestKeyRotator_RotationWithGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	manager := jwt.NewManager(jwt.Config{AccessTokenExpiry: 15 * time.Minute, RefreshTokenSecret: "refresh"})
+	rotator := NewKeyRotator(memory.NewSigningKeyRepository(), manager, KeyRotationConfig{
+		Algorithm:        jwt.AlgorithmES256,
+		RotationInterval: 24 * time.Hour,
+		GracePeriod:      time.Hour,
+		CheckInterval:    time.Minute,
+	}, newTestLogger(t)).(*keyRotator)
+	rotator.now = func() time.Time { return now }
+
+	if err := rotator.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	set, _ := manager.JWKS()
+	if len(set.Keys) != 2 {
+		t.Fatalf("JWKS after bootstrap has %d keys, want active and next", len(set.Keys))
+	}
+	activeKid, nextKid := set.Keys[0].Kid, set.Keys[1].Kid
+
+	oldPair, err := manager.GenerateTokens(ctx, jwt.TokenParams{UserID: "user-1", Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("GenerateTokens: %v", err)
+	}
+
+	// Интервал ротации истек
+	now = now.Add(25 * time.Hour)
+	if err := rotator.tick(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+
+	set, _ = manager.JWKS()
+	if len(set.Keys) != 3 || set.Keys[0].Kid != nextKid {
+		t.Fatalf("after rotation signing kid = %s, keys = %d; want %s and 3 keys", set.Keys[0].Kid, len(set.Keys), nextKid)
+	}
+	if _, err := manager.ValidateAccessToken(ctx, oldPair.AccessToken); err != nil {
+		t.Errorf("token of retiring key rejected during grace period: %v", err)
+	}
+
+	// Grace период истек - старый ключ отозван
+	now = now.Add(2 * time.Hour)
+	if err := rotator.tick(ctx); err != nil {
+		t.Fatalf("tick: %v", err)
+	}
+
+	set, _ = manager.JWKS()
+	for _, key := range set.Keys {
+		if key.Kid == activeKid {
+			t.Errorf("revoked key %s still published", activeKid)
+		}
+	}
+	if _, err := manager.ValidateAccessToken(ctx, oldPair.AccessToken); err == nil {
+		t.Error("token of revoked key accepted")
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"auth-service/internal/logger"
+	"auth-service/internal/util/jwt"
+	"context"
+	"time"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+)
+
+type keyService struct {
+	keys   jwt.KeySetProvider
+	maxAge time.Duration
+	log    logger.Logger
+}
+
+func NewKeyService(keys jwt.KeySetProvider, maxAge time.Duration, log logger.Logger) KeyService {
+	return &keyService{
+		keys:   keys,
+		maxAge: maxAge,
+		log:    log.With(logger.F("layer", "service"), logger.F("component", "key_service")),
+	}
+}
+
+func (s *keyService) GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*pb.GetJWKSResponse, error) {
+	set, err := s.keys.JWKS()
+	if err != nil {
+		s.log.Error("failed to build jwks", logger.F("error", err))
+		return nil, err
+	}
+
+	resp := &pb.GetJWKSResponse{
+		Keys:          make([]*pb.JsonWebKey, 0, len(set.Keys)),
+		MaxAgeSeconds: int64(s.maxAge.Seconds()),
+	}
+
+	for _, key := range set.Keys {
+		resp.Keys = append(resp.Keys, &pb.JsonWebKey{
+			Kty: key.Kty,
+			Use: key.Use,
+			Alg: key.Alg,
+			Kid: key.Kid,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+			Y:   key.Y,
+		})
+	}
+
+	return resp, nil
+}
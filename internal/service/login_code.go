@@ -0,0 +1,263 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+)
+
+var ErrLoginCodeInvalid = errors.New("login code is invalid or has expired")
+
+// loginCodeDigits - длина кода из письма
+const loginCodeDigits = 6
+
+// Письмо для входа без пароля: код, ссылка, срок действия
+const (
+	loginCodeMailSubject = "Your sign-in code"
+	loginCodeMailBody    = `Your sign-in code is %s
+
+You can also sign in by opening this link:
+%s
+
+The code and the link expire in %s and can be used once. If you did not try to sign in, ignore this email.
+`
+)
+
+// RequestLoginCode отправляет на email одноразовый код и ссылку для входа без пароля. Ответ одинаковый
+// для неизвестного адреса и слишком частых запросов; новый код отменяет прежние.
+func (s *authService) RequestLoginCode(ctx context.Context, req *pb.RequestLoginCodeRequest) (*pb.RequestLoginCodeResponse, error) {
+	if req.Email == "" {
+		return nil, ErrBadRequest
+	}
+
+	// Поиск аккаунта и письмо - после ответа: время ответа не выдает, зарегистрирован ли email
+	email := req.Email
+	s.detach(ctx, "login_code", func(ctx context.Context) error {
+		return s.requestLoginCode(ctx, email)
+	})
+
+	return &pb.RequestLoginCodeResponse{}, nil
+}
+
+// requestLoginCode выпускает код для существующего аккаунта, если не действует cooldown
+func (s *authService) requestLoginCode(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.log.Debug("login code requested for unknown email")
+			return nil
+		}
+		return err
+	}
+
+	if err := s.sendLoginCode(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrLoginCodeCooldown) {
+			s.log.Debug("login code request throttled", logger.F("user_id", user.ID))
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// VerifyLoginCode обменивает код из письма (вместе с email) или токен из ссылки на пару токенов.
+// Вход по письму подтверждает владение адресом, поэтому неподтвержденный email становится подтвержденным.
+func (s *authService) VerifyLoginCode(ctx context.Context, req *pb.VerifyLoginCodeRequest) (*pb.LoginResponse, error) {
+	if req.Token == "" && (req.Email == "" || req.Code == "") {
+		return nil, ErrBadRequest
+	}
+
+	jkt, err := s.tokenEndpointKey(req.DpopProof)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var (
+		stored *domain.LoginCode
+		user   *domain.User
+	)
+	if req.Token != "" {
+		stored, user, err = s.loginCodeByToken(ctx, req.Token, now)
+	} else {
+		stored, user, err = s.loginCodeByCode(ctx, req.Email, req.Code, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loginCodes.MarkUsed(ctx, stored.ID, now); err != nil {
+		if errors.Is(err, repository.ErrLoginCodeUsed) {
+			return nil, ErrLoginCodeInvalid
+		}
+		return nil, err
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID, now); err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	s.log.Info("passwordless login", logger.F("user_id", user.ID))
+
+	methods, err := s.mfaMethods(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) > 0 {
+		return s.startMFAChallenge(ctx, user, jkt, methods)
+	}
+	s.resetLoginAttempts(ctx, user)
+
+	tokenPair, err := s.tokenService.IssueTokens(ctx, user, jkt)
+	if err != nil {
+		s.log.Error("failed to issue tokens", logger.F("user_id", user.ID), logger.F("error", err))
+		return nil, ErrBadToken
+	}
+
+	return &pb.LoginResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		TokenType:    tokenType(jkt),
+	}, nil
+}
+
+// loginCodeByToken находит действующий код по токену из ссылки. Блокировка аккаунта
+// в AttemptTracker действует и на ссылку, иначе она обходила бы lockout.
+func (s *authService) loginCodeByToken(ctx context.Context, token string, now time.Time) (*domain.LoginCode, *domain.User, error) {
+	stored, err := s.loginCodes.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrLoginCodeNotFound) {
+			return nil, nil, ErrLoginCodeInvalid
+		}
+		return nil, nil, err
+	}
+	if !s.loginCodeUsable(stored, now) {
+		return nil, nil, ErrLoginCodeInvalid
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID.String())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, ErrLoginCodeInvalid
+		}
+		return nil, nil, err
+	}
+	if err := s.attempts.Check(ctx, user.Email, clientIP(ctx)); err != nil {
+		return nil, nil, err
+	}
+
+	return stored, user, nil
+}
+
+// loginCodeByCode сверяет код с последним выданным пользователю. Неверные коды считаются и на коде
+// (после LoginCodeMaxAttempts не действуют ни код, ни ссылка), и в AttemptTracker, как неверные пароли.
+func (s *authService) loginCodeByCode(ctx context.Context, email, code string, now time.Time) (*domain.LoginCode, *domain.User, error) {
+	ip := clientIP(ctx)
+	if err := s.attempts.Check(ctx, email, ip); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			s.recordLoginFailure(ctx, email, ip)
+			return nil, nil, ErrLoginCodeInvalid
+		}
+		return nil, nil, err
+	}
+
+	stored, err := s.loginCodes.GetLatest(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrLoginCodeNotFound) {
+			s.recordLoginFailure(ctx, email, ip)
+			return nil, nil, ErrLoginCodeInvalid
+		}
+		return nil, nil, err
+	}
+	if !s.loginCodeUsable(stored, now) {
+		s.recordLoginFailure(ctx, email, ip)
+		return nil, nil, ErrLoginCodeInvalid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashLoginCode(user.ID, code)), []byte(stored.CodeHash)) != 1 {
+		attempts, err := s.loginCodes.RecordFailure(ctx, stored.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.recordLoginFailure(ctx, email, ip)
+		s.log.Warn("invalid login code", logger.F("user_id", user.ID), logger.F("attempts", attempts))
+		return nil, nil, ErrLoginCodeInvalid
+	}
+
+	return stored, user, nil
+}
+
+func (s *authService) loginCodeUsable(stored *domain.LoginCode, now time.Time) bool {
+	return stored.UsedAt == nil && now.Before(stored.ExpiresAt) && stored.Attempts < s.config.LoginCodeMaxAttempts
+}
+
+// sendLoginCode выпускает новый код со ссылкой (прежние перестают действовать) и ставит письмо в очередь.
+// repository.ErrLoginCodeCooldown, если код уже выдан в пределах LoginCodeCooldown
+func (s *authService) sendLoginCode(ctx context.Context, user *domain.User) error {
+	code, err := generateLoginCode()
+	if err != nil {
+		return err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	// Проверка cooldown и выдача кода атомарны: параллельные запросы не отправят два письма
+	now := time.Now()
+	if err := s.loginCodes.Issue(ctx, &domain.LoginCode{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		CodeHash:  hashLoginCode(user.ID, code),
+		ExpiresAt: now.Add(s.config.LoginCodeTTL),
+		CreateAt:  now,
+	}, now.Add(-s.config.LoginCodeCooldown)); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(loginCodeMailBody, code, linkWithToken(s.config.LoginCodeURL, token), s.config.LoginCodeTTL)
+	if err := enqueueMail(ctx, s.outbox, user.Email, loginCodeMailSubject, body); err != nil {
+		return err
+	}
+
+	s.log.Info("login code sent", logger.F("user_id", user.ID))
+	return nil
+}
+
+func generateLoginCode() (string, error) {
+	limit := big.NewInt(1)
+	for range loginCodeDigits {
+		limit.Mul(limit, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", fmt.Errorf("generate login code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", loginCodeDigits, n), nil
+}
+
+// hashLoginCode привязывает хеш к пользователю: одинаковые коды разных пользователей не совпадают
+func hashLoginCode(userID uuid.UUID, code string) string {
+	return hashToken(userID.String() + ":" + strings.TrimSpace(code))
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"auth-service/internal/repository/memory"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+	"github.com/google/uuid"
+)
+
+// loginCodeFromOutbox достает код и токен ссылки из единственного письма очереди
+func loginCodeFromOutbox(t *testing.T, outbox repository.MailOutboxRepository) (code, token string) {
+	t.Helper()
+
+	messages, err := outbox.ClaimPending(context.Background(), time.Now(), time.Minute, 10)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("ClaimPending = %d messages, %v; want 1", len(messages), err)
+	}
+	_, rest, ok := strings.Cut(messages[0].Body, "sign-in code is ")
+	if !ok {
+		t.Fatalf("no code in mail body:\n%s", messages[0].Body)
+	}
+	code, _, _ = strings.Cut(rest, "\n")
+	_, rest, ok = strings.Cut(rest, "?token=")
+	if !ok {
+		t.Fatalf("no link in mail body:\n%s", messages[0].Body)
+	}
+	token, _, _ = strings.Cut(rest, "\n")
+	return code, token
+}
+
+func TestAuthService_LoginCode(t *testing.T) {
+	ctx := context.Background()
+
+	alice := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com"}
+	bob := &domain.User{ID: uuid.New(), UserName: "bob", Email: "bob@example.com"}
+	carol := &domain.User{ID: uuid.New(), UserName: "carol", Email: "carol@example.com"}
+	users := newFakeUserRepository(alice, bob, carol)
+	outbox := memory.NewMailOutboxRepository()
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  users,
+		Outbox: outbox,
+		Tokens: newTestTokenService(t, alice, bob, carol),
+	}, AuthConfig{
+		LoginCodeTTL:         10 * time.Minute,
+		LoginCodeURL:         "https://app.example.com/login/email",
+		LoginCodeMaxAttempts: 3,
+		LoginCodeCooldown:    time.Hour,
+	})
+
+	// Неизвестный адрес: тот же пустой ответ, письма нет
+	if _, err := s.RequestLoginCode(ctx, &pb.RequestLoginCodeRequest{Email: "nobody@example.com"}); err != nil {
+		t.Fatalf("RequestLoginCode unknown email: %v", err)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if messages, _ := outbox.ClaimPending(ctx, time.Now(), time.Minute, 10); len(messages) != 0 {
+		t.Fatalf("mail sent to unknown email: %+v", messages)
+	}
+
+	t.Run("attempt limit", func(t *testing.T) {
+		if _, err := s.RequestLoginCode(ctx, &pb.RequestLoginCodeRequest{Email: alice.Email}); err != nil {
+			t.Fatalf("RequestLoginCode: %v", err)
+		}
+		if err := s.Close(ctx); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		code, token := loginCodeFromOutbox(t, outbox)
+
+		// Повторный запрос в пределах cooldown письма не отправляет
+		if _, err := s.RequestLoginCode(ctx, &pb.RequestLoginCodeRequest{Email: alice.Email}); err != nil {
+			t.Fatalf("RequestLoginCode during cooldown: %v", err)
+		}
+		if err := s.Close(ctx); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if messages, _ := outbox.ClaimPending(ctx, time.Now(), time.Minute, 10); len(messages) != 0 {
+			t.Fatalf("mail sent during cooldown: %+v", messages)
+		}
+
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+		for i := 0; i < 3; i++ {
+			if _, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Email: alice.Email, Code: wrong}); !errors.Is(err, ErrLoginCodeInvalid) {
+				t.Fatalf("wrong code #%d: %v, want ErrLoginCodeInvalid", i+1, err)
+			}
+		}
+		// После LoginCodeMaxAttempts не действуют ни верный код, ни ссылка
+		if _, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Email: alice.Email, Code: code}); !errors.Is(err, ErrLoginCodeInvalid) {
+			t.Fatalf("correct code after attempt limit: %v, want ErrLoginCodeInvalid", err)
+		}
+		if _, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Token: token}); !errors.Is(err, ErrLoginCodeInvalid) {
+			t.Fatalf("link after attempt limit: %v, want ErrLoginCodeInvalid", err)
+		}
+	})
+
+	t.Run("code", func(t *testing.T) {
+		if _, err := s.RequestLoginCode(ctx, &pb.RequestLoginCodeRequest{Email: bob.Email}); err != nil {
+			t.Fatalf("RequestLoginCode: %v", err)
+		}
+		if err := s.Close(ctx); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		code, token := loginCodeFromOutbox(t, outbox)
+
+		resp, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Email: bob.Email, Code: code})
+		if err != nil {
+			t.Fatalf("VerifyLoginCode: %v", err)
+		}
+		if resp.AccessToken == "" || resp.RefreshToken == "" {
+			t.Fatalf("tokens were not issued: %+v", resp)
+		}
+		if stored, _ := users.GetByID(ctx, bob.ID.String()); stored.EmailVerifiedAt == nil {
+			t.Error("email is not verified after passwordless login")
+		}
+
+		// Код одноразовый, ссылка из того же письма тоже перестает действовать
+		if _, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Email: bob.Email, Code: code}); !errors.Is(err, ErrLoginCodeInvalid) {
+			t.Fatalf("reused code: %v, want ErrLoginCodeInvalid", err)
+		}
+		if _, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Token: token}); !errors.Is(err, ErrLoginCodeInvalid) {
+			t.Fatalf("link after code was used: %v, want ErrLoginCodeInvalid", err)
+		}
+	})
+
+	t.Run("link", func(t *testing.T) {
+		if _, err := s.RequestLoginCode(ctx, &pb.RequestLoginCodeRequest{Email: carol.Email}); err != nil {
+			t.Fatalf("RequestLoginCode: %v", err)
+		}
+		if err := s.Close(ctx); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		_, token := loginCodeFromOutbox(t, outbox)
+
+		resp, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Token: token})
+		if err != nil {
+			t.Fatalf("VerifyLoginCode by link: %v", err)
+		}
+		if resp.AccessToken == "" {
+			t.Fatalf("tokens were not issued: %+v", resp)
+		}
+		if _, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Token: token}); !errors.Is(err, ErrLoginCodeInvalid) {
+			t.Fatalf("reused link: %v, want ErrLoginCodeInvalid", err)
+		}
+	})
+}
+
+func TestAuthService_LoginCodeCooldownIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com"}
+	outbox := memory.NewMailOutboxRepository()
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  newFakeUserRepository(user),
+		Outbox: outbox,
+		Tokens: newTestTokenService(t, user),
+	}, AuthConfig{
+		LoginCodeTTL:         10 * time.Minute,
+		LoginCodeURL:         "https://app.example.com/login/email",
+		LoginCodeMaxAttempts: 3,
+		LoginCodeCooldown:    time.Hour,
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.RequestLoginCode(ctx, &pb.RequestLoginCodeRequest{Email: user.Email}); err != nil {
+				t.Errorf("RequestLoginCode: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Из параллельных запросов cooldown пропускает только один
+	loginCodeFromOutbox(t, outbox)
+}
+
+func TestAuthService_LoginCodeLinkRespectsLockout(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com"}
+	outbox := memory.NewMailOutboxRepository()
+	attempts := NewAttemptTracker(memory.NewLoginAttemptRepository(), AttemptTrackerConfig{
+		LockoutThreshold: 2,
+		LockoutDuration:  time.Hour,
+		Window:           time.Hour,
+	}, newTestLogger(t))
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:    newFakeUserRepository(user),
+		Outbox:   outbox,
+		Attempts: attempts,
+		Tokens:   newTestTokenService(t, user),
+	}, AuthConfig{
+		LoginCodeTTL:         10 * time.Minute,
+		LoginCodeURL:         "https://app.example.com/login/email",
+		LoginCodeMaxAttempts: 5,
+		LoginCodeCooldown:    time.Hour,
+	})
+
+	if _, err := s.RequestLoginCode(ctx, &pb.RequestLoginCodeRequest{Email: user.Email}); err != nil {
+		t.Fatalf("RequestLoginCode: %v", err)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	code, token := loginCodeFromOutbox(t, outbox)
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Email: user.Email, Code: wrong}); !errors.Is(err, ErrLoginCodeInvalid) {
+			t.Fatalf("wrong code #%d: %v, want ErrLoginCodeInvalid", i+1, err)
+		}
+	}
+
+	// Заблокированный аккаунт не входит и по ссылке, а сама ссылка не расходуется
+	_, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Token: token})
+	retryAfter(t, err, ErrAccountLocked)
+
+	if err := attempts.Unlock(ctx, user.Email); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err := s.VerifyLoginCode(ctx, &pb.VerifyLoginCodeRequest{Token: token}); err != nil {
+		t.Fatalf("link after unlock: %v", err)
+	}
+}
+
+// blockingOutbox принимает письмо, только когда его отпустят
+type blockingOutbox struct {
+	repository.MailOutboxRepository
+	release chan struct{}
+}
+
+func (o blockingOutbox) Enqueue(ctx context.Context, msg *domain.OutboxMessage) error {
+	<-o.release
+	return o.MailOutboxRepository.Enqueue(ctx, msg)
+}
+
+// Ответ для существующего email не ждет выпуска кода и письма, поэтому не отличается от неизвестного
+func TestAuthService_RequestLoginCodeRespondsBeforeSending(t *testing.T) {
+	ctx := context.Background()
+
+	user := &domain.User{ID: uuid.New(), UserName: "alice", Email: "alice@example.com"}
+	outbox := blockingOutbox{MailOutboxRepository: memory.NewMailOutboxRepository(), release: make(chan struct{})}
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  newFakeUserRepository(user),
+		Outbox: outbox,
+		Tokens: newTestTokenService(t, user),
+	}, AuthConfig{
+		LoginCodeTTL:         10 * time.Minute,
+		LoginCodeURL:         "https://app.example.com/login/email",
+		LoginCodeMaxAttempts: 3,
+	})
+
+	if _, err := s.RequestLoginCode(ctx, &pb.RequestLoginCodeRequest{Email: user.Email}); err != nil {
+		t.Fatalf("RequestLoginCode: %v", err)
+	}
+	close(outbox.release)
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	loginCodeFromOutbox(t, outbox)
+}
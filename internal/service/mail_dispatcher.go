@@ -0,0 +1,102 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"auth-service/internal/util/mailer"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxMailRetryDelay - верхняя граница экспоненциальной задержки между попытками
+const maxMailRetryDelay = time.Hour
+
+// MailDispatchConfig - настройки отправки писем из outbox
+type MailDispatchConfig struct {
+	BatchSize    int           // писем за один проход
+	MaxAttempts  int           // после стольких неудач письмо помечается failed
+	RetryBackoff time.Duration // задержка после первой неудачи, дальше удваивается
+	Lease        time.Duration // на сколько письмо скрывается от других экземпляров на время отправки
+}
+
+type mailDispatcher struct {
+	outbox repository.MailOutboxRepository
+	mailer mailer.Mailer
+	config MailDispatchConfig
+	now    func() time.Time
+	log    logger.Logger
+}
+
+func NewMailDispatcher(outbox repository.MailOutboxRepository, m mailer.Mailer, config MailDispatchConfig, log logger.Logger) MailDispatcher {
+	return &mailDispatcher{
+		outbox: outbox,
+		mailer: m,
+		config: config,
+		now:    time.Now,
+		log:    log.With(logger.F("layer", "service"), logger.F("component", "mail_dispatcher")),
+	}
+}
+
+// enqueueMail ставит письмо в outbox; отправит его MailDispatcher
+func enqueueMail(ctx context.Context, outbox repository.MailOutboxRepository, to, subject, body string) error {
+	now := time.Now()
+	return outbox.Enqueue(ctx, &domain.OutboxMessage{
+		ID:            uuid.New(),
+		Recipient:     to,
+		Subject:       subject,
+		Body:          body,
+		NextAttemptAt: now,
+		CreateAt:      now,
+	})
+}
+
+func (d *mailDispatcher) Dispatch(ctx context.Context) (int, error) {
+	messages, err := d.outbox.ClaimPending(ctx, d.now(), d.config.Lease, d.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, msg := range messages {
+		sendErr := d.mailer.Send(ctx, mailer.Message{To: msg.Recipient, Subject: msg.Subject, Body: msg.Body})
+		now := d.now()
+		if sendErr == nil {
+			if err := d.outbox.MarkSent(ctx, msg.ID, now); err != nil {
+				return sent, err
+			}
+			sent++
+			continue
+		}
+
+		// Некорректное письмо повторять бесполезно
+		var next *time.Time
+		if msg.Attempts < d.config.MaxAttempts && !errors.Is(sendErr, mailer.ErrInvalidMessage) {
+			at := now.Add(d.retryDelay(msg.Attempts))
+			next = &at
+		}
+
+		d.log.Warn("failed to send mail",
+			logger.F("message_id", msg.ID),
+			logger.F("attempts", msg.Attempts),
+			logger.F("give_up", next == nil),
+			logger.F("error", sendErr),
+		)
+		if err := d.outbox.MarkFailed(ctx, msg.ID, sendErr.Error(), next, now); err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
+
+func (d *mailDispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.config.RetryBackoff
+	for i := 1; i < attempts && delay < maxMailRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxMailRetryDelay)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"auth-service/internal/repository/memory"
+	"auth-service/internal/util/mailer"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// flakyMailer возвращает ошибку на первых failures вызовах
+type flakyMailer struct {
+	failures int
+	sent     []mailer.Message
+}
+
+func (m *flakyMailer) Send(ctx context.Context, msg mailer.Message) error {
+	if m.failures > 0 {
+		m.failures--
+		return errors.New("smtp unavailable")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestMailDispatcher_RetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	outbox := memory.NewMailOutboxRepository()
+	m := &flakyMailer{failures: 1}
+
+	d := NewMailDispatcher(outbox, m, MailDispatchConfig{
+		BatchSize:    10,
+		MaxAttempts:  3,
+		RetryBackoff: time.Minute,
+		Lease:        time.Minute,
+	}, newTestLogger(t)).(*mailDispatcher)
+	if err := enqueueMail(ctx, outbox, "user@example.com", "subject", "body"); err != nil {
+		t.Fatalf("enqueueMail: %v", err)
+	}
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	if sent, err := d.Dispatch(ctx); err != nil || sent != 0 {
+		t.Fatalf("first dispatch = %d, %v; want 0 (send fails)", sent, err)
+	}
+	// До окончания задержки письмо не берется повторно
+	if sent, err := d.Dispatch(ctx); err != nil || sent != 0 {
+		t.Fatalf("dispatch before backoff = %d, %v; want 0", sent, err)
+	}
+
+	now = now.Add(time.Minute)
+	if sent, err := d.Dispatch(ctx); err != nil || sent != 1 {
+		t.Fatalf("dispatch after backoff = %d, %v; want 1", sent, err)
+	}
+	if len(m.sent) != 1 || m.sent[0].To != "user@example.com" || m.sent[0].Body != "body" {
+		t.Fatalf("sent = %+v", m.sent)
+	}
+
+	now = now.Add(time.Hour)
+	if sent, _ := d.Dispatch(ctx); sent != 0 {
+		t.Errorf("sent message dispatched again")
+	}
+}
+
+func TestMailDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	outbox := memory.NewMailOutboxRepository()
+	m := &flakyMailer{failures: 10}
+
+	d := NewMailDispatcher(outbox, m, MailDispatchConfig{
+		BatchSize:    10,
+		MaxAttempts:  2,
+		RetryBackoff: time.Second,
+		Lease:        time.Minute,
+	}, newTestLogger(t)).(*mailDispatcher)
+	if err := enqueueMail(ctx, outbox, "user@example.com", "subject", "body"); err != nil {
+		t.Fatalf("enqueueMail: %v", err)
+	}
+	now := time.Now()
+	d.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		d.Dispatch(ctx)
+		now = now.Add(time.Hour)
+	}
+	if m.failures != 8 {
+		t.Errorf("send attempts = %d, want 2", 10-m.failures)
+	}
+}
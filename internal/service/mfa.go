@@ -0,0 +1,313 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"auth-service/internal/util/dpop"
+	"auth-service/internal/util/totp"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+)
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("mfa is already enabled")
+	ErrMFANotEnrolled      = errors.New("mfa enrollment was not started")
+	ErrMFACodeInvalid      = errors.New("invalid mfa code")
+	ErrMFAChallengeInvalid = errors.New("mfa token is invalid or has expired")
+)
+
+// Способы второго фактора (LoginResponse.MfaMethods, VerifyMFARequest.Method)
+const (
+	MFAMethodTOTP         = "totp"
+	MFAMethodRecoveryCode = "recovery_code"
+)
+
+// EnrollTOTP начинает подключение TOTP: выдает новый секрет и otpauth URI для QR кода.
+// Фактор начинает действовать только после ConfirmTOTP.
+func (s *authService) EnrollTOTP(ctx context.Context, req *pb.EnrollTOTPRequest) (*pb.EnrollTOTPResponse, error) {
+	if req.AccessToken == "" {
+		return nil, ErrBadRequest
+	}
+
+	user, err := s.authenticatedUser(ctx, req.AccessToken, req.DpopProof, dpop.Request{Method: req.HttpMethod, URI: req.HttpUri})
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.secrets.Seal([]byte(secret), user.ID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.SaveTOTP(ctx, &domain.TOTPFactor{UserID: user.ID, Secret: sealed, CreateAt: time.Now()}); err != nil {
+		if errors.Is(err, repository.ErrMFAEnabled) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	s.log.Info("totp enrollment started", logger.F("user_id", user.ID))
+	return &pb.EnrollTOTPResponse{
+		Secret:     secret,
+		OtpauthUri: totp.DefaultConfig.URI(s.config.TOTPIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает TOTP после проверки первого кода из приложения.
+// Неверные коды считаются в AttemptTracker, как при входе.
+func (s *authService) ConfirmTOTP(ctx context.Context, req *pb.ConfirmTOTPRequest) (*pb.ConfirmTOTPResponse, error) {
+	if req.AccessToken == "" || req.Code == "" {
+		return nil, ErrBadRequest
+	}
+
+	user, err := s.authenticatedUser(ctx, req.AccessToken, req.DpopProof, dpop.Request{Method: req.HttpMethod, URI: req.HttpUri})
+	if err != nil {
+		return nil, err
+	}
+
+	factor, err := s.mfaRepo.GetTOTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if factor.ConfirmedAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	ip := clientIP(ctx)
+	if err := s.attempts.Check(ctx, user.Email, ip); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	step, ok, err := s.checkTOTP(factor, req.Code, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.recordLoginFailure(ctx, user.Email, ip)
+		return nil, ErrMFACodeInvalid
+	}
+
+	if err := s.mfaRepo.ConfirmTOTP(ctx, user.ID, step, now); err != nil {
+		if errors.Is(err, repository.ErrMFAEnabled) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(ctx, user.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("totp enabled", logger.F("user_id", user.ID))
+	return &pb.ConfirmTOTPResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyMFA завершает вход: обменивает mfa токен из Login и код второго фактора на пару токенов.
+// Если при входе был DPoP proof, здесь нужен proof того же ключа.
+func (s *authService) VerifyMFA(ctx context.Context, req *pb.VerifyMFARequest) (*pb.LoginResponse, error) {
+	if req.MfaToken == "" || req.Code == "" {
+		return nil, ErrBadRequest
+	}
+	method := req.Method
+	if method == "" {
+		method = MFAMethodTOTP
+	}
+	if method != MFAMethodTOTP && method != MFAMethodRecoveryCode {
+		return nil, ErrBadRequest
+	}
+
+	now := time.Now()
+	challenge, err := s.mfaRepo.GetChallenge(ctx, hashToken(req.MfaToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrChallengeNotFound) {
+			return nil, ErrMFAChallengeInvalid
+		}
+		return nil, err
+	}
+	if challenge.UsedAt != nil || !now.Before(challenge.ExpiresAt) || challenge.Attempts >= s.config.MFAMaxAttempts {
+		return nil, ErrMFAChallengeInvalid
+	}
+
+	jkt, err := s.tokenEndpointKey(req.DpopProof)
+	if err != nil {
+		return nil, err
+	}
+	if jkt != challenge.JKT {
+		if challenge.JKT != "" && jkt == "" {
+			return nil, ErrDPoPProofRequired
+		}
+		return nil, ErrDPoPProofInvalid
+	}
+
+	user, err := s.userRepo.GetByID(ctx, challenge.UserID.String())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrMFAChallengeInvalid
+		}
+		return nil, err
+	}
+
+	// Неверные коды считаются и в AttemptTracker: новый mfa токен из Login не дает перебирать коды дальше
+	ip := clientIP(ctx)
+	if err := s.attempts.Check(ctx, user.Email, ip); err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	if method == MFAMethodRecoveryCode {
+		ok, err = s.verifyRecoveryCode(ctx, challenge.UserID, req.Code, now)
+	} else {
+		ok, err = s.verifyTOTP(ctx, challenge.UserID, req.Code, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.recordLoginFailure(ctx, user.Email, ip)
+		return nil, s.recordMFAFailure(ctx, challenge)
+	}
+
+	if err := s.mfaRepo.CompleteChallenge(ctx, challenge.ID, now); err != nil {
+		if errors.Is(err, repository.ErrChallengeUsed) {
+			return nil, ErrMFAChallengeInvalid
+		}
+		return nil, err
+	}
+	s.resetLoginAttempts(ctx, user)
+
+	tokenPair, err := s.tokenService.IssueTokens(ctx, user, jkt)
+	if err != nil {
+		s.log.Error("failed to issue tokens", logger.F("user_id", user.ID), logger.F("error", err))
+		return nil, ErrBadToken
+	}
+
+	return &pb.LoginResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		TokenType:    tokenType(jkt),
+	}, nil
+}
+
+// mfaMethods - включенные вторые факторы пользователя; пусто - MFA не требуется
+func (s *authService) mfaMethods(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var methods []string
+
+	factor, err := s.mfaRepo.GetTOTP(ctx, userID)
+	switch {
+	case err == nil && factor.ConfirmedAt != nil:
+		methods = append(methods, MFAMethodTOTP)
+	case err != nil && !errors.Is(err, repository.ErrMFANotFound):
+		return nil, err
+	}
+
+	// Коды восстановления заменяют второй фактор, но не включают MFA сами по себе
+	if len(methods) > 0 {
+		remaining, err := s.mfaRepo.CountRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if remaining > 0 {
+			methods = append(methods, MFAMethodRecoveryCode)
+		}
+	}
+
+	return methods, nil
+}
+
+// startMFAChallenge выдает mfa токен вместо пары токенов
+func (s *authService) startMFAChallenge(ctx context.Context, user *domain.User, jkt string, methods []string) (*pb.LoginResponse, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.mfaRepo.CreateChallenge(ctx, &domain.MFAChallenge{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		JKT:       jkt,
+		ExpiresAt: now.Add(s.config.MFAChallengeTTL),
+		CreateAt:  now,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &pb.LoginResponse{
+		MfaRequired:  true,
+		MfaToken:     token,
+		MfaMethods:   methods,
+		MfaExpiresIn: int64(s.config.MFAChallengeTTL / time.Second),
+	}, nil
+}
+
+// recordMFAFailure считает неверный код; после MFAMaxAttempts mfa токен перестает действовать
+func (s *authService) recordMFAFailure(ctx context.Context, challenge *domain.MFAChallenge) error {
+	attempts, err := s.mfaRepo.RecordChallengeFailure(ctx, challenge.ID)
+	if err != nil {
+		return err
+	}
+
+	s.log.Warn("invalid mfa code", logger.F("user_id", challenge.UserID), logger.F("attempts", attempts))
+	if attempts >= s.config.MFAMaxAttempts {
+		return ErrMFAChallengeInvalid
+	}
+	return ErrMFACodeInvalid
+}
+
+// verifyTOTP проверяет код включенного TOTP фактора; каждый код принимается один раз
+func (s *authService) verifyTOTP(ctx context.Context, userID uuid.UUID, code string, now time.Time) (bool, error) {
+	factor, err := s.mfaRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMFANotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if factor.ConfirmedAt == nil {
+		return false, nil
+	}
+
+	step, ok, err := s.checkTOTP(factor, code, now)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if err := s.mfaRepo.UseTOTPStep(ctx, userID, step); err != nil {
+		if errors.Is(err, repository.ErrTOTPStepUsed) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// checkTOTP расшифровывает секрет и проверяет код, не принимая шаги не новее последнего использованного
+func (s *authService) checkTOTP(factor *domain.TOTPFactor, code string, now time.Time) (int64, bool, error) {
+	secret, err := s.secrets.Open(factor.Secret, factor.UserID[:])
+	if err != nil {
+		s.log.Error("failed to decrypt totp secret", logger.F("user_id", factor.UserID), logger.F("error", err))
+		return 0, false, err
+	}
+
+	step, ok := totp.DefaultConfig.Validate(string(secret), code, now)
+	if !ok || step <= factor.LastUsedStep {
+		return 0, false, nil
+	}
+	return step, true, nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"auth-service/internal/util/dpop"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+)
+
+// recoveryCodeAlphabet - без похожих символов (0/o, 1/l/i), чтобы код было легко переписать
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// recoveryCodeLength - 10 символов (~49 бит), выдается как xxxxx-xxxxx
+const recoveryCodeLength = 10
+
+// RegenerateRecoveryCodes выдает новый набор кодов восстановления, прежний перестает действовать.
+// Нужен текущий TOTP код: одного украденного access токена недостаточно, чтобы обойти MFA.
+func (s *authService) RegenerateRecoveryCodes(ctx context.Context, req *pb.RegenerateRecoveryCodesRequest) (*pb.RegenerateRecoveryCodesResponse, error) {
+	if req.AccessToken == "" || req.Code == "" {
+		return nil, ErrBadRequest
+	}
+
+	user, err := s.authenticatedUser(ctx, req.AccessToken, req.DpopProof, dpop.Request{Method: req.HttpMethod, URI: req.HttpUri})
+	if err != nil {
+		return nil, err
+	}
+
+	factor, err := s.mfaRepo.GetTOTP(ctx, user.ID)
+	if err != nil && !errors.Is(err, repository.ErrMFANotFound) {
+		return nil, err
+	}
+	if factor == nil || factor.ConfirmedAt == nil {
+		return nil, ErrMFANotEnrolled
+	}
+
+	// Перебор кода ограничен AttemptTracker так же, как при входе
+	ip := clientIP(ctx)
+	if err := s.attempts.Check(ctx, user.Email, ip); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ok, err := s.verifyTOTP(ctx, user.ID, req.Code, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.recordLoginFailure(ctx, user.Email, ip)
+		return nil, ErrMFACodeInvalid
+	}
+
+	codes, err := s.issueRecoveryCodes(ctx, user.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, user.ID, domain.AuditRecoveryCodesRegenerated, map[string]string{"count": strconv.Itoa(len(codes))})
+	return &pb.RegenerateRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// issueRecoveryCodes заменяет коды пользователя новыми и возвращает их в открытом виде (единственный раз)
+func (s *authService) issueRecoveryCodes(ctx context.Context, userID uuid.UUID, now time.Time) ([]string, error) {
+	codes := make([]string, 0, s.config.RecoveryCodeCount)
+	hashes := make([]string, 0, s.config.RecoveryCodeCount)
+	for range s.config.RecoveryCodeCount {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(ctx, userID, hashes, now); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifyRecoveryCode гасит код восстановления вместо второго фактора и пишет событие в журнал
+func (s *authService) verifyRecoveryCode(ctx context.Context, userID uuid.UUID, code string, now time.Time) (bool, error) {
+	if err := s.mfaRepo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code), now); err != nil {
+		if errors.Is(err, repository.ErrRecoveryCodeInvalid) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	remaining, err := s.mfaRepo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	s.log.Warn("recovery code used", logger.F("user_id", userID), logger.F("remaining", remaining))
+	s.recordAudit(ctx, userID, domain.AuditRecoveryCodeUsed, map[string]string{"remaining": strconv.Itoa(remaining)})
+	return true, nil
+}
+
+// recordAudit пишет событие в журнал; сбой журнала не прерывает операцию пользователя
+func (s *authService) recordAudit(ctx context.Context, userID uuid.UUID, eventType string, details map[string]string) {
+	if err := s.audit.Record(ctx, &domain.AuditEvent{
+		ID:        uuid.New(),
+		UserID:    &userID,
+		EventType: eventType,
+		Details:   details,
+		CreateAt:  time.Now(),
+	}); err != nil {
+		s.log.Error("failed to record audit event",
+			logger.F("user_id", userID), logger.F("event", eventType), logger.F("error", err))
+	}
+}
+
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate recovery code: %w", err)
+	}
+
+	var code strings.Builder
+	for i, b := range buf {
+		if i == recoveryCodeLength/2 {
+			code.WriteByte('-')
+		}
+		// 256 % 31 дает небольшой перекос, на стойкость кода одноразового использования он не влияет
+		code.WriteByte(recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+	}
+	return code.String(), nil
+}
+
+// hashRecoveryCode - регистр, дефисы и пробелы при вводе не важны
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+	return hashToken(normalized)
+}
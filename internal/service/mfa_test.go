@@ -0,0 +1,322 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository/memory"
+	"auth-service/internal/util/password"
+	"auth-service/internal/util/totp"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+	"github.com/google/uuid"
+)
+
+func TestAuthService_TOTPLogin(t *testing.T) {
+	ctx := context.Background()
+
+	hasher := password.NewBcrypt(4)
+	passwordHash, err := hasher.Hash("secret-password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Email: "alice@example.com", PasswordHash: passwordHash}
+	users := newFakeUserRepository(user)
+	mfa := memory.NewMFARepository()
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  users,
+		MFA:    mfa,
+		Tokens: newTestTokenService(t, user),
+		Hasher: hasher,
+	}, AuthConfig{
+		TOTPIssuer:      "Auth",
+		MFAChallengeTTL: time.Minute,
+		MFAMaxAttempts:  3,
+	})
+	login := func() *pb.LoginResponse {
+		t.Helper()
+		resp, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"})
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		return resp
+	}
+	code := func(secret string, offset int64) string {
+		code, _ := totp.DefaultConfig.Code(secret, totp.DefaultConfig.Step(time.Now())+offset)
+		return code
+	}
+
+	session := login()
+	if session.MfaRequired || session.AccessToken == "" {
+		t.Fatalf("login without mfa = %+v", session)
+	}
+
+	enrollment, err := s.EnrollTOTP(ctx, &pb.EnrollTOTPRequest{AccessToken: session.AccessToken})
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	if !strings.HasPrefix(enrollment.OtpauthUri, "otpauth://totp/Auth:alice@example.com?") {
+		t.Errorf("OtpauthUri = %q", enrollment.OtpauthUri)
+	}
+	if stored, _ := mfa.GetTOTP(ctx, user.ID); stored == nil || strings.Contains(stored.Secret, enrollment.Secret) {
+		t.Fatal("totp secret must be stored encrypted")
+	}
+
+	// Пока фактор не подтвержден, вход остается одношаговым
+	if resp := login(); resp.MfaRequired {
+		t.Fatal("unconfirmed factor must not require mfa")
+	}
+
+	if _, err := s.ConfirmTOTP(ctx, &pb.ConfirmTOTPRequest{AccessToken: session.AccessToken, Code: "abcdef"}); !errors.Is(err, ErrMFACodeInvalid) {
+		t.Fatalf("wrong confirmation code: err = %v, want ErrMFACodeInvalid", err)
+	}
+	if _, err := s.ConfirmTOTP(ctx, &pb.ConfirmTOTPRequest{AccessToken: session.AccessToken, Code: code(enrollment.Secret, 0)}); err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	if _, err := s.EnrollTOTP(ctx, &pb.EnrollTOTPRequest{AccessToken: session.AccessToken}); !errors.Is(err, ErrMFAAlreadyEnabled) {
+		t.Fatalf("enroll again: err = %v, want ErrMFAAlreadyEnabled", err)
+	}
+
+	challenge := login()
+	if !challenge.MfaRequired || challenge.AccessToken != "" || challenge.RefreshToken != "" || challenge.MfaToken == "" {
+		t.Fatalf("login with mfa = %+v; want challenge without tokens", challenge)
+	}
+	if len(challenge.MfaMethods) != 1 || challenge.MfaMethods[0] != MFAMethodTOTP {
+		t.Errorf("MfaMethods = %v", challenge.MfaMethods)
+	}
+
+	// Код, уже использованный при подтверждении, повторно не принимается
+	if _, err := s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Code: code(enrollment.Secret, 0)}); !errors.Is(err, ErrMFACodeInvalid) {
+		t.Fatalf("replayed code: err = %v, want ErrMFACodeInvalid", err)
+	}
+
+	resp, err := s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Code: code(enrollment.Secret, 1)})
+	if err != nil {
+		t.Fatalf("VerifyMFA: %v", err)
+	}
+	if resp.AccessToken == "" || resp.RefreshToken == "" {
+		t.Fatalf("VerifyMFA = %+v; want token pair", resp)
+	}
+	if _, err := s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Code: code(enrollment.Secret, 1)}); !errors.Is(err, ErrMFAChallengeInvalid) {
+		t.Errorf("mfa token reuse: err = %v, want ErrMFAChallengeInvalid", err)
+	}
+
+	// После MFAMaxAttempts неверных кодов mfa токен перестает действовать
+	challenge = login()
+	for i := 0; i < 3; i++ {
+		s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Code: "abcdef"})
+	}
+	if _, err := s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Code: code(enrollment.Secret, 1)}); !errors.Is(err, ErrMFAChallengeInvalid) {
+		t.Errorf("after max attempts: err = %v, want ErrMFAChallengeInvalid", err)
+	}
+}
+
+// Неверные коды доходят до AttemptTracker: новый mfa токен из Login не сбрасывает счетчик
+func TestAuthService_MFAFailuresThrottled(t *testing.T) {
+	ctx := context.Background()
+
+	hasher := password.NewBcrypt(4)
+	passwordHash, err := hasher.Hash("secret-password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Email: "carol@example.com", PasswordHash: passwordHash}
+	attempts := NewAttemptTracker(memory.NewLoginAttemptRepository(), AttemptTrackerConfig{
+		AccountThreshold: 2,
+		BaseDelay:        time.Hour,
+		MaxDelay:         time.Hour,
+		Window:           time.Hour,
+	}, newTestLogger(t))
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:    newFakeUserRepository(user),
+		Attempts: attempts,
+		Tokens:   newTestTokenService(t, user),
+		Hasher:   hasher,
+	}, AuthConfig{
+		TOTPIssuer:      "Auth",
+		MFAChallengeTTL: time.Minute,
+		MFAMaxAttempts:  3,
+	})
+	login := func() *pb.LoginResponse {
+		t.Helper()
+		resp, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"})
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		return resp
+	}
+
+	session := login()
+	enrollment, err := s.EnrollTOTP(ctx, &pb.EnrollTOTPRequest{AccessToken: session.AccessToken})
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	step := totp.DefaultConfig.Step(time.Now())
+	first, _ := totp.DefaultConfig.Code(enrollment.Secret, step)
+	if _, err := s.ConfirmTOTP(ctx, &pb.ConfirmTOTPRequest{AccessToken: session.AccessToken, Code: first}); err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+
+	var challenge *pb.LoginResponse
+	for i := 0; i < 2; i++ {
+		challenge = login()
+		if _, err := s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Code: "abcdef"}); !errors.Is(err, ErrMFACodeInvalid) {
+			t.Fatalf("wrong code #%d: err = %v, want ErrMFACodeInvalid", i+1, err)
+		}
+	}
+
+	// У mfa токена попытки еще остались, но аккаунт уже задержан
+	next, _ := totp.DefaultConfig.Code(enrollment.Secret, step+1)
+	_, err = s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Code: next})
+	retryAfter(t, err, ErrLoginThrottled)
+	_, err = s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"})
+	retryAfter(t, err, ErrLoginThrottled)
+}
+
+func TestAuthService_RecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+
+	hasher := password.NewBcrypt(4)
+	passwordHash, err := hasher.Hash("secret-password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Email: "bob@example.com", PasswordHash: passwordHash}
+	audit := memory.NewAuditRepository()
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:  newFakeUserRepository(user),
+		Audit:  audit,
+		Tokens: newTestTokenService(t, user),
+		Hasher: hasher,
+	}, AuthConfig{
+		MFAChallengeTTL:   time.Minute,
+		MFAMaxAttempts:    5,
+		RecoveryCodeCount: 3,
+	})
+	login := func() *pb.LoginResponse {
+		t.Helper()
+		resp, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"})
+		if err != nil {
+			t.Fatalf("Login: %v", err)
+		}
+		return resp
+	}
+	step := totp.DefaultConfig.Step(time.Now())
+	code := func(secret string, offset int64) string {
+		code, _ := totp.DefaultConfig.Code(secret, step+offset)
+		return code
+	}
+
+	session := login()
+	enrollment, err := s.EnrollTOTP(ctx, &pb.EnrollTOTPRequest{AccessToken: session.AccessToken})
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	confirmed, err := s.ConfirmTOTP(ctx, &pb.ConfirmTOTPRequest{AccessToken: session.AccessToken, Code: code(enrollment.Secret, 0)})
+	if err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+	if len(confirmed.RecoveryCodes) != 3 {
+		t.Fatalf("RecoveryCodes = %v, want 3 codes", confirmed.RecoveryCodes)
+	}
+
+	challenge := login()
+	if len(challenge.MfaMethods) != 2 || challenge.MfaMethods[1] != MFAMethodRecoveryCode {
+		t.Fatalf("MfaMethods = %v", challenge.MfaMethods)
+	}
+
+	// Регистр и дефис при вводе не важны
+	typed := strings.ToUpper(strings.ReplaceAll(confirmed.RecoveryCodes[0], "-", ""))
+	if _, err := s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Method: MFAMethodRecoveryCode, Code: typed}); err != nil {
+		t.Fatalf("VerifyMFA with recovery code: %v", err)
+	}
+
+	events, _ := audit.ListByUser(ctx, user.ID, 10)
+	if len(events) != 1 || events[0].EventType != domain.AuditRecoveryCodeUsed || events[0].Details["remaining"] != "2" {
+		t.Fatalf("audit events = %+v", events)
+	}
+
+	challenge = login()
+	if _, err := s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Method: MFAMethodRecoveryCode, Code: confirmed.RecoveryCodes[0]}); !errors.Is(err, ErrMFACodeInvalid) {
+		t.Fatalf("used recovery code: err = %v, want ErrMFACodeInvalid", err)
+	}
+
+	if _, err := s.RegenerateRecoveryCodes(ctx, &pb.RegenerateRecoveryCodesRequest{AccessToken: session.AccessToken, Code: "abcdef"}); !errors.Is(err, ErrMFACodeInvalid) {
+		t.Fatalf("regenerate with wrong code: err = %v, want ErrMFACodeInvalid", err)
+	}
+	regenerated, err := s.RegenerateRecoveryCodes(ctx, &pb.RegenerateRecoveryCodesRequest{AccessToken: session.AccessToken, Code: code(enrollment.Secret, 1)})
+	if err != nil {
+		t.Fatalf("RegenerateRecoveryCodes: %v", err)
+	}
+	if len(regenerated.RecoveryCodes) != 3 {
+		t.Fatalf("RecoveryCodes = %v, want 3 codes", regenerated.RecoveryCodes)
+	}
+
+	// Старый набор больше не действует
+	if _, err := s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Method: MFAMethodRecoveryCode, Code: confirmed.RecoveryCodes[1]}); !errors.Is(err, ErrMFACodeInvalid) {
+		t.Fatalf("old recovery code: err = %v, want ErrMFACodeInvalid", err)
+	}
+	if _, err := s.VerifyMFA(ctx, &pb.VerifyMFARequest{MfaToken: challenge.MfaToken, Method: MFAMethodRecoveryCode, Code: regenerated.RecoveryCodes[1]}); err != nil {
+		t.Fatalf("VerifyMFA with new recovery code: %v", err)
+	}
+}
+
+func TestAuthService_TOTPCodeLockout(t *testing.T) {
+	ctx := context.Background()
+
+	user := &domain.User{ID: uuid.New(), Email: "dave@example.com"}
+	attempts := NewAttemptTracker(memory.NewLoginAttemptRepository(), AttemptTrackerConfig{
+		LockoutThreshold: 3,
+		LockoutDuration:  time.Hour,
+		Window:           time.Hour,
+	}, newTestLogger(t))
+	tokens := newTestTokenService(t, user)
+
+	s := newTestAuthService(t, AuthDependencies{
+		Users:    newFakeUserRepository(user),
+		Attempts: attempts,
+		Tokens:   tokens,
+	}, AuthConfig{TOTPIssuer: "Auth", RecoveryCodeCount: 2})
+
+	// Украденный access токен: перебирать коды можно только до блокировки
+	session, err := tokens.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	enrollment, err := s.EnrollTOTP(ctx, &pb.EnrollTOTPRequest{AccessToken: session.AccessToken})
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	step := totp.DefaultConfig.Step(time.Now())
+	first, _ := totp.DefaultConfig.Code(enrollment.Secret, step)
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.ConfirmTOTP(ctx, &pb.ConfirmTOTPRequest{AccessToken: session.AccessToken, Code: "abcdef"}); !errors.Is(err, ErrMFACodeInvalid) {
+			t.Fatalf("ConfirmTOTP wrong code #%d: err = %v, want ErrMFACodeInvalid", i+1, err)
+		}
+	}
+	_, err = s.ConfirmTOTP(ctx, &pb.ConfirmTOTPRequest{AccessToken: session.AccessToken, Code: first})
+	retryAfter(t, err, ErrAccountLocked)
+
+	if err := attempts.Unlock(ctx, user.Email); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if _, err := s.ConfirmTOTP(ctx, &pb.ConfirmTOTPRequest{AccessToken: session.AccessToken, Code: first}); err != nil {
+		t.Fatalf("ConfirmTOTP: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.RegenerateRecoveryCodes(ctx, &pb.RegenerateRecoveryCodesRequest{AccessToken: session.AccessToken, Code: "abcdef"}); !errors.Is(err, ErrMFACodeInvalid) {
+			t.Fatalf("RegenerateRecoveryCodes wrong code #%d: err = %v, want ErrMFACodeInvalid", i+1, err)
+		}
+	}
+	next, _ := totp.DefaultConfig.Code(enrollment.Secret, step+1)
+	_, err = s.RegenerateRecoveryCodes(ctx, &pb.RegenerateRecoveryCodesRequest{AccessToken: session.AccessToken, Code: next})
+	retryAfter(t, err, ErrAccountLocked)
+}
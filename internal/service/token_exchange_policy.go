@@ -0,0 +1,111 @@
+package service
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// TokenExchangeClient - сервис, которому разрешен обмен токенов (RFC 8693)
+type TokenExchangeClient struct {
+	ClientID     string        `json:"client_id"`
+	SecretSHA256 string        `json:"client_secret_sha256"` // hex SHA-256 от client secret
+	Audiences    []string      `json:"audiences"`            // куда можно получить токен
+	Scopes       []string      `json:"scopes"`               // верхняя граница scopes нового токена
+	MaxTTL       time.Duration `json:"-"`
+	RawMaxTTL    string        `json:"max_ttl"` // например "5m"
+}
+
+// TokenExchangePolicy - набор клиентов token exchange
+type TokenExchangePolicy struct {
+	clients map[string]*TokenExchangeClient
+}
+
+// NewTokenExchangePolicy создает политику из списка клиентов. Пустая политика запрещает обмен.
+func NewTokenExchangePolicy(clients []*TokenExchangeClient) (*TokenExchangePolicy, error) {
+	policy := &TokenExchangePolicy{clients: make(map[string]*TokenExchangeClient, len(clients))}
+
+	for _, client := range clients {
+		if client.ClientID == "" {
+			return nil, fmt.Errorf("token exchange client without client_id")
+		}
+		if _, ok := policy.clients[client.ClientID]; ok {
+			return nil, fmt.Errorf("duplicate token exchange client %q", client.ClientID)
+		}
+		if secret, err := hex.DecodeString(client.SecretSHA256); err != nil || len(secret) != sha256.Size {
+			return nil, fmt.Errorf("token exchange client %q: client_secret_sha256 must be hex SHA-256", client.ClientID)
+		}
+		if len(client.Audiences) == 0 {
+			return nil, fmt.Errorf("token exchange client %q: audiences must not be empty", client.ClientID)
+		}
+		if client.RawMaxTTL != "" {
+			ttl, err := time.ParseDuration(client.RawMaxTTL)
+			if err != nil || ttl <= 0 {
+				return nil, fmt.Errorf("token exchange client %q: invalid max_ttl %q", client.ClientID, client.RawMaxTTL)
+			}
+			client.MaxTTL = ttl
+		}
+
+		policy.clients[client.ClientID] = client
+	}
+
+	return policy, nil
+}
+
+// LoadTokenExchangePolicy читает политику из JSON файла вида {"clients": [...]}.
+// Пустой path - обмен токенов выключен.
+func LoadTokenExchangePolicy(path string) (*TokenExchangePolicy, error) {
+	if path == "" {
+		return NewTokenExchangePolicy(nil)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read token exchange policy: %w", err)
+	}
+
+	var file struct {
+		Clients []*TokenExchangeClient `json:"clients"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse token exchange policy: %w", err)
+	}
+
+	return NewTokenExchangePolicy(file.Clients)
+}
+
+// Authenticate возвращает клиента, если секрет совпадает
+func (p *TokenExchangePolicy) Authenticate(clientID, clientSecret string) (*TokenExchangeClient, bool) {
+	client, ok := p.clients[clientID]
+	if !ok || clientSecret == "" {
+		return nil, false
+	}
+
+	sum := sha256.Sum256([]byte(clientSecret))
+	expected, _ := hex.DecodeString(client.SecretSHA256)
+	if subtle.ConstantTimeCompare(sum[:], expected) != 1 {
+		return nil, false
+	}
+
+	return client, true
+}
+
+// Audiences - все audience, в которые политика разрешает выпускать токены.
+// Менеджер токенов должен их принимать, иначе обмененный токен не пройдет проверку здесь же.
+func (p *TokenExchangePolicy) Audiences() []string {
+	var audiences []string
+	for _, client := range p.clients {
+		for _, aud := range client.Audiences {
+			if !slices.Contains(audiences, aud) {
+				audiences = append(audiences, aud)
+			}
+		}
+	}
+	slices.Sort(audiences)
+	return audiences
+}
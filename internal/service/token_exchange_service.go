@@ -0,0 +1,176 @@
+package service
+
+import (
+	"auth-service/internal/logger"
+	"auth-service/internal/util/jwt"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+)
+
+// Идентификаторы типов токенов (RFC 8693, раздел 3)
+const (
+	TokenTypeURNAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeURNJWT         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+var (
+	ErrExchangeInvalidClient        = errors.New("token exchange client authentication failed")
+	ErrExchangeInvalidGrant         = errors.New("invalid subject token")
+	ErrExchangeInvalidTarget        = errors.New("requested audience is not allowed")
+	ErrExchangeInvalidScope         = errors.New("requested scope is not allowed")
+	ErrExchangeUnsupportedTokenType = errors.New("unsupported token type")
+)
+
+type tokenExchangeService struct {
+	tokenService TokenService
+	jwtManager   jwt.TokenManager
+	policy       *TokenExchangePolicy
+	log          logger.Logger
+	now          func() time.Time
+}
+
+func NewTokenExchangeService(
+	tokenService TokenService,
+	jwtManager jwt.TokenManager,
+	policy *TokenExchangePolicy,
+	log logger.Logger,
+) TokenExchangeService {
+	return &tokenExchangeService{
+		tokenService: tokenService,
+		jwtManager:   jwtManager,
+		policy:       policy,
+		log:          log.With(logger.F("layer", "service"), logger.F("component", "token_exchange_service")),
+		now:          time.Now,
+	}
+}
+
+// ExchangeToken обменивает access токен пользователя на токен для другого сервиса:
+// scopes только сужаются, aud задается политикой клиента, а в act записывается клиент.
+func (s *tokenExchangeService) ExchangeToken(ctx context.Context, req *pb.TokenExchangeRequest) (*pb.TokenExchangeResponse, error) {
+	if req.SubjectToken == "" {
+		return nil, ErrBadRequest
+	}
+	if req.SubjectTokenType != "" && req.SubjectTokenType != TokenTypeURNAccessToken {
+		return nil, ErrExchangeUnsupportedTokenType
+	}
+	if req.RequestedTokenType != "" && req.RequestedTokenType != TokenTypeURNAccessToken && req.RequestedTokenType != TokenTypeURNJWT {
+		return nil, ErrExchangeUnsupportedTokenType
+	}
+
+	client, ok := s.policy.Authenticate(req.ClientId, req.ClientSecret)
+	if !ok {
+		s.log.Warn("token exchange client authentication failed", logger.F("client_id", req.ClientId))
+		return nil, ErrExchangeInvalidClient
+	}
+
+	subject, err := s.tokenService.ValidateAccessToken(ctx, req.SubjectToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrAccessTokenInvalid), errors.Is(err, ErrAccessTokenExpired), errors.Is(err, ErrAccessTokenRevoked):
+			return nil, ErrExchangeInvalidGrant
+		default:
+			return nil, err
+		}
+	}
+	// Токен, привязанный к DPoP ключу пользователя, нельзя превратить в Bearer: обмен снял бы привязку,
+	// а доказать владение ключом пользователя клиент-сервис не может
+	if subject.BoundKey() != "" {
+		s.log.Warn("dpop-bound subject token rejected", logger.F("client_id", client.ClientID), logger.F("user_id", subject.UserID))
+		return nil, fmt.Errorf("%w: subject token is bound to a DPoP key", ErrExchangeInvalidGrant)
+	}
+
+	audience, err := exchangeAudience(client, req.Audience)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := exchangeScopes(client, subject.Scopes(), strings.Fields(req.Scope))
+	if err != nil {
+		return nil, err
+	}
+
+	// Новый токен не переживает исходный и не превышает max_ttl клиента
+	ttl := subject.ExpiresAt.Sub(s.now())
+	if client.MaxTTL > 0 && client.MaxTTL < ttl {
+		ttl = client.MaxTTL
+	}
+	if ttl <= 0 {
+		return nil, ErrExchangeInvalidGrant
+	}
+
+	token, expiresAt, err := s.jwtManager.GenerateAccessToken(ctx, jwt.TokenParams{
+		UserID:    subject.UserID,
+		Email:     subject.Email,
+		SessionID: subject.SessionID,
+		Roles:     subject.Roles,
+		Scopes:    scopes,
+		Version:   subject.Version,
+		Audience:  audience,
+		Actor:     &jwt.Actor{Subject: client.ClientID, Actor: subject.Actor},
+		TTL:       ttl,
+	})
+	if err != nil {
+		s.log.Error("failed to issue exchanged token", logger.F("error", err))
+		return nil, err
+	}
+
+	s.log.Info("token exchanged",
+		logger.F("client_id", client.ClientID),
+		logger.F("user_id", subject.UserID),
+		logger.F("audience", audience),
+	)
+
+	return &pb.TokenExchangeResponse{
+		AccessToken:     token,
+		IssuedTokenType: TokenTypeURNAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(expiresAt.Sub(s.now()).Seconds()),
+		Scope:           strings.Join(scopes, " "),
+	}, nil
+}
+
+// exchangeAudience проверяет запрошенные audience. Без запроса берется
+// единственная разрешенная клиенту audience.
+func exchangeAudience(client *TokenExchangeClient, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		if len(client.Audiences) != 1 {
+			return nil, ErrExchangeInvalidTarget
+		}
+		return slices.Clone(client.Audiences), nil
+	}
+
+	for _, aud := range requested {
+		if !slices.Contains(client.Audiences, aud) {
+			return nil, ErrExchangeInvalidTarget
+		}
+	}
+	return requested, nil
+}
+
+// exchangeScopes - scopes нового токена: пересечение scopes субъекта и клиента,
+// либо запрошенное подмножество этого пересечения
+func exchangeScopes(client *TokenExchangeClient, subject, requested []string) ([]string, error) {
+	allowed := make([]string, 0, len(subject))
+	for _, scope := range subject {
+		if slices.Contains(client.Scopes, scope) {
+			allowed = append(allowed, scope)
+		}
+	}
+
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+
+	for _, scope := range requested {
+		if !slices.Contains(allowed, scope) {
+			return nil, ErrExchangeInvalidScope
+		}
+	}
+	return requested, nil
+}
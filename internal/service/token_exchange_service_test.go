@@ -0,0 +1,128 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository/memory"
+	"auth-service/internal/util/jwt"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+	"github.com/google/uuid"
+)
+
+func TestTokenExchangeService_ExchangeToken(t *testing.T) {
+	ctx := context.Background()
+	secret := sha256.Sum256([]byte("orders-secret"))
+
+	policy, err := NewTokenExchangePolicy([]*TokenExchangeClient{{
+		ClientID:     "orders-service",
+		SecretSHA256: hex.EncodeToString(secret[:]),
+		Audiences:    []string{"billing-service"},
+		Scopes:       []string{"billing:read", "orders:read"},
+		RawMaxTTL:    "30s",
+	}})
+	if err != nil {
+		t.Fatalf("NewTokenExchangePolicy: %v", err)
+	}
+
+	manager := jwt.NewManager(jwt.Config{
+		AccessTokenSecret:  "access",
+		RefreshTokenSecret: "refresh",
+		AccessTokenExpiry:  time.Minute,
+		RefreshTokenExpiry: time.Hour,
+		Issuer:             "auth-service",
+		Audience:           []string{"api-gateway"},
+		AcceptedAudiences:  policy.Audiences(),
+	})
+	user := &domain.User{
+		ID:     uuid.New(),
+		Email:  "user@example.com",
+		Roles:  []string{domain.RoleUser},
+		Scopes: []string{"orders:read", "orders:write", "billing:read"},
+	}
+	tokens := NewTokenService(newFakeUserRepository(user), memory.NewRefreshTokenRepository(), memory.NewTokenRevocationRepository(), manager, ValidationCacheConfig{}, newTestLogger(t))
+	s := NewTokenExchangeService(tokens, manager, policy, newTestLogger(t))
+
+	pair, err := tokens.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+
+	resp, err := s.ExchangeToken(ctx, &pb.TokenExchangeRequest{
+		ClientId:         "orders-service",
+		ClientSecret:     "orders-secret",
+		SubjectToken:     pair.AccessToken,
+		SubjectTokenType: TokenTypeURNAccessToken,
+	})
+	if err != nil {
+		t.Fatalf("ExchangeToken: %v", err)
+	}
+	if resp.Scope != "orders:read billing:read" {
+		t.Errorf("scope = %q, want intersection of user and client scopes", resp.Scope)
+	}
+	if resp.ExpiresIn > 30 {
+		t.Errorf("expires_in = %d, want at most client max_ttl", resp.ExpiresIn)
+	}
+
+	claims, err := tokens.ValidateAccessToken(ctx, resp.AccessToken)
+	if err != nil {
+		t.Fatalf("exchanged token is not valid: %v", err)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != "billing-service" {
+		t.Errorf("aud = %v, want [billing-service]", claims.Audience)
+	}
+	if claims.Actor == nil || claims.Actor.Subject != "orders-service" {
+		t.Errorf("act = %+v, want orders-service", claims.Actor)
+	}
+	if claims.UserID != user.ID.String() {
+		t.Errorf("user_id = %q, want subject user", claims.UserID)
+	}
+
+	// Обменянный токен принимается resource серверами, но не операциями над аккаунтом
+	auth := newTestAuthService(t, AuthDependencies{
+		Users:  newFakeUserRepository(user),
+		Tokens: tokens,
+	}, AuthConfig{Audience: []string{"api-gateway"}, TOTPIssuer: "Auth"})
+	validated, err := auth.ValidateToken(ctx, &pb.TokenRequest{Token: resp.AccessToken})
+	if err != nil || !validated.Valid || len(validated.Audience) != 1 || validated.Audience[0] != "billing-service" {
+		t.Errorf("ValidateToken = %+v, %v; want valid with aud [billing-service]", validated, err)
+	}
+	if _, err := auth.EnrollTOTP(ctx, &pb.EnrollTOTPRequest{AccessToken: resp.AccessToken}); !errors.Is(err, ErrAccessTokenInvalid) {
+		t.Errorf("EnrollTOTP with exchanged token: err = %v, want ErrAccessTokenInvalid", err)
+	}
+	if _, err := auth.EnrollTOTP(ctx, &pb.EnrollTOTPRequest{AccessToken: pair.AccessToken}); err != nil {
+		t.Errorf("EnrollTOTP with own token: %v", err)
+	}
+
+	bound, err := tokens.IssueTokens(ctx, user, "dpop-key-thumbprint")
+	if err != nil {
+		t.Fatalf("IssueTokens with DPoP key: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		req  *pb.TokenExchangeRequest
+		want error
+	}{
+		{"wrong secret", &pb.TokenExchangeRequest{ClientId: "orders-service", ClientSecret: "nope", SubjectToken: pair.AccessToken}, ErrExchangeInvalidClient},
+		{"unknown client", &pb.TokenExchangeRequest{ClientId: "other", ClientSecret: "orders-secret", SubjectToken: pair.AccessToken}, ErrExchangeInvalidClient},
+		{"audience not allowed", &pb.TokenExchangeRequest{ClientId: "orders-service", ClientSecret: "orders-secret", SubjectToken: pair.AccessToken, Audience: []string{"admin-service"}}, ErrExchangeInvalidTarget},
+		{"scope not held by subject", &pb.TokenExchangeRequest{ClientId: "orders-service", ClientSecret: "orders-secret", SubjectToken: pair.AccessToken, Scope: "billing:write"}, ErrExchangeInvalidScope},
+		{"scope not allowed for client", &pb.TokenExchangeRequest{ClientId: "orders-service", ClientSecret: "orders-secret", SubjectToken: pair.AccessToken, Scope: "orders:write"}, ErrExchangeInvalidScope},
+		{"refresh token as subject", &pb.TokenExchangeRequest{ClientId: "orders-service", ClientSecret: "orders-secret", SubjectToken: pair.RefreshToken}, ErrExchangeInvalidGrant},
+		{"dpop-bound subject", &pb.TokenExchangeRequest{ClientId: "orders-service", ClientSecret: "orders-secret", SubjectToken: bound.AccessToken}, ErrExchangeInvalidGrant},
+		{"unsupported subject type", &pb.TokenExchangeRequest{ClientId: "orders-service", ClientSecret: "orders-secret", SubjectToken: pair.AccessToken, SubjectTokenType: "urn:ietf:params:oauth:token-type:id_token"}, ErrExchangeUnsupportedTokenType},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := s.ExchangeToken(ctx, tc.req); !errors.Is(err, tc.want) {
+				t.Errorf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"auth-service/internal/util/cache"
+	"auth-service/internal/util/jwt"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token has expired")
+	ErrRefreshTokenRevoked = errors.New("refresh token has been revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+
+	ErrAccessTokenInvalid = errors.New("invalid access token")
+	ErrAccessTokenExpired = errors.New("access token has expired")
+	ErrAccessTokenRevoked = errors.New("access token has been revoked")
+
+	ErrDPoPProofInvalid  = errors.New("invalid DPoP proof")
+	ErrDPoPProofRequired = errors.New("DPoP proof is required for this token")
+)
+
+// Типы токенов в терминах OAuth 2.0 (token_type_hint, RFC 7009/7662)
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// TokenIntrospection - результат introspection (RFC 7662)
+type TokenIntrospection struct {
+	Active    bool
+	TokenType string
+	Claims    *jwt.Claims
+}
+
+// ValidationCacheConfig - локальный кеш результатов проверки access токенов.
+// Отзыв токена может быть замечен с задержкой до TTL.
+type ValidationCacheConfig struct {
+	Size        int           // 0 - кеш выключен
+	TTL         time.Duration // для валидных токенов, но не дольше их exp
+	NegativeTTL time.Duration // для невалидных токенов
+}
+
+// validationResult - закешированный результат проверки токена
+type validationResult struct {
+	claims *jwt.Claims
+	err    error
+}
+
+type tokenService struct {
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	revocationRepo   repository.TokenRevocationRepository
+	jwtManager       jwt.TokenManager
+	cacheConfig      ValidationCacheConfig
+	cache            *cache.LRU[string, validationResult]
+	log              logger.Logger
+	now              func() time.Time
+
+	restrictUnverified bool
+}
+
+// TokenServiceOption - дополнительная настройка сервиса токенов
+type TokenServiceOption func(*tokenService)
+
+// WithUnverifiedRestriction выдает пользователям с неподтвержденным email токены
+// без ролей и только со scope ScopeVerifyEmail, в том числе при обновлении
+func WithUnverifiedRestriction() TokenServiceOption {
+	return func(s *tokenService) {
+		s.restrictUnverified = true
+	}
+}
+
+func NewTokenService(
+	userRepo repository.UserRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	revocationRepo repository.TokenRevocationRepository,
+	jwtManager jwt.TokenManager,
+	cacheConfig ValidationCacheConfig,
+	log logger.Logger,
+	opts ...TokenServiceOption,
+) TokenService {
+	s := &tokenService{
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		revocationRepo:   revocationRepo,
+		jwtManager:       jwtManager,
+		cacheConfig:      cacheConfig,
+		log:              log.With(logger.F("layer", "service"), logger.F("component", "token_service")),
+		now:              time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if cacheConfig.Size > 0 {
+		s.cache = cache.NewLRU[string, validationResult](cacheConfig.Size)
+	}
+
+	return s
+}
+
+// IssueTokens выпускает пару токенов и открывает новое семейство refresh токенов.
+// Непустой jkt привязывает access токены и все семейство к DPoP ключу клиента.
+func (s *tokenService) IssueTokens(ctx context.Context, user *domain.User, jkt string) (*jwt.TokenPair, error) {
+	familyID := uuid.New()
+
+	pair, err := s.jwtManager.GenerateTokens(ctx, s.tokenParams(user, familyID, jkt))
+	if err != nil {
+		return nil, err
+	}
+
+	record := s.newRefreshToken(user, familyID, jkt, pair)
+	if err := s.refreshTokenRepo.Create(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// RefreshTokens обменивает refresh токен на новую пару. Старый токен становится
+// использованным; повторное предъявление использованного токена отзывает все семейство.
+// Семейство, привязанное к DPoP ключу, обновляется только с proof от того же ключа (jkt).
+func (s *tokenService) RefreshTokens(ctx context.Context, refreshToken, jkt string) (*jwt.TokenPair, error) {
+	if _, err := s.jwtManager.ValidateRefreshToken(ctx, refreshToken); err != nil {
+		if errors.Is(err, jwt.ErrExpiredToken) {
+			return nil, ErrRefreshTokenExpired
+		}
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	stored, err := s.refreshTokenRepo.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	now := s.now()
+
+	switch {
+	case stored.UsedAt != nil:
+		return nil, s.handleReuse(ctx, stored, now)
+	case stored.RevokedAt != nil:
+		return nil, ErrRefreshTokenRevoked
+	case !stored.ExpiresAt.After(now):
+		return nil, ErrRefreshTokenExpired
+	case stored.JKT != "" && stored.JKT != jkt:
+		// Токен не расходуем: его предъявил не владелец ключа
+		if jkt == "" {
+			return nil, ErrDPoPProofRequired
+		}
+		return nil, ErrDPoPProofInvalid
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.AuthId.String())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			_ = s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID, now)
+			return nil, ErrRefreshTokenRevoked
+		}
+		return nil, err
+	}
+	// Токен выдан до RevokeUserTokens: в том числе параллельным обновлением,
+	// которое успело вставить запись уже после RevokeByUser
+	if stored.Version != user.TokenVersion {
+		_ = s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID, now)
+		return nil, ErrRefreshTokenRevoked
+	}
+
+	pair, err := s.jwtManager.GenerateTokens(ctx, s.tokenParams(user, stored.FamilyID, jkt))
+	if err != nil {
+		return nil, err
+	}
+
+	next := s.newRefreshToken(user, stored.FamilyID, jkt, pair)
+	if err := s.refreshTokenRepo.Rotate(ctx, stored.ID, next, now); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenUsed) {
+			// Параллельный запрос успел использовать этот же токен
+			return nil, s.handleReuse(ctx, stored, now)
+		}
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// ValidateAccessToken проверяет access токен и состояние его сессии.
+// Результат (в том числе отрицательный) кешируется по хешу токена.
+func (s *tokenService) ValidateAccessToken(ctx context.Context, accessToken string) (*jwt.Claims, error) {
+	key := hashToken(accessToken)
+
+	if s.cache != nil {
+		if cached, ok := s.cache.Get(key); ok {
+			return cached.claims, cached.err
+		}
+	}
+
+	claims, err := s.validateAccessToken(ctx, accessToken)
+	if s.cache == nil {
+		return claims, err
+	}
+
+	switch {
+	case err == nil:
+		ttl := s.cacheConfig.TTL
+		if untilExpiry := claims.ExpiresAt.Sub(s.now()); untilExpiry < ttl {
+			ttl = untilExpiry
+		}
+		s.cache.Set(key, validationResult{claims: claims}, ttl)
+	case errors.Is(err, ErrAccessTokenInvalid), errors.Is(err, ErrAccessTokenExpired), errors.Is(err, ErrAccessTokenRevoked):
+		s.cache.Set(key, validationResult{err: err}, s.cacheConfig.NegativeTTL)
+	}
+
+	return claims, err
+}
+
+func (s *tokenService) validateAccessToken(ctx context.Context, accessToken string) (*jwt.Claims, error) {
+	claims, err := s.jwtManager.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, jwt.ErrExpiredToken):
+			return nil, ErrAccessTokenExpired
+		case errors.Is(err, jwt.ErrRevokedToken):
+			return nil, ErrAccessTokenRevoked
+		case errors.Is(err, jwt.ErrInvalidToken):
+			return nil, ErrAccessTokenInvalid
+		default:
+			return nil, err
+		}
+	}
+
+	// Токен выпущен в рамках сессии: если ее семейство отозвано, токен тоже недействителен
+	if claims.SessionID != "" {
+		familyID, err := uuid.Parse(claims.SessionID)
+		if err != nil {
+			return nil, ErrAccessTokenInvalid
+		}
+
+		revoked, err := s.refreshTokenRepo.IsFamilyRevoked(ctx, familyID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrAccessTokenRevoked
+		}
+	}
+
+	// Версия токенов растет при смене пароля: все выпущенные раньше токены недействительны
+	if claims.UserID != "" {
+		version, err := s.userRepo.GetTokenVersion(ctx, claims.UserID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrAccessTokenRevoked
+			}
+			return nil, err
+		}
+		if claims.Version < version {
+			return nil, ErrAccessTokenRevoked
+		}
+	}
+
+	return claims, nil
+}
+
+// RevokeUserTokens завершает все сессии пользователя: отзывает refresh токены
+// и увеличивает версию токенов, после чего выпущенные access токены не проходят проверку
+func (s *tokenService) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	version, err := s.userRepo.IncrementTokenVersion(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.refreshTokenRepo.RevokeByUser(ctx, userID, s.now()); err != nil {
+		return err
+	}
+
+	if s.cache != nil {
+		id := userID.String()
+		s.cache.DeleteFunc(func(_ string, cached validationResult) bool {
+			return cached.claims != nil && cached.claims.UserID == id
+		})
+	}
+
+	s.log.Info("all user tokens revoked", logger.F("user_id", userID), logger.F("token_version", version))
+	return nil
+}
+
+// RevokeToken отзывает access токен (по jti до его exp) или refresh токен вместе с сессией.
+// Невалидные и уже отозванные токены игнорируются, как того требует RFC 7009.
+func (s *tokenService) RevokeToken(ctx context.Context, token string) error {
+	claims, err := s.validateAccessToken(ctx, token)
+	switch {
+	case err == nil:
+		if claims.ID == "" {
+			return nil
+		}
+		if err := s.revocationRepo.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
+		if s.cache != nil {
+			s.cache.Delete(hashToken(token))
+		}
+		s.log.Info("access token revoked", logger.F("user_id", claims.UserID), logger.F("jti", claims.ID))
+		return nil
+	case errors.Is(err, ErrAccessTokenExpired), errors.Is(err, ErrAccessTokenRevoked):
+		return nil
+	case !errors.Is(err, ErrAccessTokenInvalid):
+		return err
+	}
+
+	// Не access токен - возможно, refresh
+
+	if _, err := s.jwtManager.ValidateRefreshToken(ctx, token); err != nil {
+		return nil
+	}
+
+	stored, err := s.refreshTokenRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID, s.now()); err != nil {
+		return err
+	}
+	s.log.Info("refresh token family revoked", logger.F("user_id", stored.AuthId), logger.F("family_id", stored.FamilyID))
+	return nil
+}
+
+// Introspect сообщает, активен ли токен (RFC 7662). tokenTypeHint ("access_token" или
+// "refresh_token") лишь определяет порядок поиска. Для неактивных токенов Claims = nil.
+func (s *tokenService) Introspect(ctx context.Context, token, tokenTypeHint string) (*TokenIntrospection, error) {
+	lookups := []func(context.Context, string) (*TokenIntrospection, error){s.introspectAccessToken, s.introspectRefreshToken}
+	if tokenTypeHint == TokenTypeRefresh {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		result, err := lookup(ctx, token)
+		if err != nil || result.Active {
+			return result, err
+		}
+	}
+
+	return &TokenIntrospection{}, nil
+}
+
+func (s *tokenService) introspectAccessToken(ctx context.Context, token string) (*TokenIntrospection, error) {
+	claims, err := s.ValidateAccessToken(ctx, token)
+	switch {
+	case err == nil:
+		return &TokenIntrospection{Active: true, TokenType: TokenTypeAccess, Claims: claims}, nil
+	case errors.Is(err, ErrAccessTokenInvalid), errors.Is(err, ErrAccessTokenExpired), errors.Is(err, ErrAccessTokenRevoked):
+		return &TokenIntrospection{}, nil
+	default:
+		return nil, err
+	}
+}
+
+func (s *tokenService) introspectRefreshToken(ctx context.Context, token string) (*TokenIntrospection, error) {
+	claims, err := s.jwtManager.ValidateRefreshToken(ctx, token)
+	if err != nil {
+		return &TokenIntrospection{}, nil
+	}
+
+	stored, err := s.refreshTokenRepo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return &TokenIntrospection{}, nil
+		}
+		return nil, err
+	}
+
+	if stored.UsedAt != nil || stored.RevokedAt != nil || !stored.ExpiresAt.After(s.now()) {
+		return &TokenIntrospection{}, nil
+	}
+
+	return &TokenIntrospection{Active: true, TokenType: TokenTypeRefresh, Claims: claims}, nil
+}
+
+// handleReuse отзывает семейство токенов, в котором обнаружено повторное использование
+func (s *tokenService) handleReuse(ctx context.Context, stored *domain.RefreshToken, now time.Time) error {
+	s.log.Warn("refresh token reuse detected, revoking token family",
+		logger.F("user_id", stored.AuthId),
+		logger.F("family_id", stored.FamilyID),
+	)
+
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID, now); err != nil {
+		return err
+	}
+
+	return ErrRefreshTokenReused
+}
+
+func (s *tokenService) newRefreshToken(user *domain.User, familyID uuid.UUID, jkt string, pair *jwt.TokenPair) *domain.RefreshToken {
+	return &domain.RefreshToken{
+		ID:        uuid.New(),
+		AuthId:    user.ID,
+		Version:   user.TokenVersion,
+		FamilyID:  familyID,
+		TokenHash: hashToken(pair.RefreshToken),
+		JKT:       jkt,
+		ExpiresAt: pair.RefreshTokenExpiresAt,
+		CreateAt:  s.now(),
+	}
+}
+
+// tokenParams собирает данные пользователя для токенов
+func (s *tokenService) tokenParams(user *domain.User, familyID uuid.UUID, jkt string) jwt.TokenParams {
+	params := jwt.TokenParams{
+		UserID:    user.ID.String(),
+		Email:     user.Email,
+		SessionID: familyID.String(),
+		Roles:     user.Roles,
+		Scopes:    user.Scopes,
+		JKT:       jkt,
+		Version:   user.TokenVersion,
+	}
+
+	if s.restrictUnverified && user.EmailVerifiedAt == nil {
+		params.Roles = nil
+		params.Scopes = []string{domain.ScopeVerifyEmail}
+	}
+
+	return params
+}
+
+// hashToken - в хранилище попадает только SHA-256 от токена
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
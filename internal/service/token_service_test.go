@@ -0,0 +1,325 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"auth-service/internal/repository/memory"
+	"auth-service/internal/util/jwt"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestTokenService(t *testing.T, users ...*domain.User) *tokenService {
+	t.Helper()
+
+	revocations := memory.NewTokenRevocationRepository()
+	manager := jwt.NewManager(jwt.Config{
+		AccessTokenSecret:  "access",
+		RefreshTokenSecret: "refresh",
+		AccessTokenExpiry:  time.Minute,
+		RefreshTokenExpiry: time.Hour,
+	}, jwt.WithRevocationChecker(revocations))
+
+	return NewTokenService(newFakeUserRepository(users...), memory.NewRefreshTokenRepository(), revocations, manager, ValidationCacheConfig{
+		Size:        16,
+		TTL:         time.Minute,
+		NegativeTTL: time.Minute,
+	}, newTestLogger(t)).(*tokenService)
+}
+
+func TestTokenService_RotationAndReuseDetection(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Email: "user@example.com"}
+	s := newTestTokenService(t, user)
+
+	first, err := s.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+
+	second, err := s.RefreshTokens(ctx, first.RefreshToken, "")
+	if err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+
+	// Повторное предъявление уже использованного токена
+	if _, err := s.RefreshTokens(ctx, first.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reused token: err = %v, want ErrRefreshTokenReused", err)
+	}
+
+	// Все семейство отозвано, включая последний выданный токен
+	if _, err := s.RefreshTokens(ctx, second.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("token from revoked family: err = %v, want ErrRefreshTokenRevoked", err)
+	}
+}
+
+func TestTokenService_RejectsUnknownAndExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Email: "user@example.com"}
+	s := newTestTokenService(t, user)
+
+	pair, err := s.jwtManager.GenerateTokens(ctx, jwt.TokenParams{UserID: user.ID.String(), Email: user.Email})
+	if err != nil {
+		t.Fatalf("GenerateTokens: %v", err)
+	}
+	if _, err := s.RefreshTokens(ctx, pair.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("unstored token: err = %v, want ErrRefreshTokenInvalid", err)
+	}
+
+	issued, err := s.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := s.RefreshTokens(ctx, issued.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenExpired) {
+		t.Errorf("expired token: err = %v, want ErrRefreshTokenExpired", err)
+	}
+}
+
+func TestTokenService_ValidateAccessToken(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Email: "user@example.com"}
+	s := newTestTokenService(t, user)
+
+	active, err := s.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+
+	claims, err := s.ValidateAccessToken(ctx, active.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	if claims.UserID != user.ID.String() || claims.SessionID == "" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	stolen, err := s.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	rotated, err := s.RefreshTokens(ctx, stolen.RefreshToken, "")
+	if err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+	if _, err := s.RefreshTokens(ctx, stolen.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuse: err = %v", err)
+	}
+
+	// Сессия отозвана - access токены этой сессии больше не принимаются
+	if _, err := s.ValidateAccessToken(ctx, rotated.AccessToken); !errors.Is(err, ErrAccessTokenRevoked) {
+		t.Errorf("access token of revoked session: err = %v, want ErrAccessTokenRevoked", err)
+	}
+
+	// Отрицательный результат тоже кешируется
+	if _, err := s.ValidateAccessToken(ctx, "garbage"); !errors.Is(err, ErrAccessTokenInvalid) {
+		t.Errorf("garbage token: err = %v, want ErrAccessTokenInvalid", err)
+	}
+	if _, ok := s.cache.Get(hashToken("garbage")); !ok {
+		t.Error("negative result was not cached")
+	}
+}
+
+func TestTokenService_RevokeToken(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Email: "user@example.com"}
+	s := newTestTokenService(t, user)
+
+	pair, err := s.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	other, err := s.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+
+	// Валидный результат уже в кеше - отзыв должен его сбросить
+	if _, err := s.ValidateAccessToken(ctx, pair.AccessToken); err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	if err := s.RevokeToken(ctx, pair.AccessToken); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if _, err := s.ValidateAccessToken(ctx, pair.AccessToken); !errors.Is(err, ErrAccessTokenRevoked) {
+		t.Errorf("revoked access token: err = %v, want ErrAccessTokenRevoked", err)
+	}
+
+	// Отзыв одного токена не затрагивает другие
+	if _, err := s.ValidateAccessToken(ctx, other.AccessToken); err != nil {
+		t.Errorf("other access token rejected: %v", err)
+	}
+
+	// Refresh токен отзывается вместе с сессией
+	if err := s.RevokeToken(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("RevokeToken(refresh): %v", err)
+	}
+	if _, err := s.RefreshTokens(ctx, other.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("revoked refresh token: err = %v, want ErrRefreshTokenRevoked", err)
+	}
+
+	if err := s.RevokeToken(ctx, "garbage"); err != nil {
+		t.Errorf("invalid token should be ignored, got %v", err)
+	}
+}
+
+func TestTokenService_OpaqueTokensAndIntrospection(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Email: "user@example.com", Roles: []string{domain.RoleUser}, Scopes: []string{"orders:read"}}
+	s := newTestTokenService(t, user)
+
+	revocations := memory.NewTokenRevocationRepository()
+	s.revocationRepo = revocations
+	s.jwtManager = jwt.NewOpaqueManager(jwt.Config{
+		AccessTokenExpiry:  time.Minute,
+		RefreshTokenExpiry: time.Hour,
+		Issuer:             "auth-service",
+	}, memory.NewOpaqueTokenRepository(), revocations)
+
+	pair, err := s.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	if strings.Count(pair.AccessToken, ".") != 0 {
+		t.Fatalf("access token %q is not opaque", pair.AccessToken)
+	}
+
+	result, err := s.Introspect(ctx, pair.AccessToken, "")
+	if err != nil {
+		t.Fatalf("Introspect: %v", err)
+	}
+	if !result.Active || result.TokenType != TokenTypeAccess || result.Claims.Subject != user.ID.String() || result.Claims.Scope != "orders:read" {
+		t.Errorf("access token introspection = %+v", result)
+	}
+
+	// Подсказка о типе не должна мешать найти токен другого типа
+	result, err = s.Introspect(ctx, pair.RefreshToken, TokenTypeAccess)
+	if err != nil || !result.Active || result.TokenType != TokenTypeRefresh {
+		t.Errorf("refresh token introspection = %+v, err = %v", result, err)
+	}
+
+	rotated, err := s.RefreshTokens(ctx, pair.RefreshToken, "")
+	if err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+	if result, _ := s.Introspect(ctx, pair.RefreshToken, TokenTypeRefresh); result.Active {
+		t.Error("used refresh token is still active")
+	}
+
+	if err := s.RevokeToken(ctx, rotated.AccessToken); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if result, _ := s.Introspect(ctx, rotated.AccessToken, ""); result.Active {
+		t.Error("revoked access token is still active")
+	}
+
+	if result, _ := s.Introspect(ctx, "not-a-token", ""); result.Active || result.Claims != nil {
+		t.Errorf("garbage token introspection = %+v", result)
+	}
+}
+
+func TestTokenService_DPoPBoundRefresh(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Email: "user@example.com"}
+	s := newTestTokenService(t, user)
+
+	pair, err := s.IssueTokens(ctx, user, "client-key")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+
+	claims, err := s.ValidateAccessToken(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	if claims.BoundKey() != "client-key" {
+		t.Errorf("cnf.jkt = %q, want client-key", claims.BoundKey())
+	}
+
+	if _, err := s.RefreshTokens(ctx, pair.RefreshToken, ""); !errors.Is(err, ErrDPoPProofRequired) {
+		t.Errorf("refresh without proof: err = %v, want ErrDPoPProofRequired", err)
+	}
+	if _, err := s.RefreshTokens(ctx, pair.RefreshToken, "attacker-key"); !errors.Is(err, ErrDPoPProofInvalid) {
+		t.Errorf("refresh with another key: err = %v, want ErrDPoPProofInvalid", err)
+	}
+
+	// Неудачные попытки не расходуют токен
+	rotated, err := s.RefreshTokens(ctx, pair.RefreshToken, "client-key")
+	if err != nil {
+		t.Fatalf("RefreshTokens: %v", err)
+	}
+	claims, err = s.ValidateAccessToken(ctx, rotated.AccessToken)
+	if err != nil || claims.BoundKey() != "client-key" {
+		t.Errorf("rotated token: claims = %+v, err = %v", claims, err)
+	}
+}
+
+// racingRotationRepository воспроизводит гонку в postgres: Rotate уже заблокировал
+// использованный токен, RevokeByUser ждет блокировку, а новая строка не попадает в его снимок
+type racingRotationRepository struct {
+	repository.RefreshTokenRepository
+	rotating chan struct{}
+	revoked  chan struct{}
+}
+
+func (r *racingRotationRepository) Rotate(ctx context.Context, usedID uuid.UUID, next *domain.RefreshToken, at time.Time) error {
+	close(r.rotating)
+	<-r.revoked
+	return r.RefreshTokenRepository.Create(ctx, next)
+}
+
+func TestTokenService_RevokeUserTokensDuringRefresh(t *testing.T) {
+	ctx := context.Background()
+	user := &domain.User{ID: uuid.New(), Email: "user@example.com"}
+	s := newTestTokenService(t, user)
+
+	pair, err := s.IssueTokens(ctx, user, "")
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+
+	repo := &racingRotationRepository{
+		RefreshTokenRepository: s.refreshTokenRepo,
+		rotating:               make(chan struct{}),
+		revoked:                make(chan struct{}),
+	}
+	s.refreshTokenRepo = repo
+
+	type result struct {
+		pair *jwt.TokenPair
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		rotated, err := s.RefreshTokens(ctx, pair.RefreshToken, "")
+		done <- result{rotated, err}
+	}()
+
+	<-repo.rotating
+	if err := s.RevokeUserTokens(ctx, user.ID); err != nil {
+		t.Fatalf("RevokeUserTokens: %v", err)
+	}
+	close(repo.revoked)
+
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("RefreshTokens: %v", res.err)
+	}
+	s.refreshTokenRepo = repo.RefreshTokenRepository
+
+	// Пара, выданная параллельно с "выйти везде", не переживает отзыв
+	if _, err := s.RefreshTokens(ctx, res.pair.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenRevoked) {
+		t.Errorf("refresh token issued during revocation: err = %v, want ErrRefreshTokenRevoked", err)
+	}
+	if _, err := s.ValidateAccessToken(ctx, res.pair.AccessToken); err == nil {
+		t.Error("access token issued during revocation is still valid")
+	}
+}
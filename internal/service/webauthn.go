@@ -0,0 +1,305 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/logger"
+	"auth-service/internal/repository"
+	"auth-service/internal/util/dpop"
+	"auth-service/internal/util/webauthn"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+)
+
+var (
+	ErrWebAuthnDisabled         = errors.New("webauthn is not configured")
+	ErrWebAuthnSessionInvalid   = errors.New("webauthn session is invalid or has expired")
+	ErrWebAuthnResponseInvalid  = errors.New("invalid webauthn response")
+	ErrWebAuthnCredentialExists = errors.New("webauthn credential is already registered")
+	ErrWebAuthnSignCount        = errors.New("webauthn signature counter regressed, credential may be cloned")
+)
+
+// maxCredentialNameLength - ограничение колонки t_webauthn_credentials.name
+const maxCredentialNameLength = 100
+
+// BeginWebAuthnRegistration выдает параметры navigator.credentials.create для нового passkey
+// аутентифицированного пользователя
+func (s *authService) BeginWebAuthnRegistration(ctx context.Context, req *pb.BeginWebAuthnRegistrationRequest) (*pb.BeginWebAuthnRegistrationResponse, error) {
+	if s.relyingParty == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+	if req.AccessToken == "" {
+		return nil, ErrBadRequest
+	}
+
+	user, err := s.authenticatedUser(ctx, req.AccessToken, req.DpopProof, dpop.Request{Method: req.HttpMethod, URI: req.HttpUri})
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.webauthnCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, challenge, err := s.startWebAuthnSession(ctx, &user.ID, domain.WebAuthnRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := s.relyingParty.CreationOptions(webauthn.User{
+		ID:          user.ID[:],
+		Name:        user.Email,
+		DisplayName: user.UserName,
+	}, challenge, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.BeginWebAuthnRegistrationResponse{SessionId: sessionID, OptionsJson: string(options)}, nil
+}
+
+// FinishWebAuthnRegistration проверяет ответ аутентификатора и сохраняет учетные данные
+func (s *authService) FinishWebAuthnRegistration(ctx context.Context, req *pb.FinishWebAuthnRegistrationRequest) (*pb.FinishWebAuthnRegistrationResponse, error) {
+	if s.relyingParty == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+	if req.AccessToken == "" || req.SessionId == "" || req.CredentialJson == "" || len(req.Name) > maxCredentialNameLength {
+		return nil, ErrBadRequest
+	}
+
+	user, err := s.authenticatedUser(ctx, req.AccessToken, req.DpopProof, dpop.Request{Method: req.HttpMethod, URI: req.HttpUri})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session, err := s.consumeWebAuthnSession(ctx, req.SessionId, domain.WebAuthnRegistration, now)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID == nil || *session.UserID != user.ID {
+		return nil, ErrWebAuthnSessionInvalid
+	}
+
+	credential, err := s.relyingParty.FinishRegistration(session.Challenge, []byte(req.CredentialJson))
+	if err != nil {
+		s.log.Warn("webauthn registration rejected", logger.F("user_id", user.ID), logger.F("error", err))
+		return nil, ErrWebAuthnResponseInvalid
+	}
+
+	if err := s.webauthnRepo.CreateCredential(ctx, &domain.WebAuthnCredential{
+		ID:           uuid.New(),
+		UserID:       user.ID,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    int64(credential.SignCount),
+		Transports:   pq.StringArray(credential.Transports),
+		Name:         req.Name,
+		CreateAt:     now,
+	}); err != nil {
+		if errors.Is(err, repository.ErrWebAuthnCredentialExists) {
+			return nil, ErrWebAuthnCredentialExists
+		}
+		return nil, err
+	}
+
+	s.log.Info("webauthn credential registered", logger.F("user_id", user.ID))
+	return &pb.FinishWebAuthnRegistrationResponse{
+		CredentialId: base64.RawURLEncoding.EncodeToString(credential.ID),
+	}, nil
+}
+
+// BeginWebAuthnLogin выдает параметры navigator.credentials.get. С email в allowCredentials
+// попадают ключи пользователя, без email выбор passkey остается браузеру. Непустой allowCredentials
+// выдает, что аккаунт существует, поэтому в режиме HardenedErrors email не учитывается и всем
+// возвращаются параметры для discoverable credentials.
+func (s *authService) BeginWebAuthnLogin(ctx context.Context, req *pb.BeginWebAuthnLoginRequest) (*pb.BeginWebAuthnLoginResponse, error) {
+	if s.relyingParty == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	var (
+		userID *uuid.UUID
+		allow  []webauthn.Credential
+	)
+	if req.Email != "" && !s.config.HardenedErrors {
+		user, err := s.userRepo.GetByEmail(ctx, req.Email)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		if user != nil {
+			allow, err = s.webauthnCredentials(ctx, user.ID)
+			if err != nil {
+				return nil, err
+			}
+			if len(allow) > 0 {
+				userID = &user.ID
+			}
+		}
+	}
+
+	sessionID, challenge, err := s.startWebAuthnSession(ctx, userID, domain.WebAuthnLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := s.relyingParty.RequestOptions(challenge, allow)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.BeginWebAuthnLoginResponse{SessionId: sessionID, OptionsJson: string(options)}, nil
+}
+
+// FinishWebAuthnLogin проверяет подпись аутентификатора и выдает пару токенов без пароля.
+// Passkey сам по себе устойчив к фишингу, поэтому TOTP challenge здесь не запрашивается.
+func (s *authService) FinishWebAuthnLogin(ctx context.Context, req *pb.FinishWebAuthnLoginRequest) (*pb.LoginResponse, error) {
+	if s.relyingParty == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+	if req.SessionId == "" || req.CredentialJson == "" {
+		return nil, ErrBadRequest
+	}
+
+	jkt, err := s.tokenEndpointKey(req.DpopProof)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session, err := s.consumeWebAuthnSession(ctx, req.SessionId, domain.WebAuthnLogin, now)
+	if err != nil {
+		return nil, err
+	}
+
+	assertion, err := webauthn.ParseAssertion([]byte(req.CredentialJson))
+	if err != nil {
+		return nil, ErrWebAuthnResponseInvalid
+	}
+
+	stored, err := s.webauthnRepo.GetCredential(ctx, assertion.CredentialID)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebAuthnCredentialNotFound) {
+			return nil, ErrWebAuthnResponseInvalid
+		}
+		return nil, err
+	}
+	if session.UserID != nil && *session.UserID != stored.UserID {
+		return nil, ErrWebAuthnResponseInvalid
+	}
+	if len(assertion.UserHandle) > 0 && !bytes.Equal(assertion.UserHandle, stored.UserID[:]) {
+		return nil, ErrWebAuthnResponseInvalid
+	}
+
+	signCount, err := s.relyingParty.VerifyAssertion(session.Challenge, assertion, webauthn.Credential{
+		ID:        stored.CredentialID,
+		PublicKey: stored.PublicKey,
+		SignCount: uint32(stored.SignCount),
+	})
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCountRegression) {
+			s.log.Warn("webauthn sign count regression, possible cloned authenticator",
+				logger.F("user_id", stored.UserID), logger.F("credential", stored.ID))
+			return nil, ErrWebAuthnSignCount
+		}
+		s.log.Warn("webauthn assertion rejected", logger.F("user_id", stored.UserID), logger.F("error", err))
+		return nil, ErrWebAuthnResponseInvalid
+	}
+
+	if err := s.webauthnRepo.UpdateSignCount(ctx, stored.ID, int64(signCount), now); err != nil {
+		if errors.Is(err, repository.ErrWebAuthnSignCount) {
+			return nil, ErrWebAuthnSignCount
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID.String())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrWebAuthnResponseInvalid
+		}
+		return nil, err
+	}
+	if user.EmailVerifiedAt == nil && s.config.UnverifiedLoginMode == UnverifiedLoginDeny {
+		return nil, ErrEmailNotVerified
+	}
+
+	tokenPair, err := s.tokenService.IssueTokens(ctx, user, jkt)
+	if err != nil {
+		s.log.Error("failed to issue tokens", logger.F("user_id", user.ID), logger.F("error", err))
+		return nil, ErrBadToken
+	}
+
+	return &pb.LoginResponse{
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		TokenType:    tokenType(jkt),
+	}, nil
+}
+
+// webauthnCredentials - зарегистрированные ключи пользователя в виде, нужном RelyingParty
+func (s *authService) webauthnCredentials(ctx context.Context, userID uuid.UUID) ([]webauthn.Credential, error) {
+	stored, err := s.webauthnRepo.ListCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(stored))
+	for _, credential := range stored {
+		credentials = append(credentials, webauthn.Credential{
+			ID:         credential.CredentialID,
+			Transports: credential.Transports,
+		})
+	}
+	return credentials, nil
+}
+
+// startWebAuthnSession сохраняет challenge; клиент получает только случайный session id
+func (s *authService) startWebAuthnSession(ctx context.Context, userID *uuid.UUID, purpose string) (string, []byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", nil, err
+	}
+	sessionID, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	if err := s.webauthnRepo.CreateSession(ctx, &domain.WebAuthnSession{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TokenHash: hashToken(sessionID),
+		Challenge: challenge,
+		Purpose:   purpose,
+		ExpiresAt: now.Add(s.config.WebAuthnSessionTTL),
+		CreateAt:  now,
+	}); err != nil {
+		return "", nil, err
+	}
+
+	return sessionID, challenge, nil
+}
+
+// consumeWebAuthnSession забирает challenge; повторный finish с тем же session id не пройдет
+func (s *authService) consumeWebAuthnSession(ctx context.Context, sessionID, purpose string, now time.Time) (*domain.WebAuthnSession, error) {
+	session, err := s.webauthnRepo.ConsumeSession(ctx, hashToken(sessionID), now)
+	if err != nil {
+		if errors.Is(err, repository.ErrWebAuthnSessionNotFound) {
+			return nil, ErrWebAuthnSessionInvalid
+		}
+		return nil, err
+	}
+	if session.Purpose != purpose {
+		return nil, ErrWebAuthnSessionInvalid
+	}
+	return session, nil
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository/memory"
+	"auth-service/internal/util/password"
+	"auth-service/internal/util/webauthn"
+	"auth-service/internal/util/webauthn/webauthntest"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/Zholdaskali/go-microservices-proto/pkg/api/auth-service"
+	"github.com/google/uuid"
+)
+
+func TestAuthService_WebAuthn(t *testing.T) {
+	ctx := context.Background()
+
+	hasher := password.NewBcrypt(4)
+	passwordHash, err := hasher.Hash("secret-password")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Email: "admin@example.com", PasswordHash: passwordHash}
+	users := newFakeUserRepository(user)
+
+	rp, err := webauthn.New(webauthn.Config{
+		RPID:             "example.com",
+		Origins:          []string{"https://example.com"},
+		Timeout:          time.Minute,
+		UserVerification: webauthn.UserVerificationRequired,
+	})
+	if err != nil {
+		t.Fatalf("webauthn.New: %v", err)
+	}
+	authenticator := webauthntest.New("example.com", "https://example.com")
+
+	credentials := memory.NewWebAuthnRepository()
+	s := newTestAuthService(t, AuthDependencies{
+		Users:        users,
+		WebAuthn:     credentials,
+		RelyingParty: rp,
+		Tokens:       newTestTokenService(t, user),
+		Hasher:       hasher,
+	}, AuthConfig{WebAuthnSessionTTL: time.Minute})
+
+	session, err := s.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "secret-password"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	begin, err := s.BeginWebAuthnRegistration(ctx, &pb.BeginWebAuthnRegistrationRequest{AccessToken: session.AccessToken})
+	if err != nil {
+		t.Fatalf("BeginWebAuthnRegistration: %v", err)
+	}
+	credential, err := authenticator.Register([]byte(begin.OptionsJson))
+	if err != nil {
+		t.Fatalf("authenticator.Register: %v", err)
+	}
+	finish := &pb.FinishWebAuthnRegistrationRequest{AccessToken: session.AccessToken, SessionId: begin.SessionId, CredentialJson: string(credential), Name: "laptop"}
+	if _, err := s.FinishWebAuthnRegistration(ctx, finish); err != nil {
+		t.Fatalf("FinishWebAuthnRegistration: %v", err)
+	}
+	// Challenge одноразовый
+	if _, err := s.FinishWebAuthnRegistration(ctx, finish); !errors.Is(err, ErrWebAuthnSessionInvalid) {
+		t.Fatalf("session reuse: err = %v, want ErrWebAuthnSessionInvalid", err)
+	}
+
+	passkeyLogin := func(email string) (*pb.LoginResponse, error) {
+		t.Helper()
+		begin, err := s.BeginWebAuthnLogin(ctx, &pb.BeginWebAuthnLoginRequest{Email: email})
+		if err != nil {
+			t.Fatalf("BeginWebAuthnLogin: %v", err)
+		}
+		assertion, err := authenticator.Login([]byte(begin.OptionsJson))
+		if err != nil {
+			t.Fatalf("authenticator.Login: %v", err)
+		}
+		return s.FinishWebAuthnLogin(ctx, &pb.FinishWebAuthnLoginRequest{SessionId: begin.SessionId, CredentialJson: string(assertion)})
+	}
+
+	for _, email := range []string{user.Email, ""} {
+		resp, err := passkeyLogin(email)
+		if err != nil {
+			t.Fatalf("FinishWebAuthnLogin(email=%q): %v", email, err)
+		}
+		if resp.AccessToken == "" || resp.RefreshToken == "" {
+			t.Fatalf("FinishWebAuthnLogin = %+v; want token pair", resp)
+		}
+	}
+
+	// В режиме HardenedErrors параметры входа не зависят от того, есть ли у email ключи
+	hardened := newTestAuthService(t, AuthDependencies{
+		Users:        users,
+		WebAuthn:     credentials,
+		RelyingParty: rp,
+		Tokens:       newTestTokenService(t, user),
+		Hasher:       hasher,
+	}, AuthConfig{WebAuthnSessionTTL: time.Minute, HardenedErrors: true})
+	for _, email := range []string{user.Email, "nobody@example.com"} {
+		begin, err := hardened.BeginWebAuthnLogin(ctx, &pb.BeginWebAuthnLoginRequest{Email: email})
+		if err != nil {
+			t.Fatalf("hardened BeginWebAuthnLogin(%q): %v", email, err)
+		}
+		var options struct {
+			AllowCredentials []json.RawMessage `json:"allowCredentials"`
+		}
+		if err := json.Unmarshal([]byte(begin.OptionsJson), &options); err != nil {
+			t.Fatalf("options: %v", err)
+		}
+		if len(options.AllowCredentials) != 0 {
+			t.Errorf("hardened allowCredentials for %q = %d entries, want none", email, len(options.AllowCredentials))
+		}
+	}
+
+	// Клон ключа присылает уже виденное значение счетчика
+	authenticator.SetSignCount(0)
+	if _, err := passkeyLogin(user.Email); !errors.Is(err, ErrWebAuthnSignCount) {
+		t.Errorf("sign count regression: err = %v, want ErrWebAuthnSignCount", err)
+	}
+}
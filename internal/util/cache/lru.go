@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU - ограниченный по размеру кеш с временем жизни записей.
+// При переполнении вытесняется давно не использованная запись.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[K]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// NewLRU создает кеш на capacity записей
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &LRU[K, V]{
+		capacity: capacity,
+		items:    make(map[K]*list.Element, capacity),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// Get возвращает значение, если оно есть и не истекло
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+
+	e := elem.Value.(*entry[K, V])
+	if !c.now().Before(e.expiresAt) {
+		c.removeElement(elem)
+		return zero, false
+	}
+
+	c.order.MoveToFront(elem)
+	return e.value, true
+}
+
+// Set сохраняет значение на ttl. Неположительный ttl удаляет запись.
+func (c *LRU[K, V]) Set(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.insert(key, value, ttl)
+}
+
+// SetIfAbsent сохраняет значение, только если действующей записи с таким ключом нет.
+// Возвращает false, если запись уже была (проверка и запись атомарны).
+func (c *LRU[K, V]) SetIfAbsent(key K, value V, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		if c.now().Before(elem.Value.(*entry[K, V]).expiresAt) {
+			return false
+		}
+		c.removeElement(elem)
+	}
+	if ttl <= 0 {
+		return true
+	}
+
+	c.insert(key, value, ttl)
+	return true
+}
+
+// Delete удаляет запись
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+// DeleteFunc удаляет все записи, для которых match вернул true, и возвращает их число
+func (c *LRU[K, V]) DeleteFunc(match func(key K, value V) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var deleted int
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		if e := elem.Value.(*entry[K, V]); match(e.key, e.value) {
+			c.removeElement(elem)
+			deleted++
+		}
+		elem = next
+	}
+	return deleted
+}
+
+// Len возвращает число записей, включая еще не вытесненные истекшие
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// insert добавляет запись и вытесняет лишние, вызывается под mu
+func (c *LRU[K, V]) insert(key K, value V, ttl time.Duration) {
+	elem := c.order.PushFront(&entry[K, V]{
+		key:       key,
+		value:     value,
+		expiresAt: c.now().Add(ttl),
+	})
+	c.items[key] = elem
+
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *LRU[K, V]) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRU[string, int](2)
+
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+	c.Get("a")
+	c.Set("c", 3, time.Minute)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("a = %v, %v; want 1, true", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d, want 2", c.Len())
+	}
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	now := time.Now()
+	c := NewLRU[string, int](10)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1, time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a should be present")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should have expired")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len = %d, want 0", c.Len())
+	}
+}
+
+func TestLRU_SetIfAbsent(t *testing.T) {
+	now := time.Now()
+	c := NewLRU[string, int](10)
+	c.now = func() time.Time { return now }
+
+	if !c.SetIfAbsent("a", 1, time.Second) {
+		t.Fatal("first SetIfAbsent should succeed")
+	}
+	if c.SetIfAbsent("a", 2, time.Second) {
+		t.Error("second SetIfAbsent should fail while a is present")
+	}
+
+	now = now.Add(time.Second)
+	if !c.SetIfAbsent("a", 3, time.Second) {
+		t.Error("SetIfAbsent should succeed after expiry")
+	}
+	if v, _ := c.Get("a"); v != 3 {
+		t.Errorf("a = %d, want 3", v)
+	}
+}
+
+func TestLRU_DeleteFunc(t *testing.T) {
+	c := NewLRU[string, int](10)
+	for i, key := range []string{"a", "b", "c", "d"} {
+		c.Set(key, i, time.Minute)
+	}
+
+	if deleted := c.DeleteFunc(func(key string, value int) bool { return value%2 == 0 }); deleted != 2 {
+		t.Errorf("deleted = %d, want 2", deleted)
+	}
+	for key, want := range map[string]bool{"a": false, "b": true, "c": false, "d": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("%s present = %v, want %v", key, ok, want)
+		}
+	}
+}
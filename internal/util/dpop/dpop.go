@@ -0,0 +1,167 @@
+// Package dpop проверяет DPoP proof (RFC 9449): JWT, которым клиент доказывает
+// владение ключом, к которому привязан токен.
+package dpop
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"auth-service/internal/util/cache"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// proofType - обязательный typ заголовок DPoP proof
+const proofType = "dpop+jwt"
+
+// Алгоритмы, которыми можно подписывать proof (только асимметричные)
+var supportedAlgorithms = []string{"ES256", "RS256", "PS256", "EdDSA"}
+
+var (
+	ErrInvalidProof = errors.New("invalid DPoP proof")
+	ErrReplayed     = errors.New("DPoP proof has already been used")
+)
+
+// Config - параметры проверки proof
+type Config struct {
+	MaxAge          time.Duration // насколько старым может быть iat
+	ClockSkew       time.Duration // допустимое отставание часов сервера от клиента
+	ReplayCacheSize int           // сколько jti помнить
+}
+
+// Request - HTTP запрос (или его аналог), к которому относится proof
+type Request struct {
+	Method      string
+	URI         string
+	AccessToken string // если задан, proof должен содержать ath от этого токена
+}
+
+// Proof - проверенный DPoP proof
+type Proof struct {
+	JKT      string // JWK SHA-256 thumbprint ключа клиента (RFC 7638)
+	ID       string // jti
+	IssuedAt time.Time
+}
+
+// proofClaims - claims DPoP proof
+type proofClaims struct {
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Verifier проверяет DPoP proof
+type Verifier interface {
+	Verify(proof string, req Request) (*Proof, error)
+}
+
+type verifier struct {
+	config Config
+	seen   *cache.LRU[string, struct{}]
+	now    func() time.Time
+}
+
+// NewVerifier создает проверку proof с локальным кешем jti.
+// Кеш не разделяется между инстансами: повтор на другой инстанс ограничен только окном iat.
+func NewVerifier(config Config) Verifier {
+	return &verifier{
+		config: config,
+		seen:   cache.NewLRU[string, struct{}](config.ReplayCacheSize),
+		now:    time.Now,
+	}
+}
+
+// Verify выполняет проверки из RFC 9449, раздел 4.3
+func (v *verifier) Verify(proof string, req Request) (*Proof, error) {
+	if proof == "" {
+		return nil, fmt.Errorf("%w: missing proof", ErrInvalidProof)
+	}
+
+	var jkt string
+	claims := &proofClaims{}
+	token, err := jwt.ParseWithClaims(proof, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); !strings.EqualFold(typ, proofType) {
+			return nil, fmt.Errorf("unexpected typ %q", token.Header["typ"])
+		}
+
+		jwk, ok := token.Header["jwk"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("missing jwk header")
+		}
+
+		publicKey, thumbprint, err := parseJWK(jwk)
+		if err != nil {
+			return nil, err
+		}
+		jkt = thumbprint
+
+		return publicKey, nil
+	}, jwt.WithValidMethods(supportedAlgorithms), jwt.WithoutClaimsValidation())
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return nil, fmt.Errorf("%w: jti and iat are required", ErrInvalidProof)
+	}
+
+	if !strings.EqualFold(claims.HTM, req.Method) {
+		return nil, fmt.Errorf("%w: htm mismatch", ErrInvalidProof)
+	}
+	if !sameURI(claims.HTU, req.URI) {
+		return nil, fmt.Errorf("%w: htu mismatch", ErrInvalidProof)
+	}
+
+	now := v.now()
+	issuedAt := claims.IssuedAt.Time
+	if issuedAt.Before(now.Add(-v.config.MaxAge)) || issuedAt.After(now.Add(v.config.ClockSkew)) {
+		return nil, fmt.Errorf("%w: iat outside of acceptable window", ErrInvalidProof)
+	}
+
+	if req.AccessToken != "" {
+		if subtle.ConstantTimeCompare([]byte(claims.ATH), []byte(AccessTokenHash(req.AccessToken))) != 1 {
+			return nil, fmt.Errorf("%w: ath mismatch", ErrInvalidProof)
+		}
+	}
+
+	// Proof живет до конца окна iat, столько же помним его jti
+	ttl := issuedAt.Add(v.config.MaxAge + v.config.ClockSkew).Sub(now)
+	if !v.seen.SetIfAbsent(jkt+":"+claims.ID, struct{}{}, ttl) {
+		return nil, ErrReplayed
+	}
+
+	return &Proof{
+		JKT:      jkt,
+		ID:       claims.ID,
+		IssuedAt: issuedAt,
+	}, nil
+}
+
+// AccessTokenHash - значение ath: base64url(SHA-256(access token))
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// sameURI сравнивает htu с URI запроса без query и fragment (RFC 9449, раздел 4.3)
+func sameURI(htu, uri string) bool {
+	a, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	b, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Host, b.Host) &&
+		a.EscapedPath() == b.EscapedPath()
+}
@@ -0,0 +1,121 @@
+package dpop
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type testClient struct {
+	key *ecdsa.PrivateKey
+	jwk map[string]interface{}
+}
+
+func newTestClient(t *testing.T) *testClient {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testClient{
+		key: key,
+		jwk: map[string]interface{}{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		},
+	}
+}
+
+func (c *testClient) proof(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = c.jwk
+
+	signed, err := token.SignedString(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestThumbprint_RFC7638Example(t *testing.T) {
+	_, jkt, err := parseJWK(map[string]interface{}{
+		"kty": "RSA",
+		"n":   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		"e":   "AQAB",
+		"alg": "RS256",
+		"kid": "2011-04-29",
+	})
+	if err != nil {
+		t.Fatalf("parseJWK: %v", err)
+	}
+	if jkt != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("thumbprint = %s", jkt)
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	client := newTestClient(t)
+	v := NewVerifier(Config{MaxAge: time.Minute, ClockSkew: 5 * time.Second, ReplayCacheSize: 100})
+	now := time.Now()
+
+	valid := func(jti string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"jti": jti,
+			"htm": "GET",
+			"htu": "https://api.example.com/orders",
+			"iat": now.Unix(),
+			"ath": AccessTokenHash("access-token"),
+		}
+	}
+	req := Request{Method: "GET", URI: "https://api.example.com/orders?page=2", AccessToken: "access-token"}
+
+	proof, err := v.Verify(client.proof(t, valid("1")), req)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if _, want, _ := parseJWK(client.jwk); proof.JKT != want {
+		t.Errorf("JKT = %s, want %s", proof.JKT, want)
+	}
+
+	if _, err := v.Verify(client.proof(t, valid("1")), req); !errors.Is(err, ErrReplayed) {
+		t.Errorf("replayed jti: err = %v, want ErrReplayed", err)
+	}
+
+	cases := map[string]func(jwt.MapClaims){
+		"wrong method": func(c jwt.MapClaims) { c["htm"] = "POST" },
+		"wrong uri":    func(c jwt.MapClaims) { c["htu"] = "https://api.example.com/admin" },
+		"stale iat":    func(c jwt.MapClaims) { c["iat"] = now.Add(-2 * time.Minute).Unix() },
+		"future iat":   func(c jwt.MapClaims) { c["iat"] = now.Add(time.Minute).Unix() },
+		"wrong ath":    func(c jwt.MapClaims) { c["ath"] = AccessTokenHash("other-token") },
+		"missing jti":  func(c jwt.MapClaims) { delete(c, "jti") },
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := valid(name)
+			mutate(claims)
+			if _, err := v.Verify(client.proof(t, claims), req); !errors.Is(err, ErrInvalidProof) {
+				t.Errorf("err = %v, want ErrInvalidProof", err)
+			}
+		})
+	}
+
+	// Подпись другим ключом, чем в заголовке jwk
+	other := newTestClient(t)
+	other.jwk = client.jwk
+	if _, err := v.Verify(other.proof(t, valid("foreign")), req); !errors.Is(err, ErrInvalidProof) {
+		t.Errorf("foreign signature: err = %v, want ErrInvalidProof", err)
+	}
+}
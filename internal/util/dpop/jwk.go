@@ -0,0 +1,90 @@
+package dpop
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// parseJWK разбирает публичный ключ из заголовка jwk и считает его thumbprint.
+// Ключ с приватной частью (d) отклоняется.
+func parseJWK(jwk map[string]interface{}) (crypto.PublicKey, string, error) {
+	field := func(name string) string {
+		value, _ := jwk[name].(string)
+		return value
+	}
+
+	if _, ok := jwk["d"]; ok {
+		return nil, "", errors.New("jwk must not contain a private key")
+	}
+
+	switch kty := field("kty"); kty {
+	case "EC":
+		if field("crv") != "P-256" {
+			return nil, "", fmt.Errorf("unsupported curve %q", field("crv"))
+		}
+		x, errX := decodeInt(field("x"))
+		y, errY := decodeInt(field("y"))
+		if errX != nil || errY != nil {
+			return nil, "", errors.New("invalid EC coordinates")
+		}
+
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !publicKey.Curve.IsOnCurve(x, y) {
+			return nil, "", errors.New("EC point is not on curve")
+		}
+
+		return publicKey, thumbprint(map[string]string{"crv": "P-256", "kty": kty, "x": field("x"), "y": field("y")}), nil
+
+	case "RSA":
+		n, errN := decodeInt(field("n"))
+		e, errE := decodeInt(field("e"))
+		if errN != nil || errE != nil || !e.IsInt64() {
+			return nil, "", errors.New("invalid RSA key")
+		}
+		if n.BitLen() < 2048 {
+			return nil, "", errors.New("RSA key must be at least 2048 bits")
+		}
+
+		publicKey := &rsa.PublicKey{N: n, E: int(e.Int64())}
+		return publicKey, thumbprint(map[string]string{"e": field("e"), "kty": kty, "n": field("n")}), nil
+
+	case "OKP":
+		if field("crv") != "Ed25519" {
+			return nil, "", fmt.Errorf("unsupported curve %q", field("crv"))
+		}
+		x, err := base64.RawURLEncoding.DecodeString(field("x"))
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, "", errors.New("invalid Ed25519 key")
+		}
+
+		return ed25519.PublicKey(x), thumbprint(map[string]string{"crv": "Ed25519", "kty": kty, "x": field("x")}), nil
+
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %q", kty)
+	}
+}
+
+// thumbprint - JWK SHA-256 thumbprint (RFC 7638) по обязательным членам ключа.
+// encoding/json сортирует ключи map, что и дает каноническую форму.
+func thumbprint(members map[string]string) string {
+	canonical, _ := json.Marshal(members)
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -1,12 +1,51 @@
 package jwt
 
-// TokenManager интерфейс для работы с JWT токенами
+import (
+	"context"
+	"time"
+)
+
+// TokenManager интерфейс для работы с JWT токенами.
+// Ротация refresh токенов требует хранилища и выполняется в сервисном слое.
 type TokenManager interface {
-	GenerateTokens(userID, email string) (*TokenPair, error)
-	ValidateAccessToken(token string) (*Claims, error)
-	ValidateRefreshToken(token string) (*Claims, error)
-	RefreshTokens(refreshToken string) (*TokenPair, error)
+	GenerateTokens(ctx context.Context, params TokenParams) (*TokenPair, error)
+	// GenerateAccessToken выпускает только access токен, например при token exchange
+	GenerateAccessToken(ctx context.Context, params TokenParams) (token string, expiresAt time.Time, err error)
+	ValidateAccessToken(ctx context.Context, token string) (*Claims, error)
+	ValidateRefreshToken(ctx context.Context, token string) (*Claims, error)
+}
+
+// RevocationChecker сообщает, отозван ли access токен с данным jti
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
 }
 
-// Проверяем, что Manager реализует интерфейс
-var _ TokenManager = (*Manager)(nil)
+// OpaqueTokenStore хранит данные opaque токенов по SHA-256 хешу токена
+type OpaqueTokenStore interface {
+	Save(ctx context.Context, tokenHash string, data []byte, expiresAt time.Time) error
+	// Load возвращает ok = false, если токен не найден
+	Load(ctx context.Context, tokenHash string) (data []byte, ok bool, err error)
+}
+
+// KeySetProvider отдает публичные ключи проверки в формате JWKS
+type KeySetProvider interface {
+	JWKS() (*JWKS, error)
+}
+
+// KeyRing позволяет подменять ключи во время работы (ротация)
+type KeyRing interface {
+	SetKeys(signingKey *Key, verificationKeys []*Key)
+}
+
+// Проверяем, что Manager реализует интерфейсы
+var (
+	_ TokenManager   = (*Manager)(nil)
+	_ KeySetProvider = (*Manager)(nil)
+	_ KeyRing        = (*Manager)(nil)
+
+	_ TokenManager = (*OpaqueManager)(nil)
+
+	_ TokenManager   = (*PasetoManager)(nil)
+	_ KeySetProvider = (*PasetoManager)(nil)
+	_ KeyRing        = (*PasetoManager)(nil)
+)
@@ -0,0 +1,102 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// JWK - публичный ключ в формате JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC / OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS - набор публичных ключей для проверки токенов
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK возвращает публичную часть ключа в формате JWK
+func (k *Key) JWK() (JWK, error) {
+	jwk := JWK{
+		Use: "sig",
+		Alg: k.Algorithm,
+		Kid: k.ID,
+	}
+
+	switch pk := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64URL(pk.N.Bytes())
+		jwk.E = encodeBase64URL(big.NewInt(int64(pk.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pk.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pk.Curve.Params().Name
+		jwk.X = encodeBase64URL(pk.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64URL(pk.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64URL(pk)
+	default:
+		return JWK{}, fmt.Errorf("%w: key type %T", ErrUnsupportedAlgorithm, k.PublicKey)
+	}
+
+	return jwk, nil
+}
+
+// JWKS возвращает все ключи, которыми сейчас принимаются access токены.
+// Текущий ключ подписи идет первым, остальные отсортированы по kid.
+func (m *Manager) JWKS() (*JWKS, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return buildJWKS(m.signingKey, m.verificationKeys)
+}
+
+// buildJWKS собирает набор ключей: ключ подписи первым, остальные по kid
+func buildJWKS(signingKey *Key, verificationKeys map[string]*Key) (*JWKS, error) {
+	keys := make([]*Key, 0, len(verificationKeys))
+	for _, key := range verificationKeys {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if signingKey != nil && (keys[i].ID == signingKey.ID) != (keys[j].ID == signingKey.ID) {
+			return keys[i].ID == signingKey.ID
+		}
+		return keys[i].ID < keys[j].ID
+	})
+
+	set := &JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		jwk, err := key.JWK()
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set, nil
+}
+
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
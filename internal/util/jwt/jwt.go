@@ -2,29 +2,85 @@
 package jwt
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
 // Claims - кастомные claims для нашего приложения
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string   `json:"user_id"`
+	Email     string   `json:"email"`
+	SessionID string   `json:"sid,omitempty"` // семейство refresh токенов, из которого выпущен токен
+	Roles     []string `json:"roles,omitempty"`
+	Scope     string   `json:"scope,omitempty"` // scopes через пробел (RFC 9068)
+	Version   int64    `json:"ver,omitempty"`   // версия токенов пользователя на момент выпуска
+
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+	Actor        *Actor        `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor - сторона, действующая от имени субъекта токена (RFC 8693, раздел 4.1).
+// Вложенный Actor - предыдущие участники цепочки делегирования.
+type Actor struct {
+	Subject string `json:"sub"`
+	Actor   *Actor `json:"act,omitempty"`
+}
+
+// Confirmation - ключ, которым клиент должен подтверждать владение токеном (RFC 7800)
+type Confirmation struct {
+	JKT string `json:"jkt,omitempty"` // JWK SHA-256 thumbprint DPoP ключа (RFC 9449)
+}
+
+// BoundKey возвращает thumbprint ключа, к которому привязан токен, или пустую строку
+func (c *Claims) BoundKey() string {
+	if c.Confirmation == nil {
+		return ""
+	}
+	return c.Confirmation.JKT
+}
+
+// Scopes возвращает scopes токена списком
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// TokenParams - данные субъекта, которые попадают в токены
+type TokenParams struct {
+	UserID    string
+	Email     string
+	SessionID string
+	Roles     []string
+	Scopes    []string
+	JKT       string // если задан, access токен привязывается к DPoP ключу (cnf.jkt)
+	Version   int64  // версия токенов пользователя (claim ver)
+
+	// Только для GenerateAccessToken (token exchange)
+	Audience []string      // заменяет Config.Audience
+	Actor    *Actor        // claim act
+	TTL      time.Duration // заменяет Config.AccessTokenExpiry
+}
+
 // TokenPair - пара access и refresh токенов
 type TokenPair struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken           string    `json:"access_token"`
+	RefreshToken          string    `json:"refresh_token"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
 }
 
 // Config - конфигурация JWT
@@ -33,90 +89,294 @@ type Config struct {
 	RefreshTokenSecret string        `json:"refresh_token_secret"`
 	AccessTokenExpiry  time.Duration `json:"access_token_expiry"`  // например: 15 * time.Minute
 	RefreshTokenExpiry time.Duration `json:"refresh_token_expiry"` // например: 7 * 24 * time.Hour
+
+	// Issuer попадает в iss всех токенов, Audience - в aud access токенов.
+	// Если заданы, при проверке токены без них отклоняются.
+	Issuer   string   `json:"issuer"`
+	Audience []string `json:"audience"`
+	// Дополнительные audience, которые принимаются при проверке (например, выданные через token exchange)
+	AcceptedAudiences []string `json:"accepted_audiences"`
+
+	// Асимметричная подпись access токенов. Если SigningKey не задан,
+	// используется HS256 с AccessTokenSecret.
+	SigningKey       *Key   `json:"-"`
+	VerificationKeys []*Key `json:"-"` // дополнительные ключи, которыми принимаются токены
 }
 
 // Manager - менеджер JWT токенов
 type Manager struct {
 	config Config
+
+	mu               sync.RWMutex
+	signingKey       *Key
+	verificationKeys map[string]*Key
+
+	revocations RevocationChecker
+}
+
+// Option - дополнительная настройка менеджера
+type Option func(*Manager)
+
+// WithRevocationChecker включает проверку jti access токенов по denylist
+func WithRevocationChecker(checker RevocationChecker) Option {
+	return func(m *Manager) {
+		m.revocations = checker
+	}
 }
 
 // NewManager создает новый менеджер JWT
-func NewManager(config Config) *Manager {
-	return &Manager{
-		config: config,
+func NewManager(config Config, opts ...Option) *Manager {
+	m := &Manager{
+		config:           config,
+		signingKey:       config.SigningKey,
+		verificationKeys: make(map[string]*Key),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	for _, key := range config.VerificationKeys {
+		m.verificationKeys[key.ID] = key
 	}
+	if config.SigningKey != nil {
+		m.verificationKeys[config.SigningKey.ID] = config.SigningKey
+	}
+
+	return m
+}
+
+// SetKeys атомарно заменяет ключ подписи и набор ключей проверки.
+// Используется при ротации; ключ подписи всегда остается доступен для проверки.
+func (m *Manager) SetKeys(signingKey *Key, verificationKeys []*Key) {
+	keys := make(map[string]*Key, len(verificationKeys)+1)
+	for _, key := range verificationKeys {
+		keys[key.ID] = key
+	}
+	if signingKey != nil {
+		keys[signingKey.ID] = signingKey
+	}
+
+	m.mu.Lock()
+	m.signingKey = signingKey
+	m.verificationKeys = keys
+	m.mu.Unlock()
 }
 
 // GenerateTokens создает пару access и refresh токенов
-func (m *Manager) GenerateTokens(userID, email string) (*TokenPair, error) {
+func (m *Manager) GenerateTokens(ctx context.Context, params TokenParams) (*TokenPair, error) {
+	now := time.Now()
+	pair := &TokenPair{
+		AccessTokenExpiresAt:  now.Add(m.config.AccessTokenExpiry),
+		RefreshTokenExpiresAt: now.Add(m.config.RefreshTokenExpiry),
+	}
+
 	// Генерация Access Token
-	accessToken, err := m.generateAccessToken(userID, email)
+	accessToken, err := m.generateAccessToken(params, now, pair.AccessTokenExpiresAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
 	// Генерация Refresh Token
-	refreshToken, err := m.generateRefreshToken(userID, email)
+	refreshToken, err := m.generateRefreshToken(params, now, pair.RefreshTokenExpiresAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	return &TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
+	pair.AccessToken = accessToken
+	pair.RefreshToken = refreshToken
+	return pair, nil
+}
+
+// GenerateAccessToken создает отдельный access токен без refresh токена (token exchange)
+func (m *Manager) GenerateAccessToken(ctx context.Context, params TokenParams) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(accessTokenTTL(m.config, params))
+
+	token, err := m.generateAccessToken(params, now, expiresAt)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return token, expiresAt, nil
 }
 
 // generateAccessToken создает access token
-func (m *Manager) generateAccessToken(userID, email string) (string, error) {
-	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+func (m *Manager) generateAccessToken(params TokenParams, issuedAt, expiresAt time.Time) (string, error) {
+	return m.signAccessToken(newAccessClaims(m.config, params, issuedAt, expiresAt))
+}
+
+// newAccessClaims собирает claims access токена, общие для всех форматов
+func newAccessClaims(config Config, params TokenParams, issuedAt, expiresAt time.Time) *Claims {
+	var confirmation *Confirmation
+	if params.JKT != "" {
+		confirmation = &Confirmation{JKT: params.JKT}
+	}
+
+	audience := config.Audience
+	if len(params.Audience) > 0 {
+		audience = params.Audience
+	}
+
+	return &Claims{
+		UserID:    params.UserID,
+		Email:     params.Email,
+		SessionID: params.SessionID,
+		Roles:     params.Roles,
+		Scope:     strings.Join(params.Scopes, " "),
+		Version:   params.Version,
+
+		Confirmation: confirmation,
+		Actor:        params.Actor,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.config.AccessTokenExpiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   userID,
+			ID:        uuid.NewString(),
+			Issuer:    config.Issuer,
+			Audience:  audience,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			Subject:   params.UserID,
 		},
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(m.config.AccessTokenSecret))
+// accessTokenTTL - время жизни отдельно выпускаемого access токена
+func accessTokenTTL(config Config, params TokenParams) time.Duration {
+	if params.TTL > 0 {
+		return params.TTL
+	}
+	return config.AccessTokenExpiry
 }
 
-// generateRefreshToken создает refresh token
-func (m *Manager) generateRefreshToken(userID, email string) (string, error) {
-	claims := &Claims{
-		UserID: userID,
-		Email:  email,
+// acceptedAudiences - audience, с которыми принимаются access токены
+func acceptedAudiences(config Config) []string {
+	return append(slices.Clone(config.Audience), config.AcceptedAudiences...)
+}
+
+// newRefreshClaims собирает claims refresh токена.
+// Уникальный jti нужен, чтобы у каждого токена был свой хеш в хранилище.
+func newRefreshClaims(config Config, params TokenParams, issuedAt, expiresAt time.Time) *Claims {
+	return &Claims{
+		UserID:    params.UserID,
+		Email:     params.Email,
+		SessionID: params.SessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.config.RefreshTokenExpiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   userID,
+			ID:        uuid.NewString(),
+			Issuer:    config.Issuer,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			Subject:   params.UserID,
 		},
 	}
+}
+
+// signAccessToken подписывает access token текущим ключом подписи
+func (m *Manager) signAccessToken(claims *Claims) (string, error) {
+	m.mu.RLock()
+	key := m.signingKey
+	m.mu.RUnlock()
+
+	if key == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(m.config.AccessTokenSecret))
+	}
+
+	token := jwt.NewWithClaims(key.signingMethod(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// generateRefreshToken создает refresh token
+func (m *Manager) generateRefreshToken(params TokenParams, issuedAt, expiresAt time.Time) (string, error) {
+	claims := newRefreshClaims(m.config, params, issuedAt, expiresAt)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(m.config.RefreshTokenSecret))
 }
 
-// ValidateAccessToken проверяет access token
-func (m *Manager) ValidateAccessToken(tokenString string) (*Claims, error) {
-	return m.validateToken(tokenString, m.config.AccessTokenSecret)
+// ValidateAccessToken проверяет access token и, если настроено, его отзыв по jti
+func (m *Manager) ValidateAccessToken(ctx context.Context, tokenString string) (*Claims, error) {
+	m.mu.RLock()
+	asymmetric := m.signingKey != nil || len(m.verificationKeys) > 0
+	m.mu.RUnlock()
+
+	keyFunc := m.verificationKeyFunc
+	if !asymmetric {
+		keyFunc = m.hmacKeyFunc(m.config.AccessTokenSecret)
+	}
+
+	opts := m.issuerOptions()
+	if audience := acceptedAudiences(m.config); len(audience) > 0 {
+		opts = append(opts, jwt.WithAudience(audience...))
+	}
+
+	claims, err := m.validateToken(tokenString, keyFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.revocations != nil && claims.ID != "" {
+		revoked, err := m.revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, fmt.Errorf("check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+
+	return claims, nil
 }
 
-// ValidateRefreshToken проверяет refresh token
-func (m *Manager) ValidateRefreshToken(tokenString string) (*Claims, error) {
-	return m.validateToken(tokenString, m.config.RefreshTokenSecret)
+// ValidateRefreshToken проверяет refresh token.
+// Refresh токены проверяет только сам сервис, поэтому они остаются на HS256.
+func (m *Manager) ValidateRefreshToken(ctx context.Context, tokenString string) (*Claims, error) {
+	return m.validateToken(tokenString, m.hmacKeyFunc(m.config.RefreshTokenSecret), m.issuerOptions()...)
 }
 
-// validateToken общая функция валидации
-func (m *Manager) validateToken(tokenString, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+// issuerOptions требует совпадения iss, если издатель настроен
+func (m *Manager) issuerOptions() []jwt.ParserOption {
+	if m.config.Issuer == "" {
+		return nil
+	}
+	return []jwt.ParserOption{jwt.WithIssuer(m.config.Issuer)}
+}
+
+// hmacKeyFunc возвращает секрет для токенов, подписанных HS256
+func (m *Manager) hmacKeyFunc(secret string) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(secret), nil
-	})
+	}
+}
+
+// verificationKeyFunc выбирает публичный ключ по kid и сверяет алгоритм
+func (m *Manager) verificationKeyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("%w: missing kid header", ErrUnknownKeyID)
+	}
+
+	m.mu.RLock()
+	key, ok := m.verificationKeys[kid]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+	}
+
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.PublicKey, nil
+}
+
+// validateToken общая функция валидации
+func (m *Manager) validateToken(tokenString string, keyFunc jwt.Keyfunc, opts ...jwt.ParserOption) (*Claims, error) {
+	opts = append(opts, jwt.WithExpirationRequired())
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc, opts...)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -132,13 +392,3 @@ func (m *Manager) validateToken(tokenString, secret string) (*Claims, error) {
 
 	return claims, nil
 }
-
-// RefreshTokens обновляет пару токенов
-func (m *Manager) RefreshTokens(refreshToken string) (*TokenPair, error) {
-	claims, err := m.ValidateRefreshToken(refreshToken)
-	if err != nil {
-		return nil, err
-	}
-
-	return m.GenerateTokens(claims.UserID, claims.Email)
-}
@@ -0,0 +1,193 @@
+package jwt
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testConfig() Config {
+	return Config{
+		AccessTokenSecret:  "access-secret",
+		RefreshTokenSecret: "refresh-secret",
+		AccessTokenExpiry:  time.Minute,
+		RefreshTokenExpiry: time.Hour,
+	}
+}
+
+func TestManager_HS256Fallback(t *testing.T) {
+	m := NewManager(testConfig())
+
+	pair, err := m.GenerateTokens(context.Background(), TokenParams{UserID: "user-1", Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("GenerateTokens: %v", err)
+	}
+
+	claims, err := m.ValidateAccessToken(context.Background(), pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	if claims.UserID != "user-1" {
+		t.Errorf("UserID = %q, want user-1", claims.UserID)
+	}
+
+	if _, err := m.ValidateAccessToken(context.Background(), pair.RefreshToken); err == nil {
+		t.Error("refresh token accepted as access token")
+	}
+}
+
+func TestManager_AsymmetricSigning(t *testing.T) {
+	for _, alg := range []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			key, err := GenerateSigningKey("", alg)
+			if err != nil {
+				t.Fatalf("GenerateSigningKey: %v", err)
+			}
+
+			cfg := testConfig()
+			cfg.SigningKey = key
+			issuer := NewManager(cfg)
+
+			pair, err := issuer.GenerateTokens(context.Background(), TokenParams{UserID: "user-1", Email: "user@example.com"})
+			if err != nil {
+				t.Fatalf("GenerateTokens: %v", err)
+			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(pair.AccessToken, &Claims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified: %v", err)
+			}
+			if parsed.Header["kid"] != key.ID || parsed.Header["alg"] != alg {
+				t.Errorf("header = %v, want kid %s alg %s", parsed.Header, key.ID, alg)
+			}
+
+			// Сервис, у которого есть только публичный ключ
+			publicKey, err := NewVerificationKey(key.ID, key.PublicKey)
+			if err != nil {
+				t.Fatalf("NewVerificationKey: %v", err)
+			}
+			verifierCfg := Config{VerificationKeys: []*Key{publicKey}}
+			verifier := NewManager(verifierCfg)
+
+			claims, err := verifier.ValidateAccessToken(context.Background(), pair.AccessToken)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken: %v", err)
+			}
+			if claims.Email != "user@example.com" {
+				t.Errorf("Email = %q", claims.Email)
+			}
+		})
+	}
+}
+
+func TestManager_MultipleVerificationKeys(t *testing.T) {
+	oldKey, _ := GenerateSigningKey("old", AlgorithmES256)
+	newKey, _ := GenerateSigningKey("new", AlgorithmEdDSA)
+
+	oldCfg := testConfig()
+	oldCfg.SigningKey = oldKey
+	oldToken, err := NewManager(oldCfg).GenerateTokens(context.Background(), TokenParams{UserID: "user-1", Email: "user@example.com"})
+	if err != nil {
+		t.Fatalf("GenerateTokens: %v", err)
+	}
+
+	cfg := testConfig()
+	cfg.SigningKey = newKey
+	cfg.VerificationKeys = []*Key{{ID: oldKey.ID, Algorithm: oldKey.Algorithm, PublicKey: oldKey.PublicKey}}
+	m := NewManager(cfg)
+
+	if _, err := m.ValidateAccessToken(context.Background(), oldToken.AccessToken); err != nil {
+		t.Errorf("token signed by previous key rejected: %v", err)
+	}
+
+	newToken, _ := m.GenerateTokens(context.Background(), TokenParams{UserID: "user-1", Email: "user@example.com"})
+	if _, err := m.ValidateAccessToken(context.Background(), newToken.AccessToken); err != nil {
+		t.Errorf("token signed by current key rejected: %v", err)
+	}
+
+	otherKey, _ := GenerateSigningKey("other", AlgorithmES256)
+	otherCfg := testConfig()
+	otherCfg.SigningKey = otherKey
+	foreign, _ := NewManager(otherCfg).GenerateTokens(context.Background(), TokenParams{UserID: "user-1", Email: "user@example.com"})
+	if _, err := m.ValidateAccessToken(context.Background(), foreign.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token with unknown kid: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestManager_RejectsAlgorithmConfusion(t *testing.T) {
+	key, _ := GenerateSigningKey("rsa", AlgorithmRS256)
+	cfg := testConfig()
+	cfg.SigningKey = key
+	m := NewManager(cfg)
+
+	// HS256 токен, подписанный публичным ключом как HMAC секретом
+	der, err := x509.MarshalPKIXPublicKey(key.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserID: "admin",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	})
+	forged.Header["kid"] = key.ID
+	tokenString, _ := forged.SignedString(der)
+
+	if _, err := m.ValidateAccessToken(context.Background(), tokenString); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("forged HS256 token: err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestManager_IssuerAudienceAndRoles(t *testing.T) {
+	cfg := testConfig()
+	cfg.Issuer = "auth-service"
+	cfg.Audience = []string{"api-gateway"}
+	m := NewManager(cfg)
+
+	pair, err := m.GenerateTokens(context.Background(), TokenParams{
+		UserID: "user-1",
+		Email:  "user@example.com",
+		Roles:  []string{"user", "admin"},
+		Scopes: []string{"orders:read", "orders:write"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateTokens: %v", err)
+	}
+
+	claims, err := m.ValidateAccessToken(context.Background(), pair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateAccessToken: %v", err)
+	}
+	if claims.Issuer != "auth-service" || len(claims.Audience) != 1 || claims.Audience[0] != "api-gateway" {
+		t.Errorf("iss = %q, aud = %v", claims.Issuer, claims.Audience)
+	}
+	if len(claims.Roles) != 2 || claims.Roles[1] != "admin" {
+		t.Errorf("Roles = %v", claims.Roles)
+	}
+	if scopes := claims.Scopes(); len(scopes) != 2 || scopes[0] != "orders:read" {
+		t.Errorf("Scopes = %v", scopes)
+	}
+
+	if _, err := m.ValidateRefreshToken(context.Background(), pair.RefreshToken); err != nil {
+		t.Errorf("ValidateRefreshToken: %v", err)
+	}
+
+	otherAudience := testConfig()
+	otherAudience.Issuer = "auth-service"
+	otherAudience.Audience = []string{"billing"}
+	if _, err := NewManager(otherAudience).ValidateAccessToken(context.Background(), pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("foreign audience: err = %v, want ErrInvalidToken", err)
+	}
+
+	otherIssuer := testConfig()
+	otherIssuer.Issuer = "someone-else"
+	otherIssuer.Audience = []string{"api-gateway"}
+	if _, err := NewManager(otherIssuer).ValidateAccessToken(context.Background(), pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("foreign issuer: err = %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,253 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Поддерживаемые асимметричные алгоритмы подписи
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrInvalidKey           = errors.New("invalid key")
+	ErrUnknownKeyID         = errors.New("unknown key id")
+)
+
+// Key - асимметричный ключ подписи/проверки JWT с идентификатором (kid)
+type Key struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer // nil, если ключ используется только для проверки
+	PublicKey  crypto.PublicKey
+}
+
+// NewSigningKey создает ключ подписи из приватного ключа.
+// Алгоритм определяется по типу ключа, пустой id заменяется отпечатком публичного ключа.
+func NewSigningKey(id string, privateKey crypto.Signer) (*Key, error) {
+	if privateKey == nil {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := NewVerificationKey(id, privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.PrivateKey = privateKey
+
+	return key, nil
+}
+
+// NewVerificationKey создает ключ, пригодный только для проверки подписи
+func NewVerificationKey(id string, publicKey crypto.PublicKey) (*Key, error) {
+	alg, err := algorithmForKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		id, err = keyFingerprint(publicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Key{
+		ID:        id,
+		Algorithm: alg,
+		PublicKey: publicKey,
+	}, nil
+}
+
+// GenerateSigningKey генерирует новый ключ подписи для указанного алгоритма
+func GenerateSigningKey(id, algorithm string) (*Key, error) {
+	var (
+		privateKey crypto.Signer
+		err        error
+	)
+
+	switch algorithm {
+	case AlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("generate %s key: %w", algorithm, err)
+	}
+
+	return NewSigningKey(id, privateKey)
+}
+
+// LoadSigningKeyFile читает приватный ключ в формате PEM из файла
+func LoadSigningKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key file: %w", err)
+	}
+
+	privateKey, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSigningKey(id, privateKey)
+}
+
+// LoadVerificationKeyFile читает публичный ключ в формате PEM из файла
+func LoadVerificationKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read verification key file: %w", err)
+	}
+
+	publicKey, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewVerificationKey(id, publicKey)
+}
+
+// ParsePrivateKeyPEM разбирает приватный ключ (PKCS#8, PKCS#1 или SEC 1)
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrInvalidKey)
+	}
+
+	var (
+		parsed any
+		err    error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported private key type %T", ErrInvalidKey, parsed)
+	}
+
+	return signer, nil
+}
+
+// EncodePrivateKeyPEM кодирует приватный ключ ключа подписи в PEM (PKCS#8)
+func (k *Key) EncodePrivateKeyPEM() ([]byte, error) {
+	if k.PrivateKey == nil {
+		return nil, fmt.Errorf("%w: key %s has no private part", ErrInvalidKey, k.ID)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(k.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// ParsePublicKeyPEM разбирает публичный ключ (PKIX или сертификат)
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrInvalidKey)
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		return publicKey, nil
+	default:
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		return publicKey, nil
+	}
+}
+
+// signingMethod возвращает метод подписи jwt для алгоритма ключа
+func (k *Key) signingMethod() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	case AlgorithmES256:
+		return jwt.SigningMethodES256
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return nil
+	}
+}
+
+// algorithmForKey определяет алгоритм подписи по типу публичного ключа
+func algorithmForKey(publicKey crypto.PublicKey) (string, error) {
+	switch pk := publicKey.(type) {
+	case *rsa.PublicKey:
+		if pk.N.BitLen() < 2048 {
+			return "", fmt.Errorf("%w: RSA key must be at least 2048 bits", ErrInvalidKey)
+		}
+		return AlgorithmRS256, nil
+	case *ecdsa.PublicKey:
+		if pk.Curve != elliptic.P256() {
+			return "", fmt.Errorf("%w: only P-256 curve is supported", ErrUnsupportedAlgorithm)
+		}
+		return AlgorithmES256, nil
+	case ed25519.PublicKey:
+		return AlgorithmEdDSA, nil
+	default:
+		return "", fmt.Errorf("%w: key type %T", ErrUnsupportedAlgorithm, publicKey)
+	}
+}
+
+// keyFingerprint - идентификатор ключа по умолчанию: SHA-256 от DER публичного ключа
+func keyFingerprint(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:16]), nil
+}
@@ -0,0 +1,166 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Типы opaque токенов, хранятся вместе с claims
+const (
+	opaqueAccessToken  = "access"
+	opaqueRefreshToken = "refresh"
+)
+
+// opaqueTokenBytes - энтропия opaque токена
+const opaqueTokenBytes = 32
+
+// opaqueRecord - то, что хранится на сервере вместо содержимого токена
+type opaqueRecord struct {
+	Type   string  `json:"type"`
+	Claims *Claims `json:"claims"`
+}
+
+// OpaqueManager выпускает случайные opaque токены, данные которых хранятся на сервере.
+// Клиент не видит claims; проверить токен можно только через этот сервис (introspection).
+type OpaqueManager struct {
+	config      Config
+	store       OpaqueTokenStore
+	revocations RevocationChecker
+	now         func() time.Time
+}
+
+// NewOpaqueManager создает менеджер opaque токенов. revocations может быть nil.
+func NewOpaqueManager(config Config, store OpaqueTokenStore, revocations RevocationChecker) *OpaqueManager {
+	return &OpaqueManager{
+		config:      config,
+		store:       store,
+		revocations: revocations,
+		now:         time.Now,
+	}
+}
+
+// GenerateTokens создает пару opaque токенов и сохраняет их claims
+func (m *OpaqueManager) GenerateTokens(ctx context.Context, params TokenParams) (*TokenPair, error) {
+	now := m.now()
+	pair := &TokenPair{
+		AccessTokenExpiresAt:  now.Add(m.config.AccessTokenExpiry),
+		RefreshTokenExpiresAt: now.Add(m.config.RefreshTokenExpiry),
+	}
+
+	accessClaims := newAccessClaims(m.config, params, now, pair.AccessTokenExpiresAt)
+
+	accessToken, err := m.issue(ctx, opaqueAccessToken, accessClaims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	refreshClaims := newRefreshClaims(m.config, params, now, pair.RefreshTokenExpiresAt)
+
+	refreshToken, err := m.issue(ctx, opaqueRefreshToken, refreshClaims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	pair.AccessToken = accessToken
+	pair.RefreshToken = refreshToken
+	return pair, nil
+}
+
+// GenerateAccessToken создает отдельный opaque access токен без refresh токена (token exchange)
+func (m *OpaqueManager) GenerateAccessToken(ctx context.Context, params TokenParams) (string, time.Time, error) {
+	now := m.now()
+	expiresAt := now.Add(accessTokenTTL(m.config, params))
+
+	token, err := m.issue(ctx, opaqueAccessToken, newAccessClaims(m.config, params, now, expiresAt))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return token, expiresAt, nil
+}
+
+// ValidateAccessToken находит access токен в хранилище и проверяет срок и отзыв по jti
+func (m *OpaqueManager) ValidateAccessToken(ctx context.Context, token string) (*Claims, error) {
+	claims, err := m.lookup(ctx, opaqueAccessToken, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.revocations != nil && claims.ID != "" {
+		revoked, err := m.revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, fmt.Errorf("check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+
+	return claims, nil
+}
+
+// ValidateRefreshToken находит refresh токен в хранилище и проверяет срок
+func (m *OpaqueManager) ValidateRefreshToken(ctx context.Context, token string) (*Claims, error) {
+	return m.lookup(ctx, opaqueRefreshToken, token)
+}
+
+func (m *OpaqueManager) issue(ctx context.Context, tokenType string, claims *Claims) (string, error) {
+	buf := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	data, err := json.Marshal(opaqueRecord{Type: tokenType, Claims: claims})
+	if err != nil {
+		return "", err
+	}
+
+	if err := m.store.Save(ctx, hashOpaqueToken(token), data, claims.ExpiresAt.Time); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (m *OpaqueManager) lookup(ctx context.Context, tokenType, token string) (*Claims, error) {
+	// Случайные токены имеют фиксированную длину, остальное даже не ищем в хранилище
+	if base64.RawURLEncoding.DecodedLen(len(token)) != opaqueTokenBytes {
+		return nil, ErrInvalidToken
+	}
+
+	data, ok, err := m.store.Load(ctx, hashOpaqueToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("load opaque token: %w", err)
+	}
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	var record opaqueRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.Claims == nil {
+		return nil, ErrInvalidToken
+	}
+	if record.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	claims := record.Claims
+	if claims.ExpiresAt == nil || !claims.ExpiresAt.After(m.now()) {
+		return nil, ErrExpiredToken
+	}
+
+	return claims, nil
+}
+
+// hashOpaqueToken - в хранилище попадает только SHA-256 от токена
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,330 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"auth-service/internal/util/paseto"
+)
+
+// Назначение (purpose) PASETO access токенов
+const (
+	PasetoLocal  = "local"  // v4.local: зашифрованы, проверяет только этот сервис
+	PasetoPublic = "public" // v4.public: подписаны Ed25519, проверяются по публичному ключу
+)
+
+// PasetoConfig - ключи PASETO v4. Refresh токены всегда v4.local с RefreshKey.
+type PasetoConfig struct {
+	Purpose    string
+	LocalKey   []byte // 32 байта, только для purpose local
+	RefreshKey []byte // 32 байта
+}
+
+// pasetoFooter - footer v4.public токенов, по kid выбирается ключ проверки
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// Временные claims в PASETO - строки RFC 3339, а не NumericDate
+var pasetoTimeClaims = []string{"exp", "iat", "nbf"}
+
+// PasetoManager выпускает токены PASETO v4 с теми же claims, что и Manager.
+// Алгоритм зафиксирован версией и назначением токена, поэтому подмена алгоритма невозможна.
+type PasetoManager struct {
+	config Config
+	paseto PasetoConfig
+
+	mu               sync.RWMutex
+	signingKey       *Key
+	verificationKeys map[string]*Key
+
+	revocations RevocationChecker
+	now         func() time.Time
+}
+
+// NewPasetoManager создает менеджер PASETO. Для purpose public ключи берутся из
+// config.SigningKey/VerificationKeys и должны быть Ed25519. revocations может быть nil.
+func NewPasetoManager(config Config, pasetoConfig PasetoConfig, revocations RevocationChecker) (*PasetoManager, error) {
+	if len(pasetoConfig.RefreshKey) != paseto.LocalKeySize {
+		return nil, fmt.Errorf("%w: paseto refresh key must be %d bytes", ErrInvalidKey, paseto.LocalKeySize)
+	}
+
+	switch pasetoConfig.Purpose {
+	case PasetoLocal:
+		if len(pasetoConfig.LocalKey) != paseto.LocalKeySize {
+			return nil, fmt.Errorf("%w: paseto local key must be %d bytes", ErrInvalidKey, paseto.LocalKeySize)
+		}
+	case PasetoPublic:
+		for _, key := range append([]*Key{config.SigningKey}, config.VerificationKeys...) {
+			if key != nil && key.Algorithm != AlgorithmEdDSA {
+				return nil, fmt.Errorf("%w: paseto v4.public requires Ed25519 keys, got %s (kid %s)", ErrUnsupportedAlgorithm, key.Algorithm, key.ID)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown paseto purpose %q", pasetoConfig.Purpose)
+	}
+
+	m := &PasetoManager{
+		config:      config,
+		paseto:      pasetoConfig,
+		revocations: revocations,
+		now:         time.Now,
+	}
+	m.SetKeys(config.SigningKey, config.VerificationKeys)
+
+	return m, nil
+}
+
+// SetKeys заменяет ключи v4.public (ротация). Ключи не Ed25519 игнорируются.
+func (m *PasetoManager) SetKeys(signingKey *Key, verificationKeys []*Key) {
+	keys := make(map[string]*Key, len(verificationKeys)+1)
+	for _, key := range append(verificationKeys, signingKey) {
+		if key != nil && key.Algorithm == AlgorithmEdDSA {
+			keys[key.ID] = key
+		}
+	}
+	if signingKey != nil && signingKey.Algorithm != AlgorithmEdDSA {
+		signingKey = nil
+	}
+
+	m.mu.Lock()
+	m.signingKey = signingKey
+	m.verificationKeys = keys
+	m.mu.Unlock()
+}
+
+// JWKS публикует публичные ключи v4.public (OKP/Ed25519)
+func (m *PasetoManager) JWKS() (*JWKS, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return buildJWKS(m.signingKey, m.verificationKeys)
+}
+
+// GenerateTokens создает пару PASETO токенов
+func (m *PasetoManager) GenerateTokens(ctx context.Context, params TokenParams) (*TokenPair, error) {
+	now := m.now()
+	pair := &TokenPair{
+		AccessTokenExpiresAt:  now.Add(m.config.AccessTokenExpiry),
+		RefreshTokenExpiresAt: now.Add(m.config.RefreshTokenExpiry),
+	}
+
+	accessToken, err := m.sealAccessToken(newAccessClaims(m.config, params, now, pair.AccessTokenExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	payload, err := encodePasetoClaims(newRefreshClaims(m.config, params, now, pair.RefreshTokenExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken, err := paseto.Encrypt(m.paseto.RefreshKey, payload, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	pair.AccessToken = accessToken
+	pair.RefreshToken = refreshToken
+	return pair, nil
+}
+
+// GenerateAccessToken создает отдельный access токен без refresh токена (token exchange)
+func (m *PasetoManager) GenerateAccessToken(ctx context.Context, params TokenParams) (string, time.Time, error) {
+	now := m.now()
+	expiresAt := now.Add(accessTokenTTL(m.config, params))
+
+	token, err := m.sealAccessToken(newAccessClaims(m.config, params, now, expiresAt))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return token, expiresAt, nil
+}
+
+// ValidateAccessToken проверяет access токен, iss/aud и, если настроено, отзыв по jti
+func (m *PasetoManager) ValidateAccessToken(ctx context.Context, token string) (*Claims, error) {
+	payload, err := m.openAccessToken(token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, err := m.validateClaims(payload, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.revocations != nil && claims.ID != "" {
+		revoked, err := m.revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, fmt.Errorf("check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+
+	return claims, nil
+}
+
+// ValidateRefreshToken расшифровывает v4.local refresh токен
+func (m *PasetoManager) ValidateRefreshToken(ctx context.Context, token string) (*Claims, error) {
+	payload, _, err := paseto.Decrypt(m.paseto.RefreshKey, token, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return m.validateClaims(payload, false)
+}
+
+func (m *PasetoManager) sealAccessToken(claims *Claims) (string, error) {
+	payload, err := encodePasetoClaims(claims)
+	if err != nil {
+		return "", err
+	}
+
+	if m.paseto.Purpose == PasetoLocal {
+		return paseto.Encrypt(m.paseto.LocalKey, payload, nil, nil)
+	}
+
+	m.mu.RLock()
+	key := m.signingKey
+	m.mu.RUnlock()
+
+	if key == nil || key.PrivateKey == nil {
+		return "", fmt.Errorf("%w: no Ed25519 signing key", ErrInvalidKey)
+	}
+	privateKey, ok := key.PrivateKey.(ed25519.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("%w: key %s is not Ed25519", ErrInvalidKey, key.ID)
+	}
+
+	footer, err := json.Marshal(pasetoFooter{KeyID: key.ID})
+	if err != nil {
+		return "", err
+	}
+
+	return paseto.Sign(privateKey, payload, footer, nil)
+}
+
+func (m *PasetoManager) openAccessToken(token string) ([]byte, error) {
+	if m.paseto.Purpose == PasetoLocal {
+		payload, _, err := paseto.Decrypt(m.paseto.LocalKey, token, nil)
+		return payload, err
+	}
+
+	rawFooter, err := paseto.Footer(token)
+	if err != nil {
+		return nil, err
+	}
+	var footer pasetoFooter
+	if err := json.Unmarshal(rawFooter, &footer); err != nil || footer.KeyID == "" {
+		return nil, fmt.Errorf("%w: missing kid footer", ErrUnknownKeyID)
+	}
+
+	m.mu.RLock()
+	key, ok := m.verificationKeys[footer.KeyID]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, footer.KeyID)
+	}
+
+	publicKey, ok := key.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: key %s is not Ed25519", ErrInvalidKey, key.ID)
+	}
+
+	payload, _, err := paseto.Verify(publicKey, token, nil)
+	return payload, err
+}
+
+// validateClaims проверяет exp/nbf, iss и, для access токенов, aud
+func (m *PasetoManager) validateClaims(payload []byte, checkAudience bool) (*Claims, error) {
+	claims, err := decodePasetoClaims(payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := m.now()
+	if claims.ExpiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+	if !claims.ExpiresAt.After(now) {
+		return nil, ErrExpiredToken
+	}
+	if claims.NotBefore != nil && claims.NotBefore.After(now) {
+		return nil, ErrInvalidToken
+	}
+
+	if m.config.Issuer != "" && claims.Issuer != m.config.Issuer {
+		return nil, ErrInvalidToken
+	}
+	if audience := acceptedAudiences(m.config); checkAudience && len(audience) > 0 &&
+		!slices.ContainsFunc(claims.Audience, func(aud string) bool { return slices.Contains(audience, aud) }) {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// encodePasetoClaims сериализует Claims, заменяя NumericDate на строки RFC 3339
+func encodePasetoClaims(claims *Claims) ([]byte, error) {
+	raw, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+
+	for _, name := range pasetoTimeClaims {
+		if value, ok := fields[name].(float64); ok {
+			fields[name] = time.Unix(int64(value), 0).UTC().Format(time.RFC3339)
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// decodePasetoClaims - обратное преобразование encodePasetoClaims
+func decodePasetoClaims(payload []byte) (*Claims, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+
+	for _, name := range pasetoTimeClaims {
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			return nil, errors.New("paseto time claim must be a string")
+		}
+		parsed, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = parsed.Unix()
+	}
+
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,102 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/util/paseto"
+)
+
+func testPasetoConfig(purpose string) PasetoConfig {
+	return PasetoConfig{
+		Purpose:    purpose,
+		LocalKey:   bytes.Repeat([]byte{1}, paseto.LocalKeySize),
+		RefreshKey: bytes.Repeat([]byte{2}, paseto.LocalKeySize),
+	}
+}
+
+func TestPasetoManager_RoundTrip(t *testing.T) {
+	key, _ := GenerateSigningKey("ed-1", AlgorithmEdDSA)
+
+	for _, purpose := range []string{PasetoLocal, PasetoPublic} {
+		t.Run(purpose, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.SigningKey = key
+			cfg.Issuer = "auth-service"
+			cfg.Audience = []string{"api-gateway"}
+			m, err := NewPasetoManager(cfg, testPasetoConfig(purpose), nil)
+			if err != nil {
+				t.Fatalf("NewPasetoManager: %v", err)
+			}
+
+			pair, err := m.GenerateTokens(context.Background(), TokenParams{UserID: "user-1", Email: "user@example.com", Roles: []string{"admin"}})
+			if err != nil {
+				t.Fatalf("GenerateTokens: %v", err)
+			}
+			if !strings.HasPrefix(pair.AccessToken, "v4."+purpose+".") || !strings.HasPrefix(pair.RefreshToken, paseto.HeaderLocal) {
+				t.Fatalf("tokens = %s, %s", pair.AccessToken, pair.RefreshToken)
+			}
+
+			claims, err := m.ValidateAccessToken(context.Background(), pair.AccessToken)
+			if err != nil {
+				t.Fatalf("ValidateAccessToken: %v", err)
+			}
+			if claims.UserID != "user-1" || claims.Roles[0] != "admin" || !claims.ExpiresAt.Equal(pair.AccessTokenExpiresAt.Truncate(time.Second)) {
+				t.Errorf("claims = %+v", claims)
+			}
+
+			if _, err := m.ValidateRefreshToken(context.Background(), pair.RefreshToken); err != nil {
+				t.Errorf("ValidateRefreshToken: %v", err)
+			}
+			if _, err := m.ValidateAccessToken(context.Background(), pair.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("refresh token as access: err = %v", err)
+			}
+
+			// Другой audience
+			otherCfg := cfg
+			otherCfg.Audience = []string{"billing"}
+			other, _ := NewPasetoManager(otherCfg, testPasetoConfig(purpose), nil)
+			if _, err := other.ValidateAccessToken(context.Background(), pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("foreign audience: err = %v", err)
+			}
+		})
+	}
+}
+
+func TestPasetoManager_PublicVerification(t *testing.T) {
+	key, _ := GenerateSigningKey("ed-1", AlgorithmEdDSA)
+	cfg := testConfig()
+	cfg.SigningKey = key
+	issuer, _ := NewPasetoManager(cfg, testPasetoConfig(PasetoPublic), nil)
+
+	pair, err := issuer.GenerateTokens(context.Background(), TokenParams{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("GenerateTokens: %v", err)
+	}
+
+	// Сервис только с публичным ключом
+	publicKey, _ := NewVerificationKey(key.ID, key.PublicKey)
+	verifierCfg := testConfig()
+	verifierCfg.VerificationKeys = []*Key{publicKey}
+	verifier, _ := NewPasetoManager(verifierCfg, testPasetoConfig(PasetoPublic), nil)
+	if _, err := verifier.ValidateAccessToken(context.Background(), pair.AccessToken); err != nil {
+		t.Errorf("ValidateAccessToken with public key: %v", err)
+	}
+
+	otherKey, _ := GenerateSigningKey("ed-2", AlgorithmEdDSA)
+	verifier.SetKeys(nil, []*Key{otherKey})
+	if _, err := verifier.ValidateAccessToken(context.Background(), pair.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("unknown kid: err = %v", err)
+	}
+
+	ecKey, _ := GenerateSigningKey("ec", AlgorithmES256)
+	ecCfg := testConfig()
+	ecCfg.SigningKey = ecKey
+	if _, err := NewPasetoManager(ecCfg, testPasetoConfig(PasetoPublic), nil); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("ES256 key: err = %v, want ErrUnsupportedAlgorithm", err)
+	}
+}
@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type fileMailer struct {
+	dir  string
+	from string
+	now  func() time.Time
+}
+
+// NewFileMailer сохраняет каждое письмо в отдельный .eml файл в dir (локальная разработка, тесты)
+func NewFileMailer(dir, from string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create mail directory: %w", err)
+	}
+	return &fileMailer{dir: dir, from: from, now: time.Now}, nil
+}
+
+func (m *fileMailer) Send(ctx context.Context, msg Message) error {
+	now := m.now()
+	data, err := render(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), data, 0o600); err != nil {
+		return fmt.Errorf("write mail file: %w", err)
+	}
+	return nil
+}
+
+type writerMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+	now  func() time.Time
+}
+
+// NewWriterMailer печатает письма в w, например в os.Stdout
+func NewWriterMailer(w io.Writer, from string) Mailer {
+	return &writerMailer{w: w, from: from, now: time.Now}
+}
+
+func (m *writerMailer) Send(ctx context.Context, msg Message) error {
+	data, err := render(m.from, msg, m.now())
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := fmt.Fprintf(m.w, "%s\r\n.\r\n", data); err != nil {
+		return fmt.Errorf("write mail: %w", err)
+	}
+	return nil
+}
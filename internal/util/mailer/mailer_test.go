@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriterMailer_Send(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewWriterMailer(&buf, "auth@example.com")
+
+	if err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Сброс пароля", Body: "line 1\nline 2"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"From: auth@example.com\r\n", "To: user@example.com\r\n", "Subject: =?utf-8?q?", "\r\n\r\nline 1\r\nline 2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMailer_RejectsHeaderInjection(t *testing.T) {
+	m := NewWriterMailer(&bytes.Buffer{}, "auth@example.com")
+
+	for _, msg := range []Message{
+		{To: "user@example.com\r\nBcc: attacker@example.com", Subject: "hi"},
+		{To: "user@example.com", Subject: "hi\nBcc: attacker@example.com"},
+		{To: "", Subject: "hi"},
+	} {
+		if err := m.Send(context.Background(), msg); !errors.Is(err, ErrInvalidMessage) {
+			t.Errorf("Send(%q) err = %v, want ErrInvalidMessage", msg.To, err)
+		}
+	}
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir, "auth@example.com")
+	if err != nil {
+		t.Fatalf("NewFileMailer: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "hi", Body: "body"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 2 {
+		t.Fatalf("got %d mail files, want 2", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if !bytes.Contains(data, []byte("To: user@example.com")) {
+		t.Errorf("unexpected mail file:\n%s", data)
+	}
+}
@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig - параметры SMTP сервера
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // пусто - без аутентификации
+	Password string
+	From     string
+}
+
+type smtpMailer struct {
+	config SMTPConfig
+	now    func() time.Time
+}
+
+// NewSMTPMailer создает отправку через SMTP. STARTTLS используется, если сервер его поддерживает;
+// net/smtp не передает пароль по незашифрованному соединению (кроме localhost).
+func NewSMTPMailer(config SMTPConfig) Mailer {
+	return &smtpMailer{config: config, now: time.Now}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	data, err := render(m.config.From, msg, m.now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	if err := smtp.SendMail(addr, auth, m.config.From, []string{msg.To}, data); err != nil {
+		return fmt.Errorf("send mail via smtp: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+// Package paseto реализует PASETO v4 (local и public) по спецификации
+// https://github.com/paseto-standard/paseto-spec/blob/master/docs/01-Protocol-Versions/Version4.md
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+// Заголовки токенов v4
+const (
+	HeaderLocal  = "v4.local."
+	HeaderPublic = "v4.public."
+)
+
+// LocalKeySize - размер симметричного ключа v4.local
+const LocalKeySize = 32
+
+const (
+	nonceSize = 32
+	macSize   = 32
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid paseto token")
+	ErrInvalidKey   = errors.New("invalid paseto key")
+)
+
+// Encrypt создает v4.local токен (XChaCha20 + BLAKE2b-MAC)
+func Encrypt(key, message, footer, implicit []byte) (string, error) {
+	if len(key) != LocalKeySize {
+		return "", ErrInvalidKey
+	}
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return encrypt(key, nonce, message, footer, implicit)
+}
+
+func encrypt(key, nonce, message, footer, implicit []byte) (string, error) {
+	encKey, counterNonce, authKey, err := splitKey(key, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(message))
+	cipher.XORKeyStream(ciphertext, message)
+
+	tag, err := mac(authKey, pae([]byte(HeaderLocal), nonce, ciphertext, footer, implicit))
+	if err != nil {
+		return "", err
+	}
+
+	body := make([]byte, 0, len(nonce)+len(ciphertext)+len(tag))
+	body = append(append(append(body, nonce...), ciphertext...), tag...)
+
+	return encode(HeaderLocal, body, footer), nil
+}
+
+// Decrypt проверяет и расшифровывает v4.local токен, возвращает сообщение и footer
+func Decrypt(key []byte, token string, implicit []byte) (message, footer []byte, err error) {
+	if len(key) != LocalKeySize {
+		return nil, nil, ErrInvalidKey
+	}
+
+	body, footer, err := decode(HeaderLocal, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) < nonceSize+macSize {
+		return nil, nil, ErrInvalidToken
+	}
+
+	nonce := body[:nonceSize]
+	ciphertext := body[nonceSize : len(body)-macSize]
+	tag := body[len(body)-macSize:]
+
+	encKey, counterNonce, authKey, err := splitKey(key, nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expected, err := mac(authKey, pae([]byte(HeaderLocal), nonce, ciphertext, footer, implicit))
+	if err != nil {
+		return nil, nil, err
+	}
+	if subtle.ConstantTimeCompare(tag, expected) != 1 {
+		return nil, nil, ErrInvalidToken
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	message = make([]byte, len(ciphertext))
+	cipher.XORKeyStream(message, ciphertext)
+
+	return message, footer, nil
+}
+
+// Sign создает v4.public токен (Ed25519)
+func Sign(key ed25519.PrivateKey, message, footer, implicit []byte) (string, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return "", ErrInvalidKey
+	}
+
+	signature := ed25519.Sign(key, pae([]byte(HeaderPublic), message, footer, implicit))
+
+	body := make([]byte, 0, len(message)+len(signature))
+	body = append(append(body, message...), signature...)
+
+	return encode(HeaderPublic, body, footer), nil
+}
+
+// Verify проверяет подпись v4.public токена, возвращает сообщение и footer
+func Verify(key ed25519.PublicKey, token string, implicit []byte) (message, footer []byte, err error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, nil, ErrInvalidKey
+	}
+
+	body, footer, err := decode(HeaderPublic, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) < ed25519.SignatureSize {
+		return nil, nil, ErrInvalidToken
+	}
+
+	message = body[:len(body)-ed25519.SignatureSize]
+	signature := body[len(body)-ed25519.SignatureSize:]
+
+	if !ed25519.Verify(key, pae([]byte(HeaderPublic), message, footer, implicit), signature) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	return message, footer, nil
+}
+
+// Footer возвращает непроверенный footer токена, например чтобы выбрать ключ по kid
+func Footer(token string) ([]byte, error) {
+	for _, header := range []string{HeaderLocal, HeaderPublic} {
+		if strings.HasPrefix(token, header) {
+			_, footer, err := decode(header, token)
+			return footer, err
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+// splitKey выводит ключ шифрования, nonce XChaCha20 и ключ MAC (шаги 4-5 v4.local)
+func splitKey(key, nonce []byte) (encKey, counterNonce, authKey []byte, err error) {
+	h, err := blake2b.New(56, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	h.Write([]byte("paseto-encryption-key"))
+	h.Write(nonce)
+	tmp := h.Sum(nil)
+
+	authKey, err = mac(key, append([]byte("paseto-auth-key-for-aead"), nonce...))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return tmp[:32], tmp[32:], authKey, nil
+}
+
+func mac(key, data []byte) ([]byte, error) {
+	h, err := blake2b.New(macSize, key)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// pae - Pre-Authentication Encoding
+func pae(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	writeLE64(&buf, len(pieces))
+	for _, piece := range pieces {
+		writeLE64(&buf, len(piece))
+		buf.Write(piece)
+	}
+	return buf.Bytes()
+}
+
+func writeLE64(buf *bytes.Buffer, n int) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(n)&^(1<<63))
+	buf.Write(b[:])
+}
+
+func encode(header string, body, footer []byte) string {
+	token := header + base64.RawURLEncoding.EncodeToString(body)
+	if len(footer) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(footer)
+	}
+	return token
+}
+
+func decode(header, token string) (body, footer []byte, err error) {
+	rest, ok := strings.CutPrefix(token, header)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: expected %s header", ErrInvalidToken, strings.TrimSuffix(header, "."))
+	}
+
+	payload, encodedFooter, hasFooter := strings.Cut(rest, ".")
+	if strings.Contains(encodedFooter, ".") {
+		return nil, nil, ErrInvalidToken
+	}
+
+	if body, err = base64.RawURLEncoding.DecodeString(payload); err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+	if hasFooter {
+		if footer, err = base64.RawURLEncoding.DecodeString(encodedFooter); err != nil {
+			return nil, nil, ErrInvalidToken
+		}
+	}
+
+	return body, footer, nil
+}
@@ -0,0 +1,100 @@
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// Вектор 4-S-1 из paseto-standard/test-vectors
+func TestVerify_Vector4S1(t *testing.T) {
+	publicKey, _ := hex.DecodeString("1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	secretKey, _ := hex.DecodeString("b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a37741eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2")
+	payload := []byte(`{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`)
+	token := "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA"
+
+	signed, err := Sign(ed25519.PrivateKey(secretKey), payload, nil, nil)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if signed != token {
+		t.Errorf("Sign =\n%s\nwant\n%s", signed, token)
+	}
+
+	message, _, err := Verify(ed25519.PublicKey(publicKey), token, nil)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !bytes.Equal(message, payload) {
+		t.Errorf("message = %s", message)
+	}
+}
+
+// Вектор 4-E-1 из paseto-standard/test-vectors (нулевой nonce)
+func TestEncrypt_Vector4E1(t *testing.T) {
+	key, _ := hex.DecodeString("707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f")
+	payload := []byte(`{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`)
+	token := "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg"
+
+	encrypted, err := encrypt(key, make([]byte, nonceSize), payload, nil, nil)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if encrypted != token {
+		t.Errorf("encrypt =\n%s\nwant\n%s", encrypted, token)
+	}
+
+	message, _, err := Decrypt(key, token, nil)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(message, payload) {
+		t.Errorf("message = %s", message)
+	}
+}
+
+func TestLocal_RoundTripAndTampering(t *testing.T) {
+	key := make([]byte, LocalKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	footer := []byte(`{"kid":"k1"}`)
+	implicit := []byte("auth-service")
+
+	token, err := Encrypt(key, []byte(`{"sub":"user-1"}`), footer, implicit)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	message, gotFooter, err := Decrypt(key, token, implicit)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if string(message) != `{"sub":"user-1"}` || !bytes.Equal(gotFooter, footer) {
+		t.Errorf("message = %s, footer = %s", message, gotFooter)
+	}
+
+	if _, _, err := Decrypt(key, token, []byte("other")); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("wrong implicit assertion: err = %v", err)
+	}
+
+	otherKey := bytes.Repeat([]byte{1}, LocalKeySize)
+	if _, _, err := Decrypt(otherKey, token, implicit); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("wrong key: err = %v", err)
+	}
+
+	// Подмена footer должна ломать MAC
+	forged := token[:len(token)-len(`eyJraWQiOiJrMSJ9`)] + "eyJraWQiOiJrMiJ9"
+	if _, _, err := Decrypt(key, forged, implicit); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("forged footer: err = %v", err)
+	}
+
+	// local токен нельзя предъявить как public
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, _, err := Verify(publicKey, token, implicit); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("local token verified as public: err = %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package password
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"math"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params - параметры argon2id (RFC 9106)
+type Argon2Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params - рекомендация OWASP: m=64 MiB, t=3, p=2
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Ограничения на параметры из хеша, чтобы испорченная запись не съела всю память
+const (
+	maxArgon2Memory     = 4 * 1024 * 1024 // 4 GiB
+	maxArgon2Iterations = 64
+)
+
+type argon2Hasher struct {
+	params Argon2Params
+}
+
+// NewArgon2id создает argon2id хешер
+func NewArgon2id(params Argon2Params) PasswordHasher {
+	return &argon2Hasher{params: params}
+}
+
+func (h *argon2Hasher) Algorithm() string {
+	return AlgorithmArgon2id
+}
+
+func (h *argon2Hasher) Hash(password string) (string, error) {
+	if password == "" {
+		return "", ErrEmptyPassword
+	}
+
+	salt, err := randomSalt(h.params.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", p.Memory, p.Iterations, p.Parallelism)
+
+	return encodePHC(AlgorithmArgon2id, argon2.Version, params, salt, key), nil
+}
+
+func (h *argon2Hasher) Verify(password, encoded string) (bool, error) {
+	p, params, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), p.salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, p.hash) == 1, nil
+}
+
+func (h *argon2Hasher) NeedsRehash(encoded string) bool {
+	p, params, err := decodeArgon2(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		params.KeyLength != h.params.KeyLength ||
+		len(p.salt) < h.params.SaltLength
+}
+
+func decodeArgon2(encoded string) (*phcHash, Argon2Params, error) {
+	p, err := parsePHC(encoded, AlgorithmArgon2id)
+	if err != nil {
+		return nil, Argon2Params{}, err
+	}
+	if p.version != argon2.Version {
+		return nil, Argon2Params{}, fmt.Errorf("%w: argon2 version %d", ErrInvalidHash, p.version)
+	}
+
+	memory, err := p.uintParam("m", maxArgon2Memory)
+	if err != nil {
+		return nil, Argon2Params{}, err
+	}
+	iterations, err := p.uintParam("t", maxArgon2Iterations)
+	if err != nil {
+		return nil, Argon2Params{}, err
+	}
+	parallelism, err := p.uintParam("p", math.MaxUint8)
+	if err != nil {
+		return nil, Argon2Params{}, err
+	}
+
+	return p, Argon2Params{
+		Memory:      uint32(memory),
+		Iterations:  uint32(iterations),
+		Parallelism: uint8(parallelism),
+		SaltLength:  len(p.salt),
+		KeyLength:   uint32(len(p.hash)),
+	}, nil
+}
@@ -0,0 +1,129 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// BreachedChecker проверяет пароль по базе утекших паролей
+type BreachedChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// sha1HexLength - длина SHA-1 в hex
+const sha1HexLength = 2 * sha1.Size
+
+// maxBreachedLine - строки корпуса короткие ("<sha1>:<count>"), читаем окном такого размера
+const maxBreachedLine = 128
+
+// BreachedFile - локальная копия базы Pwned Passwords в формате "ordered by hash":
+// строки "<SHA1 HEX>:<count>", отсортированные по хешу. Файл не загружается в память,
+// поиск - бинарный по смещениям, поэтому подходит и полный корпус на десятки гигабайт.
+// Как и в range API (k-anonymity), в файле нет самих паролей, только SHA-1.
+type BreachedFile struct {
+	file *os.File
+	size int64
+}
+
+// OpenBreachedFile открывает отсортированный файл с SHA-1 утекших паролей
+func OpenBreachedFile(path string) (*BreachedFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached password file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("stat breached password file: %w", err)
+	}
+
+	return &BreachedFile{file: file, size: info.Size()}, nil
+}
+
+// Close закрывает файл корпуса
+func (f *BreachedFile) Close() error {
+	return f.file.Close()
+}
+
+// IsBreached ищет SHA-1 пароля в корпусе
+func (f *BreachedFile) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	// Инвариант: искомая строка, если есть, начинается в [lo, hi)
+	lo, hi := int64(0), f.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, line, err := f.lineAt(mid)
+		if err != nil {
+			return false, err
+		}
+		if start < 0 || start >= hi {
+			hi = mid
+			continue
+		}
+
+		switch hash := lineHash(line); {
+		case hash == target:
+			return true, nil
+		case hash < target:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+
+	return false, nil
+}
+
+// lineAt возвращает первую строку, начинающуюся не раньше offset.
+// start = -1, если таких строк нет.
+func (f *BreachedFile) lineAt(offset int64) (int64, []byte, error) {
+	// Читаем с байта перед offset: если там '\n', строка начинается ровно в offset
+	readFrom := offset
+	if offset > 0 {
+		readFrom = offset - 1
+	}
+
+	buf := make([]byte, 2*maxBreachedLine)
+	n, err := f.file.ReadAt(buf, readFrom)
+	if err != nil && err != io.EOF {
+		return 0, nil, fmt.Errorf("read breached password file: %w", err)
+	}
+	buf = buf[:n]
+
+	start := 0
+	if offset > 0 {
+		newline := bytes.IndexByte(buf, '\n')
+		if newline < 0 {
+			return -1, nil, nil
+		}
+		start = newline + 1
+	}
+	if start >= len(buf) {
+		return -1, nil, nil
+	}
+
+	line := buf[start:]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+
+	return readFrom + int64(start), line, nil
+}
+
+// lineHash - хеш из строки "<hash>[:count]" в верхнем регистре
+func lineHash(line []byte) string {
+	hash, _, _ := bytes.Cut(bytes.TrimRight(line, "\r"), []byte(":"))
+	if len(hash) > sha1HexLength {
+		hash = hash[:sha1HexLength]
+	}
+	return strings.ToUpper(string(hash))
+}
@@ -0,0 +1,184 @@
+// Package password хеширует пароли. Хеши хранятся в формате PHC
+// ($<id>$v=<version>$<params>$<salt>$<hash>), bcrypt - в своем модульном формате ($2a$...),
+// поэтому алгоритм и параметры всегда восстанавливаются из самой строки.
+// Хеш с перцем дополнительно начинается с $pepper$v=<версия>.
+package password
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Идентификаторы алгоритмов
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmScrypt   = "scrypt"
+)
+
+var (
+	ErrInvalidHash          = errors.New("invalid password hash format")
+	ErrUnsupportedAlgorithm = errors.New("unsupported password hash algorithm")
+	ErrEmptyPassword        = errors.New("password must not be empty")
+)
+
+// PasswordHasher хеширует и проверяет пароли
+type PasswordHasher interface {
+	// Algorithm - идентификатор алгоритма новых хешей
+	Algorithm() string
+	Hash(password string) (string, error)
+	// Verify возвращает false без ошибки, если пароль не подходит
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash сообщает, что хеш сделан другим алгоритмом или с устаревшими параметрами
+	NeedsRehash(encoded string) bool
+}
+
+// multiHasher создает хеши предпочтительным алгоритмом, а проверяет любым из известных
+type multiHasher struct {
+	preferred PasswordHasher
+	hashers   map[string]PasswordHasher
+}
+
+// NewHasher создает хешер: новые хеши - preferred, проверка - preferred и legacy.
+// Хеш любого другого алгоритма считается устаревшим (NeedsRehash).
+func NewHasher(preferred PasswordHasher, legacy ...PasswordHasher) PasswordHasher {
+	hashers := make(map[string]PasswordHasher, len(legacy)+1)
+	for _, hasher := range legacy {
+		hashers[hasher.Algorithm()] = hasher
+	}
+	hashers[preferred.Algorithm()] = preferred
+
+	return &multiHasher{preferred: preferred, hashers: hashers}
+}
+
+func (h *multiHasher) Algorithm() string {
+	return h.preferred.Algorithm()
+}
+
+func (h *multiHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h *multiHasher) Verify(password, encoded string) (bool, error) {
+	algorithm, err := Identify(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	hasher, ok := h.hashers[algorithm]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	return hasher.Verify(password, encoded)
+}
+
+func (h *multiHasher) NeedsRehash(encoded string) bool {
+	algorithm, err := Identify(encoded)
+	if err != nil || algorithm != h.preferred.Algorithm() {
+		return true
+	}
+	return h.preferred.NeedsRehash(encoded)
+}
+
+// Identify определяет алгоритм по строке хеша
+func Identify(encoded string) (string, error) {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return AlgorithmBcrypt, nil
+		}
+	}
+
+	id, _, ok := strings.Cut(strings.TrimPrefix(encoded, "$"), "$")
+	if !ok || !strings.HasPrefix(encoded, "$") || id == "" {
+		return "", ErrInvalidHash
+	}
+	return id, nil
+}
+
+// phcHash - разобранная строка PHC
+type phcHash struct {
+	id      string
+	version int // 0, если сегмента v= нет
+	params  map[string]string
+	salt    []byte
+	hash    []byte
+}
+
+// PHC использует base64 без padding
+var phcEncoding = base64.RawStdEncoding
+
+// encodePHC собирает строку PHC; params уже отформатированы в порядке алгоритма
+func encodePHC(id string, version int, params string, salt, hash []byte) string {
+	var b strings.Builder
+	b.WriteString("$" + id)
+	if version != 0 {
+		b.WriteString("$v=" + strconv.Itoa(version))
+	}
+	b.WriteString("$" + params)
+	b.WriteString("$" + phcEncoding.EncodeToString(salt))
+	b.WriteString("$" + phcEncoding.EncodeToString(hash))
+	return b.String()
+}
+
+// parsePHC разбирает $id[$v=N]$k=v,...$salt$hash
+func parsePHC(encoded, id string) (*phcHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) < 5 || parts[0] != "" || parts[1] != id {
+		return nil, ErrInvalidHash
+	}
+
+	p := &phcHash{id: id}
+	rest := parts[2:]
+	if version, ok := strings.CutPrefix(rest[0], "v="); ok {
+		n, err := strconv.Atoi(version)
+		if err != nil {
+			return nil, ErrInvalidHash
+		}
+		p.version = n
+		rest = rest[1:]
+	}
+	if len(rest) != 3 {
+		return nil, ErrInvalidHash
+	}
+
+	p.params = make(map[string]string)
+	for _, pair := range strings.Split(rest[0], ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, ErrInvalidHash
+		}
+		p.params[key] = value
+	}
+
+	var err error
+	if p.salt, err = phcEncoding.DecodeString(rest[1]); err != nil {
+		return nil, ErrInvalidHash
+	}
+	if p.hash, err = phcEncoding.DecodeString(rest[2]); err != nil || len(p.hash) == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	return p, nil
+}
+
+// uintParam читает числовой параметр PHC с верхней границей
+func (p *phcHash) uintParam(name string, max uint64) (uint64, error) {
+	value, err := strconv.ParseUint(p.params[name], 10, 64)
+	if err != nil || value == 0 || value > max {
+		return 0, fmt.Errorf("%w: bad parameter %s", ErrInvalidHash, name)
+	}
+	return value, nil
+}
+
+func randomSalt(size int) ([]byte, error) {
+	salt := make([]byte, size)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
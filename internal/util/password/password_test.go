@@ -0,0 +1,113 @@
+package password
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Дешевые параметры, чтобы тесты не тратили время на KDF
+var (
+	testArgon2Params = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	testScryptParams = ScryptParams{LogN: 4, R: 8, P: 1, SaltLength: 16, KeyLength: 32}
+)
+
+func TestHashers_RoundTrip(t *testing.T) {
+	hashers := []PasswordHasher{
+		NewBcrypt(4),
+		NewArgon2id(testArgon2Params),
+		NewScrypt(testScryptParams),
+	}
+
+	for _, hasher := range hashers {
+		t.Run(hasher.Algorithm(), func(t *testing.T) {
+			encoded, err := hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if algorithm, err := Identify(encoded); err != nil || algorithm != hasher.Algorithm() {
+				t.Fatalf("Identify(%q) = %q, %v", encoded, algorithm, err)
+			}
+
+			if ok, err := hasher.Verify("correct horse", encoded); err != nil || !ok {
+				t.Errorf("Verify(correct) = %v, %v", ok, err)
+			}
+			if ok, err := hasher.Verify("wrong horse", encoded); err != nil || ok {
+				t.Errorf("Verify(wrong) = %v, %v", ok, err)
+			}
+			if hasher.NeedsRehash(encoded) {
+				t.Error("fresh hash needs rehash")
+			}
+		})
+	}
+}
+
+func TestArgon2id_PHCFormat(t *testing.T) {
+	encoded, err := NewArgon2id(testArgon2Params).Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected encoding %q", encoded)
+	}
+	if parts := strings.Split(encoded, "$"); len(parts) != 6 {
+		t.Errorf("expected 6 PHC segments, got %d", len(parts))
+	}
+}
+
+// Тестовый вектор RFC 7914, раздел 12, в формате PHC
+func TestScrypt_RFC7914Vector(t *testing.T) {
+	key, _ := hex.DecodeString("fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640")
+	encoded := encodePHC(AlgorithmScrypt, 0, "ln=10,r=8,p=16", []byte("NaCl"), key)
+
+	ok, err := NewScrypt(testScryptParams).Verify("password", encoded)
+	if err != nil || !ok {
+		t.Fatalf("Verify = %v, %v", ok, err)
+	}
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	legacyBcrypt, _ := NewBcrypt(4).Hash("secret")
+	weakArgon2, _ := NewArgon2id(Argon2Params{Memory: 512, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}).Hash("secret")
+	current, _ := NewArgon2id(testArgon2Params).Hash("secret")
+
+	hasher := NewHasher(NewArgon2id(testArgon2Params), NewBcrypt(4), NewScrypt(testScryptParams))
+
+	cases := []struct {
+		name    string
+		encoded string
+		want    bool
+	}{
+		{"other algorithm", legacyBcrypt, true},
+		{"outdated parameters", weakArgon2, true},
+		{"current", current, false},
+		{"garbage", "not-a-hash", true},
+	}
+	for _, tc := range cases {
+		if got := hasher.NeedsRehash(tc.encoded); got != tc.want {
+			t.Errorf("%s: NeedsRehash = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// Старые хеши по-прежнему проверяются
+	if ok, err := hasher.Verify("secret", legacyBcrypt); err != nil || !ok {
+		t.Errorf("Verify(legacy bcrypt) = %v, %v", ok, err)
+	}
+}
+
+func TestHasher_RejectsMalformedHashes(t *testing.T) {
+	hasher := NewHasher(NewArgon2id(testArgon2Params))
+
+	for _, encoded := range []string{
+		"",
+		"plain",
+		"$md5$abc",
+		"$argon2id$v=19$m=1024,t=1,p=1$salt",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+		"$argon2id$v=19$m=99999999,t=1,p=1$c2FsdHNhbHQ$aGFzaA",
+	} {
+		if ok, err := hasher.Verify("secret", encoded); err == nil || ok {
+			t.Errorf("Verify(%q) = %v, %v; want error", encoded, ok, err)
+		}
+	}
+}
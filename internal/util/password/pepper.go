@@ -0,0 +1,118 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pepperPrefix - префикс хеша с перцем: $pepper$v=<версия><хеш внутреннего алгоритма>
+const pepperPrefix = "$pepper$v="
+
+// MinPepperSize - минимальная длина перца в байтах
+const MinPepperSize = 16
+
+var ErrUnknownPepper = errors.New("unknown pepper version")
+
+// Peppers - секретные ключи HMAC по версиям. Перец хранится вне БД (конфиг/secret файлы),
+// поэтому одного дампа t_users недостаточно для подбора паролей.
+type Peppers struct {
+	Current int            // версия для новых хешей
+	Keys    map[int][]byte // все версии, которые еще могут встретиться в БД
+}
+
+// pepperedHasher перед хешированием заменяет пароль на HMAC-SHA256(перец, пароль)
+type pepperedHasher struct {
+	inner   PasswordHasher
+	peppers Peppers
+}
+
+// NewPepperedHasher оборачивает inner перцем. Хеши без перца и со старой версией перца
+// проверяются, но считаются устаревшими (NeedsRehash) и перехешируются при входе.
+func NewPepperedHasher(inner PasswordHasher, peppers Peppers) (PasswordHasher, error) {
+	if _, ok := peppers.Keys[peppers.Current]; !ok {
+		return nil, fmt.Errorf("%w: current version %d is not loaded", ErrUnknownPepper, peppers.Current)
+	}
+	for version, key := range peppers.Keys {
+		if version <= 0 {
+			return nil, fmt.Errorf("pepper version must be positive, got %d", version)
+		}
+		if len(key) < MinPepperSize {
+			return nil, fmt.Errorf("pepper version %d must be at least %d bytes", version, MinPepperSize)
+		}
+	}
+
+	return &pepperedHasher{inner: inner, peppers: peppers}, nil
+}
+
+func (h *pepperedHasher) Algorithm() string {
+	return h.inner.Algorithm()
+}
+
+func (h *pepperedHasher) Hash(password string) (string, error) {
+	if password == "" {
+		return "", ErrEmptyPassword
+	}
+
+	encoded, err := h.inner.Hash(h.pepper(h.peppers.Keys[h.peppers.Current], password))
+	if err != nil {
+		return "", err
+	}
+
+	return pepperPrefix + strconv.Itoa(h.peppers.Current) + encoded, nil
+}
+
+func (h *pepperedHasher) Verify(password, encoded string) (bool, error) {
+	version, inner, peppered, err := splitPepper(encoded)
+	if err != nil {
+		return false, err
+	}
+	if !peppered {
+		return h.inner.Verify(password, encoded)
+	}
+
+	key, ok := h.peppers.Keys[version]
+	if !ok {
+		return false, fmt.Errorf("%w: %d", ErrUnknownPepper, version)
+	}
+
+	return h.inner.Verify(h.pepper(key, password), inner)
+}
+
+func (h *pepperedHasher) NeedsRehash(encoded string) bool {
+	version, inner, peppered, err := splitPepper(encoded)
+	if err != nil || !peppered || version != h.peppers.Current {
+		return true
+	}
+	return h.inner.NeedsRehash(inner)
+}
+
+// pepper - HMAC-SHA256 в base64: 43 символа, что помещается и в лимит bcrypt (72 байта)
+func (h *pepperedHasher) pepper(key []byte, password string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// splitPepper отделяет версию перца от хеша внутреннего алгоритма
+func splitPepper(encoded string) (version int, inner string, peppered bool, err error) {
+	rest, ok := strings.CutPrefix(encoded, pepperPrefix)
+	if !ok {
+		return 0, encoded, false, nil
+	}
+
+	end := strings.IndexByte(rest, '$')
+	if end <= 0 {
+		return 0, "", false, ErrInvalidHash
+	}
+	version, err = strconv.Atoi(rest[:end])
+	if err != nil {
+		return 0, "", false, ErrInvalidHash
+	}
+
+	return version, rest[end:], true, nil
+}
@@ -0,0 +1,79 @@
+package password
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPepperedHasher(t *testing.T) {
+	inner := NewHasher(NewArgon2id(testArgon2Params), NewBcrypt(4))
+	v1 := bytes.Repeat([]byte{1}, 32)
+	v2 := bytes.Repeat([]byte{2}, 32)
+
+	old, err := NewPepperedHasher(inner, Peppers{Current: 1, Keys: map[int][]byte{1: v1}})
+	if err != nil {
+		t.Fatalf("NewPepperedHasher: %v", err)
+	}
+	rotated, err := NewPepperedHasher(inner, Peppers{Current: 2, Keys: map[int][]byte{1: v1, 2: v2}})
+	if err != nil {
+		t.Fatalf("NewPepperedHasher: %v", err)
+	}
+
+	encoded, err := old.Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "$pepper$v=1$argon2id$") {
+		t.Fatalf("unexpected encoding %q", encoded)
+	}
+
+	// Без перца хеш бесполезен
+	_, innerHash, _, _ := splitPepper(encoded)
+	if ok, _ := inner.Verify("secret", innerHash); ok {
+		t.Error("peppered hash verified without pepper")
+	}
+
+	if ok, err := rotated.Verify("secret", encoded); err != nil || !ok {
+		t.Errorf("Verify with old pepper version = %v, %v", ok, err)
+	}
+	if ok, err := rotated.Verify("wrong", encoded); err != nil || ok {
+		t.Errorf("Verify(wrong) = %v, %v", ok, err)
+	}
+	if !rotated.NeedsRehash(encoded) {
+		t.Error("hash with old pepper version must be rehashed")
+	}
+	if old.NeedsRehash(encoded) {
+		t.Error("hash with current pepper must not be rehashed")
+	}
+
+	// Хеши, сделанные до включения перца
+	legacy, _ := NewBcrypt(4).Hash("secret")
+	if ok, err := rotated.Verify("secret", legacy); err != nil || !ok {
+		t.Errorf("Verify(unpeppered) = %v, %v", ok, err)
+	}
+	if !rotated.NeedsRehash(legacy) {
+		t.Error("unpeppered hash must be rehashed")
+	}
+
+	// Версия перца удалена из конфигурации
+	withoutV1, _ := NewPepperedHasher(inner, Peppers{Current: 2, Keys: map[int][]byte{2: v2}})
+	if _, err := withoutV1.Verify("secret", encoded); !errors.Is(err, ErrUnknownPepper) {
+		t.Errorf("Verify with missing pepper: err = %v, want ErrUnknownPepper", err)
+	}
+}
+
+func TestNewPepperedHasher_RejectsBadConfig(t *testing.T) {
+	inner := NewBcrypt(4)
+
+	for _, peppers := range []Peppers{
+		{Current: 1},
+		{Current: 1, Keys: map[int][]byte{1: []byte("short")}},
+		{Current: 0, Keys: map[int][]byte{0: bytes.Repeat([]byte{1}, 32)}},
+	} {
+		if _, err := NewPepperedHasher(inner, peppers); err == nil {
+			t.Errorf("NewPepperedHasher(%+v) succeeded", peppers)
+		}
+	}
+}
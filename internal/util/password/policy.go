@@ -0,0 +1,162 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Коды нарушений политики паролей (FieldViolation.Reason)
+const (
+	ViolationTooShort      = "PASSWORD_TOO_SHORT"
+	ViolationTooLong       = "PASSWORD_TOO_LONG"
+	ViolationMissingUpper  = "PASSWORD_MISSING_UPPERCASE"
+	ViolationMissingLower  = "PASSWORD_MISSING_LOWERCASE"
+	ViolationMissingDigit  = "PASSWORD_MISSING_DIGIT"
+	ViolationMissingSymbol = "PASSWORD_MISSING_SYMBOL"
+	ViolationBannedWord    = "PASSWORD_CONTAINS_BANNED_WORD"
+	ViolationContainsUser  = "PASSWORD_CONTAINS_USERNAME"
+	ViolationContainsEmail = "PASSWORD_CONTAINS_EMAIL"
+	ViolationBreached      = "PASSWORD_BREACHED"
+	ViolationReused        = "PASSWORD_RECENTLY_USED"
+)
+
+// passwordField - поле запроса, к которому относятся нарушения
+const passwordField = "password"
+
+// minPersonalWordLength - более короткие имя и email не проверяем, иначе ложные срабатывания
+const minPersonalWordLength = 3
+
+// PolicyConfig - требования к паролю
+type PolicyConfig struct {
+	MinLength     int // в символах (rune)
+	MaxLength     int // 0 - без ограничения
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	BannedWords   []string // без учета регистра
+	HistorySize   int      // сколько прежних паролей нельзя повторять (кроме текущего); 0 - только текущий
+}
+
+// Violation - одно нарушение политики, привязанное к полю запроса
+type Violation struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+// Subject - данные пользователя, которые не должны входить в пароль
+type Subject struct {
+	UserName string
+	Email    string
+}
+
+// Policy проверяет пароль до хеширования
+type Policy struct {
+	config   PolicyConfig
+	breached BreachedChecker
+}
+
+// NewPolicy создает политику паролей. breached может быть nil - проверка утечек выключена.
+func NewPolicy(config PolicyConfig, breached BreachedChecker) *Policy {
+	banned := make([]string, 0, len(config.BannedWords))
+	for _, word := range config.BannedWords {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			banned = append(banned, word)
+		}
+	}
+	config.BannedWords = banned
+
+	return &Policy{config: config, breached: breached}
+}
+
+// Validate возвращает все нарушения сразу, чтобы клиент мог показать их вместе.
+// Ошибка - только если не удалось проверить пароль по базе утечек.
+func (p *Policy) Validate(password string, subject Subject) ([]Violation, error) {
+	var violations []Violation
+	add := func(code, format string, args ...any) {
+		violations = append(violations, Violation{Field: passwordField, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.MinLength {
+		add(ViolationTooShort, "password must be at least %d characters", p.config.MinLength)
+	}
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		add(ViolationTooLong, "password must be at most %d characters", p.config.MaxLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if p.config.RequireUpper && !hasUpper {
+		add(ViolationMissingUpper, "password must contain an uppercase letter")
+	}
+	if p.config.RequireLower && !hasLower {
+		add(ViolationMissingLower, "password must contain a lowercase letter")
+	}
+	if p.config.RequireDigit && !hasDigit {
+		add(ViolationMissingDigit, "password must contain a digit")
+	}
+	if p.config.RequireSymbol && !hasSymbol {
+		add(ViolationMissingSymbol, "password must contain a symbol")
+	}
+
+	lower := strings.ToLower(password)
+	for _, word := range p.config.BannedWords {
+		if strings.Contains(lower, word) {
+			add(ViolationBannedWord, "password must not contain common words")
+			break
+		}
+	}
+	if name := strings.ToLower(subject.UserName); len(name) >= minPersonalWordLength && strings.Contains(lower, name) {
+		add(ViolationContainsUser, "password must not contain the username")
+	}
+	if local, _, _ := strings.Cut(strings.ToLower(subject.Email), "@"); len(local) >= minPersonalWordLength && strings.Contains(lower, local) {
+		add(ViolationContainsEmail, "password must not contain the email address")
+	}
+
+	if p.breached != nil && password != "" {
+		breached, err := p.breached.IsBreached(password)
+		if err != nil {
+			return nil, fmt.Errorf("check breached passwords: %w", err)
+		}
+		if breached {
+			add(ViolationBreached, "password has appeared in a data breach")
+		}
+	}
+
+	return violations, nil
+}
+
+// HistorySize - сколько прежних хешей нужно передать в CheckHistory
+func (p *Policy) HistorySize() int {
+	return p.config.HistorySize
+}
+
+// CheckHistory возвращает нарушение, если пароль подходит к одному из хешей previous
+// (текущий и последние HistorySize паролей). Хеши неизвестных алгоритмов пропускаются.
+func (p *Policy) CheckHistory(hasher PasswordHasher, password string, previous []string) []Violation {
+	for _, encoded := range previous {
+		if ok, err := hasher.Verify(password, encoded); err == nil && ok {
+			return []Violation{{
+				Field:   passwordField,
+				Code:    ViolationReused,
+				Message: fmt.Sprintf("password must differ from the last %d passwords", p.config.HistorySize+1),
+			}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeBreachedFile(t *testing.T, passwords []string) string {
+	t.Helper()
+
+	lines := make([]string, 0, len(passwords))
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600); err != nil {
+		t.Fatalf("write corpus: %v", err)
+	}
+	return path
+}
+
+func TestBreachedFile_IsBreached(t *testing.T) {
+	var breached []string
+	for i := 0; i < 1000; i++ {
+		breached = append(breached, fmt.Sprintf("leaked-%d", i))
+	}
+	corpus, err := OpenBreachedFile(writeBreachedFile(t, breached))
+	if err != nil {
+		t.Fatalf("OpenBreachedFile: %v", err)
+	}
+	defer corpus.Close()
+
+	for _, password := range breached {
+		if ok, err := corpus.IsBreached(password); err != nil || !ok {
+			t.Fatalf("IsBreached(%q) = %v, %v; want true", password, ok, err)
+		}
+	}
+	for _, password := range []string{"", "leaked-1000", "not leaked", "zzzz"} {
+		if ok, err := corpus.IsBreached(password); err != nil || ok {
+			t.Errorf("IsBreached(%q) = %v, %v; want false", password, ok, err)
+		}
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	corpus, err := OpenBreachedFile(writeBreachedFile(t, []string{"Password1!"}))
+	if err != nil {
+		t.Fatalf("OpenBreachedFile: %v", err)
+	}
+	defer corpus.Close()
+
+	policy := NewPolicy(PolicyConfig{
+		MinLength:     10,
+		MaxLength:     64,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		BannedWords:   []string{"Qwerty"},
+	}, corpus)
+	subject := Subject{UserName: "alice", Email: "alice.smith@example.com"}
+
+	cases := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"strong", "Tr0ub4dor&3-horse", nil},
+		{"short and plain", "abc", []string{ViolationTooShort, ViolationMissingUpper, ViolationMissingDigit, ViolationMissingSymbol}},
+		{"too long", strings.Repeat("Aa1!", 17), []string{ViolationTooLong}},
+		{"banned word", "MyQWERTY-pass9", []string{ViolationBannedWord}},
+		{"username", "Alice-Rocks-42", []string{ViolationContainsUser}},
+		{"email local part", "x-Alice.Smith-7", []string{ViolationContainsUser, ViolationContainsEmail}},
+		{"breached", "Password1!", []string{ViolationBreached}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			violations, err := policy.Validate(tc.password, subject)
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+
+			var codes []string
+			for _, violation := range violations {
+				if violation.Field != "password" || violation.Message == "" {
+					t.Errorf("incomplete violation %+v", violation)
+				}
+				codes = append(codes, violation.Code)
+			}
+			if !slices.Equal(codes, tc.want) {
+				t.Errorf("codes = %v, want %v", codes, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package password
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ScryptParams - параметры scrypt (RFC 7914). N = 2^LogN.
+type ScryptParams struct {
+	LogN       uint8
+	R          int
+	P          int
+	SaltLength int
+	KeyLength  int
+}
+
+// DefaultScryptParams - рекомендация OWASP: N=2^17, r=8, p=1
+var DefaultScryptParams = ScryptParams{
+	LogN:       17,
+	R:          8,
+	P:          1,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+// Ограничения на параметры из хеша: 128 * N * r байт памяти
+const (
+	maxScryptLogN = 22
+	maxScryptR    = 32
+	maxScryptP    = 16
+)
+
+type scryptHasher struct {
+	params ScryptParams
+}
+
+// NewScrypt создает scrypt хешер
+func NewScrypt(params ScryptParams) PasswordHasher {
+	return &scryptHasher{params: params}
+}
+
+func (h *scryptHasher) Algorithm() string {
+	return AlgorithmScrypt
+}
+
+func (h *scryptHasher) Hash(password string) (string, error) {
+	if password == "" {
+		return "", ErrEmptyPassword
+	}
+
+	salt, err := randomSalt(h.params.SaltLength)
+	if err != nil {
+		return "", err
+	}
+
+	p := h.params
+	key, err := scrypt.Key([]byte(password), salt, 1<<p.LogN, p.R, p.P, p.KeyLength)
+	if err != nil {
+		return "", err
+	}
+	params := fmt.Sprintf("ln=%d,r=%d,p=%d", p.LogN, p.R, p.P)
+
+	return encodePHC(AlgorithmScrypt, 0, params, salt, key), nil
+}
+
+func (h *scryptHasher) Verify(password, encoded string) (bool, error) {
+	p, params, err := decodeScrypt(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := scrypt.Key([]byte(password), p.salt, 1<<params.LogN, params.R, params.P, params.KeyLength)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+	return subtle.ConstantTimeCompare(key, p.hash) == 1, nil
+}
+
+func (h *scryptHasher) NeedsRehash(encoded string) bool {
+	p, params, err := decodeScrypt(encoded)
+	if err != nil {
+		return true
+	}
+
+	return params.LogN != h.params.LogN ||
+		params.R != h.params.R ||
+		params.P != h.params.P ||
+		params.KeyLength != h.params.KeyLength ||
+		len(p.salt) < h.params.SaltLength
+}
+
+func decodeScrypt(encoded string) (*phcHash, ScryptParams, error) {
+	p, err := parsePHC(encoded, AlgorithmScrypt)
+	if err != nil {
+		return nil, ScryptParams{}, err
+	}
+
+	logN, err := p.uintParam("ln", maxScryptLogN)
+	if err != nil {
+		return nil, ScryptParams{}, err
+	}
+	r, err := p.uintParam("r", maxScryptR)
+	if err != nil {
+		return nil, ScryptParams{}, err
+	}
+	parallelism, err := p.uintParam("p", maxScryptP)
+	if err != nil {
+		return nil, ScryptParams{}, err
+	}
+
+	return p, ScryptParams{
+		LogN:       uint8(logN),
+		R:          int(r),
+		P:          int(parallelism),
+		SaltLength: len(p.salt),
+		KeyLength:  len(p.hash),
+	}, nil
+}
@@ -0,0 +1,99 @@
+// Package secretbox шифрует секреты для хранения в БД (AES-256-GCM).
+// Ключи версионируются: шифротекст начинается с v<версия>$, поэтому после ротации
+// старые ключи остаются для расшифровки, а новые данные шифруются текущим.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KeySize - длина ключа AES-256
+const KeySize = 32
+
+var (
+	ErrInvalidKey        = errors.New("secretbox key must be 32 bytes")
+	ErrUnknownKey        = errors.New("secretbox key version is not configured")
+	ErrInvalidCiphertext = errors.New("invalid secretbox ciphertext")
+)
+
+// Keys - ключи по версиям; Current шифрует новые данные
+type Keys struct {
+	Current int
+	Keys    map[int][]byte
+}
+
+// Box шифрует и расшифровывает секреты
+type Box struct {
+	current int
+	aeads   map[int]cipher.AEAD
+}
+
+func New(keys Keys) (*Box, error) {
+	if _, ok := keys.Keys[keys.Current]; !ok {
+		return nil, fmt.Errorf("%w: version %d", ErrUnknownKey, keys.Current)
+	}
+
+	box := &Box{current: keys.Current, aeads: make(map[int]cipher.AEAD, len(keys.Keys))}
+	for version, key := range keys.Keys {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("%w: version %d", ErrInvalidKey, version)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		box.aeads[version] = aead
+	}
+	return box, nil
+}
+
+// Seal шифрует plaintext текущим ключом. aad (например, id владельца) не шифруется,
+// но без него расшифровать нельзя - шифротекст не перенести в чужую запись.
+func (b *Box) Seal(plaintext, aad []byte) (string, error) {
+	aead := b.aeads[b.current]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, aad)
+
+	return "v" + strconv.Itoa(b.current) + "$" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open расшифровывает результат Seal любой настроенной версией ключа
+func (b *Box) Open(ciphertext string, aad []byte) ([]byte, error) {
+	rawVersion, encoded, ok := strings.Cut(ciphertext, "$")
+	if !ok || !strings.HasPrefix(rawVersion, "v") {
+		return nil, ErrInvalidCiphertext
+	}
+	version, err := strconv.Atoi(rawVersion[1:])
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	aead, ok := b.aeads[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: version %d", ErrUnknownKey, version)
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,41 @@
+package secretbox
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestBox_SealOpen(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, KeySize)
+	newKey := bytes.Repeat([]byte{2}, KeySize)
+
+	old, err := New(Keys{Current: 1, Keys: map[int][]byte{1: oldKey}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	sealed, err := old.Seal([]byte("secret"), []byte("user-1"))
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	// После ротации старый шифротекст по-прежнему читается
+	rotated, err := New(Keys{Current: 2, Keys: map[int][]byte{1: oldKey, 2: newKey}})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	plaintext, err := rotated.Open(sealed, []byte("user-1"))
+	if err != nil || string(plaintext) != "secret" {
+		t.Fatalf("Open = %q, %v", plaintext, err)
+	}
+	if resealed, _ := rotated.Seal([]byte("secret"), nil); resealed[:3] != "v2$" {
+		t.Errorf("new ciphertext = %q, want current key version v2", resealed)
+	}
+
+	if _, err := rotated.Open(sealed, []byte("user-2")); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("Open with other aad: err = %v, want ErrInvalidCiphertext", err)
+	}
+	if _, err := New(Keys{Current: 1, Keys: map[int][]byte{1: []byte("short")}}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("short key: err = %v, want ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package totp реализует одноразовые пароли по времени (RFC 6238) поверх HOTP (RFC 4226).
+// Параметры совместимы с Google Authenticator и аналогами: HMAC-SHA1, 6 цифр, шаг 30 секунд.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры по умолчанию
+const (
+	DefaultDigits = 6
+	DefaultPeriod = 30 * time.Second
+	SecretSize    = 20 // 160 бит, рекомендация RFC 4226
+)
+
+var ErrInvalidSecret = errors.New("invalid TOTP secret")
+
+// encoding - base32 без паддинга, как в otpauth URI
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Config - параметры генерации кодов
+type Config struct {
+	Digits int
+	Period time.Duration
+	Skew   int // сколько соседних шагов принимается с каждой стороны (рассинхронизация часов)
+}
+
+// DefaultConfig - 6 цифр, 30 секунд, допускается один шаг в каждую сторону
+var DefaultConfig = Config{Digits: DefaultDigits, Period: DefaultPeriod, Skew: 1}
+
+// GenerateSecret возвращает случайный секрет в base32
+func GenerateSecret() (string, error) {
+	secret := make([]byte, SecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generate totp secret: %w", err)
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// Step - номер временного шага для t
+func (c Config) Step(t time.Time) int64 {
+	return t.Unix() / int64(c.Period/time.Second)
+}
+
+// Code возвращает код для шага step
+func (c Config) Code(secret string, step int64) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, uint64(step), c.Digits), nil
+}
+
+// Validate проверяет код на момент t с учетом Skew и возвращает совпавший шаг.
+// Шаг нужно сохранить и не принимать коды с шагом не больше него (защита от повтора).
+func (c Config) Validate(secret, code string, t time.Time) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil || len(code) != c.Digits {
+		return 0, false
+	}
+
+	current := c.Step(t)
+	for delta := -int64(c.Skew); delta <= int64(c.Skew); delta++ {
+		step := current + delta
+		if step < 0 {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(key, uint64(step), c.Digits)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// URI - otpauth:// ссылка для QR кода приложения-аутентификатора
+func (c Config) URI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(c.Digits))
+	query.Set("period", fmt.Sprint(int64(c.Period/time.Second)))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// hotp - RFC 4226, раздел 5.3
+func hotp(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
@@ -0,0 +1,68 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Тестовые векторы RFC 6238, приложение B (SHA1, 8 цифр)
+func TestCode_RFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	config := Config{Digits: 8, Period: 30 * time.Second}
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := config.Code(secret, config.Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		if code != tt.code {
+			t.Errorf("Code(T=%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestValidate_Skew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	config := DefaultConfig
+	now := time.Unix(1700000000, 0)
+
+	previous, _ := config.Code(secret, config.Step(now)-1)
+	if step, ok := config.Validate(secret, previous, now); !ok || step != config.Step(now)-1 {
+		t.Errorf("previous step code: step = %d, ok = %v", step, ok)
+	}
+
+	stale, _ := config.Code(secret, config.Step(now)-2)
+	if _, ok := config.Validate(secret, stale, now); ok {
+		t.Error("code outside of skew window accepted")
+	}
+
+	if _, ok := config.Validate(secret, "12345", now); ok {
+		t.Error("code of wrong length accepted")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := DefaultConfig.URI("Auth Service", "alice@example.com", "JBSWY3DPEHPK3PXP")
+	for _, want := range []string{"otpauth://totp/Auth%20Service:alice@example.com?", "secret=JBSWY3DPEHPK3PXP", "issuer=Auth+Service", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("URI %q does not contain %q", uri, want)
+		}
+	}
+}
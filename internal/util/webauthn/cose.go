@@ -0,0 +1,137 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Алгоритмы COSE (RFC 9053), которые принимает сервер
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+// SupportedAlgorithms - в порядке предпочтения для pubKeyCredParams
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// Параметры ключа COSE
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1 // EC2/OKP: crv; RSA: n
+	coseX         = -2 // EC2/OKP: x; RSA: e
+	coseY         = -3
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+// minRSABits - более короткие RSA ключи не принимаются
+const minRSABits = 2048
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported credential public key")
+	ErrBadSignature   = errors.New("signature verification failed")
+)
+
+// PublicKey - открытый ключ учетных данных, разобранный из COSE
+type PublicKey struct {
+	Algorithm int64
+	key       crypto.PublicKey
+}
+
+// ParsePublicKey разбирает ключ в формате COSE_Key
+func ParsePublicKey(cose []byte) (*PublicKey, error) {
+	decoded, rest, err := decodeCBOR(cose)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data after key", ErrUnsupportedKey)
+	}
+	return publicKeyFromCOSE(decoded)
+}
+
+func publicKeyFromCOSE(decoded any) (*PublicKey, error) {
+	params, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: key is not a map", ErrUnsupportedKey)
+	}
+
+	keyType, _ := params[int64(coseKeyType)].(int64)
+	alg, _ := params[int64(coseAlgorithm)].(int64)
+	curve, _ := params[int64(coseCurve)].(int64)
+	x, _ := params[int64(coseX)].([]byte)
+	y, _ := params[int64(coseY)].([]byte)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && alg == AlgES256 && curve == coseCurveP256:
+		if len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: invalid P-256 coordinates", ErrUnsupportedKey)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: point is not on curve", ErrUnsupportedKey)
+		}
+		return &PublicKey{Algorithm: alg, key: key}, nil
+
+	case keyType == coseKeyTypeOKP && alg == AlgEdDSA && curve == coseCurveEd25519:
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 key", ErrUnsupportedKey)
+		}
+		return &PublicKey{Algorithm: alg, key: ed25519.PublicKey(x)}, nil
+
+	case keyType == coseKeyTypeRSA && alg == AlgRS256:
+		n, _ := params[int64(coseCurve)].([]byte)
+		e, _ := params[int64(coseX)].([]byte)
+		if len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: invalid RSA exponent", ErrUnsupportedKey)
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("%w: RSA key is shorter than %d bits", ErrUnsupportedKey, minRSABits)
+		}
+		return &PublicKey{Algorithm: alg, key: key}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: kty %d, alg %d", ErrUnsupportedKey, keyType, alg)
+	}
+}
+
+// Verify проверяет подпись message (authenticatorData || SHA-256(clientDataJSON))
+func (k *PublicKey) Verify(message, signature []byte) error {
+	return verifySignature(k.Algorithm, k.key, message, signature)
+}
+
+func verifySignature(alg int64, key crypto.PublicKey, message, signature []byte) error {
+	var ok bool
+	switch alg {
+	case AlgES256:
+		ecKey, isEC := key.(*ecdsa.PublicKey)
+		digest := sha256.Sum256(message)
+		ok = isEC && ecdsa.VerifyASN1(ecKey, digest[:], signature)
+	case AlgEdDSA:
+		edKey, isEd := key.(ed25519.PublicKey)
+		ok = isEd && ed25519.Verify(edKey, message, signature)
+	case AlgRS256:
+		rsaKey, isRSA := key.(*rsa.PublicKey)
+		digest := sha256.Sum256(message)
+		ok = isRSA && rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) == nil
+	}
+	if !ok {
+		return ErrBadSignature
+	}
+	return nil
+}
@@ -0,0 +1,452 @@
+// Package webauthn - серверная часть WebAuthn Level 2 (passkeys, ключи безопасности):
+// параметры для navigator.credentials.create/get и проверка ответов аутентификатора.
+// Аттестация не проверяется по цепочке доверия (conveyance "none"): принимаются форматы
+// none и packed, подпись packed проверяется, но производитель аутентификатора не важен.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Требование проверки пользователя (PIN, биометрия)
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+// ChallengeSize - 256 бит, спецификация требует не меньше 16 байт
+const ChallengeSize = 32
+
+// Флаги authenticatorData
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+	flagExtensions   = 0x80
+)
+
+var (
+	ErrInvalidResponse        = errors.New("invalid webauthn response")
+	ErrChallengeMismatch      = errors.New("webauthn challenge mismatch")
+	ErrOriginMismatch         = errors.New("webauthn origin is not allowed")
+	ErrRPIDMismatch           = errors.New("webauthn rp id mismatch")
+	ErrUserNotPresent         = errors.New("user presence flag is not set")
+	ErrUserNotVerified        = errors.New("user verification is required")
+	ErrUnsupportedAttestation = errors.New("unsupported attestation format")
+	ErrSignCountRegression    = errors.New("authenticator signature counter did not increase, credential may be cloned")
+)
+
+// Config - параметры Relying Party
+type Config struct {
+	RPID             string   // домен, к которому привязаны учетные данные
+	RPName           string   // отображается пользователю
+	Origins          []string // разрешенные origin страниц, например https://example.com
+	Timeout          time.Duration
+	UserVerification string // UserVerification*
+}
+
+// RelyingParty строит параметры церемоний и проверяет ответы
+type RelyingParty struct {
+	config   Config
+	rpIDHash [32]byte
+}
+
+func New(config Config) (*RelyingParty, error) {
+	if config.RPID == "" || len(config.Origins) == 0 {
+		return nil, errors.New("webauthn rp id and origins are required")
+	}
+	switch config.UserVerification {
+	case UserVerificationRequired, UserVerificationPreferred, UserVerificationDiscouraged:
+	default:
+		return nil, fmt.Errorf("unknown webauthn user verification %q", config.UserVerification)
+	}
+	if config.RPName == "" {
+		config.RPName = config.RPID
+	}
+
+	return &RelyingParty{config: config, rpIDHash: sha256.Sum256([]byte(config.RPID))}, nil
+}
+
+// NewChallenge генерирует случайный challenge церемонии
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// User - владелец учетных данных; ID - user handle, не должен содержать персональных данных
+type User struct {
+	ID          []byte
+	Name        string
+	DisplayName string
+}
+
+// Credential - зарегистрированные учетные данные
+type Credential struct {
+	ID         []byte
+	PublicKey  []byte // COSE_Key
+	Algorithm  int64
+	SignCount  uint32
+	AAGUID     []byte
+	Transports []string
+}
+
+// AssertionResponse - разобранный ответ navigator.credentials.get
+type AssertionResponse struct {
+	CredentialID      []byte
+	UserHandle        []byte // пусто, если аутентификатор его не вернул
+	clientDataJSON    []byte
+	authenticatorData []byte
+	signature         []byte
+}
+
+// URLEncodedBytes - байты в base64url, как в JSON сериализации WebAuthn
+type URLEncodedBytes []byte
+
+func (b URLEncodedBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncodedBytes) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+type credentialDescriptor struct {
+	Type       string          `json:"type"`
+	ID         URLEncodedBytes `json:"id"`
+	Transports []string        `json:"transports,omitempty"`
+}
+
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CreationOptions - PublicKeyCredentialCreationOptionsJSON для navigator.credentials.create.
+// exclude - уже зарегистрированные учетные данные пользователя, чтобы не завести дубликат.
+func (rp *RelyingParty) CreationOptions(user User, challenge []byte, exclude []Credential) ([]byte, error) {
+	params := make([]credentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, credentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	return json.Marshal(map[string]any{
+		"rp": map[string]string{"id": rp.config.RPID, "name": rp.config.RPName},
+		"user": map[string]any{
+			"id":          URLEncodedBytes(user.ID),
+			"name":        user.Name,
+			"displayName": user.DisplayName,
+		},
+		"challenge":          URLEncodedBytes(challenge),
+		"pubKeyCredParams":   params,
+		"timeout":            rp.config.Timeout.Milliseconds(),
+		"excludeCredentials": descriptors(exclude),
+		"authenticatorSelection": map[string]string{
+			"residentKey":      "preferred",
+			"userVerification": rp.config.UserVerification,
+		},
+		"attestation": "none",
+	})
+}
+
+// RequestOptions - PublicKeyCredentialRequestOptionsJSON для navigator.credentials.get.
+// Пустой allow - вход по passkey без указания пользователя (discoverable credentials).
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow []Credential) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"challenge":        URLEncodedBytes(challenge),
+		"rpId":             rp.config.RPID,
+		"timeout":          rp.config.Timeout.Milliseconds(),
+		"allowCredentials": descriptors(allow),
+		"userVerification": rp.config.UserVerification,
+	})
+}
+
+func descriptors(credentials []Credential) []credentialDescriptor {
+	result := make([]credentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, credentialDescriptor{Type: "public-key", ID: credential.ID, Transports: credential.Transports})
+	}
+	return result
+}
+
+// registrationJSON - RegistrationResponseJSON (PublicKeyCredential.toJSON())
+type registrationJSON struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+		AttestationObject URLEncodedBytes `json:"attestationObject"`
+		Transports        []string        `json:"transports"`
+	} `json:"response"`
+}
+
+// assertionJSON - AuthenticationResponseJSON
+type assertionJSON struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+		AuthenticatorData URLEncodedBytes `json:"authenticatorData"`
+		Signature         URLEncodedBytes `json:"signature"`
+		UserHandle        URLEncodedBytes `json:"userHandle"`
+	} `json:"response"`
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// FinishRegistration проверяет ответ navigator.credentials.create на challenge
+// и возвращает учетные данные для сохранения (WebAuthn L2, 7.1)
+func (rp *RelyingParty) FinishRegistration(challenge, credentialJSON []byte) (*Credential, error) {
+	var resp registrationJSON
+	if err := json.Unmarshal(credentialJSON, &resp); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if resp.Type != "public-key" || len(resp.RawID) == 0 {
+		return nil, fmt.Errorf("%w: unexpected credential type", ErrInvalidResponse)
+	}
+
+	if err := rp.checkClientData(resp.Response.ClientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, rest, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: attestation object: %v", ErrInvalidResponse, err)
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: attestation object is not a map", ErrInvalidResponse)
+	}
+	format, _ := attestation["fmt"].(string)
+	rawAuthData, _ := attestation["authData"].([]byte)
+	statement, _ := attestation["attStmt"].(map[any]any)
+
+	authData, err := rp.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttested == 0 {
+		return nil, fmt.Errorf("%w: attested credential data is missing", ErrInvalidResponse)
+	}
+	if !bytes.Equal(authData.credentialID, resp.RawID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrInvalidResponse)
+	}
+
+	publicKey, err := ParsePublicKey(authData.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	if err := verifyAttestation(format, statement, publicKey, append(rawAuthData, clientDataHash[:]...)); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:         authData.credentialID,
+		PublicKey:  authData.publicKey,
+		Algorithm:  publicKey.Algorithm,
+		SignCount:  authData.signCount,
+		AAGUID:     authData.aaguid,
+		Transports: resp.Response.Transports,
+	}, nil
+}
+
+// ParseAssertion разбирает ответ navigator.credentials.get, чтобы найти учетные данные по CredentialID
+func ParseAssertion(credentialJSON []byte) (*AssertionResponse, error) {
+	var resp assertionJSON
+	if err := json.Unmarshal(credentialJSON, &resp); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if resp.Type != "public-key" || len(resp.RawID) == 0 {
+		return nil, fmt.Errorf("%w: unexpected credential type", ErrInvalidResponse)
+	}
+
+	return &AssertionResponse{
+		CredentialID:      resp.RawID,
+		UserHandle:        resp.Response.UserHandle,
+		clientDataJSON:    resp.Response.ClientDataJSON,
+		authenticatorData: resp.Response.AuthenticatorData,
+		signature:         resp.Response.Signature,
+	}, nil
+}
+
+// VerifyAssertion проверяет подпись ответа ключом сохраненных учетных данных (WebAuthn L2, 7.2)
+// и возвращает новое значение счетчика подписей. Если счетчик не вырос, возвращается
+// ErrSignCountRegression: вероятно, ключ скопирован.
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, resp *AssertionResponse, credential Credential) (uint32, error) {
+	if err := rp.checkClientData(resp.clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := rp.parseAuthenticatorData(resp.authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	publicKey, err := ParsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(resp.clientDataJSON)
+	message := append(append([]byte(nil), resp.authenticatorData...), clientDataHash[:]...)
+	if err := publicKey.Verify(message, resp.signature); err != nil {
+		return 0, err
+	}
+
+	// Аутентификаторы без счетчика всегда присылают 0
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, ErrSignCountRegression
+	}
+
+	return authData.signCount, nil
+}
+
+func (rp *RelyingParty) checkClientData(raw []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("%w: client data: %v", ErrInvalidResponse, err)
+	}
+	if data.Type != ceremony {
+		return fmt.Errorf("%w: client data type %q", ErrInvalidResponse, data.Type)
+	}
+
+	received, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+	if !slices.Contains(rp.config.Origins, data.Origin) || data.CrossOrigin {
+		return fmt.Errorf("%w: %q", ErrOriginMismatch, data.Origin)
+	}
+	return nil
+}
+
+func (rp *RelyingParty) parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, fmt.Errorf("%w: authenticator data is too short", ErrInvalidResponse)
+	}
+
+	data := &authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if subtle.ConstantTimeCompare(data.rpIDHash, rp.rpIDHash[:]) != 1 {
+		return nil, ErrRPIDMismatch
+	}
+	if data.flags&flagUserPresent == 0 {
+		return nil, ErrUserNotPresent
+	}
+	if rp.config.UserVerification == UserVerificationRequired && data.flags&flagUserVerified == 0 {
+		return nil, ErrUserNotVerified
+	}
+
+	rest := raw[37:]
+	if data.flags&flagAttested != 0 {
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: attested credential data is too short", ErrInvalidResponse)
+		}
+		data.aaguid = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return nil, fmt.Errorf("%w: credential id exceeds data", ErrInvalidResponse)
+		}
+		data.credentialID, rest = rest[:idLength], rest[idLength:]
+
+		_, afterKey, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential public key: %v", ErrInvalidResponse, err)
+		}
+		data.publicKey, rest = rest[:len(rest)-len(afterKey)], afterKey
+	}
+	if data.flags&flagExtensions != 0 {
+		_, afterExtensions, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extensions: %v", ErrInvalidResponse, err)
+		}
+		rest = afterExtensions
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing authenticator data", ErrInvalidResponse)
+	}
+
+	return data, nil
+}
+
+// verifyAttestation проверяет подпись аттестации; signed = authData || SHA-256(clientDataJSON)
+func verifyAttestation(format string, statement map[any]any, credentialKey *PublicKey, signed []byte) error {
+	switch format {
+	case "none":
+		if len(statement) != 0 {
+			return fmt.Errorf("%w: none attestation with statement", ErrInvalidResponse)
+		}
+		return nil
+
+	case "packed":
+		alg, _ := statement["alg"].(int64)
+		signature, _ := statement["sig"].([]byte)
+		chain, hasChain := statement["x5c"].([]any)
+
+		// Самоаттестация: подписано ключом самих учетных данных
+		if !hasChain {
+			if alg != credentialKey.Algorithm {
+				return fmt.Errorf("%w: self attestation algorithm mismatch", ErrInvalidResponse)
+			}
+			return credentialKey.Verify(signed, signature)
+		}
+
+		if len(chain) == 0 {
+			return fmt.Errorf("%w: empty x5c", ErrInvalidResponse)
+		}
+		der, _ := chain[0].([]byte)
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("%w: attestation certificate: %v", ErrInvalidResponse, err)
+		}
+		return verifySignature(alg, cert.PublicKey, signed, signature)
+
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedAttestation, format)
+	}
+}
@@ -0,0 +1,148 @@
+package webauthn_test
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"auth-service/internal/util/webauthn"
+	"auth-service/internal/util/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+func newRelyingParty(t *testing.T) *webauthn.RelyingParty {
+	t.Helper()
+	rp, err := webauthn.New(webauthn.Config{
+		RPID:             testRPID,
+		Origins:          []string{testOrigin},
+		Timeout:          time.Minute,
+		UserVerification: webauthn.UserVerificationRequired,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return rp
+}
+
+func challenge(t *testing.T) []byte {
+	t.Helper()
+	c := make([]byte, webauthn.ChallengeSize)
+	if _, err := rand.Read(c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+	c := challenge(t)
+	options, err := rp.CreationOptions(webauthn.User{ID: []byte("user-1"), Name: "a@example.com"}, c, nil)
+	if err != nil {
+		t.Fatalf("CreationOptions: %v", err)
+	}
+	response, err := authenticator.Register(options)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	credential, err := rp.FinishRegistration(c, response)
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	return credential
+}
+
+func login(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator, credential webauthn.Credential) (uint32, error) {
+	t.Helper()
+	c := challenge(t)
+	options, err := rp.RequestOptions(c, []webauthn.Credential{credential})
+	if err != nil {
+		t.Fatalf("RequestOptions: %v", err)
+	}
+	response, err := authenticator.Login(options)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	assertion, err := webauthn.ParseAssertion(response)
+	if err != nil {
+		t.Fatalf("ParseAssertion: %v", err)
+	}
+	if string(assertion.UserHandle) != "user-1" {
+		t.Errorf("UserHandle = %q, want user-1", assertion.UserHandle)
+	}
+	return rp.VerifyAssertion(c, assertion, credential)
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := newRelyingParty(t)
+	authenticator := webauthntest.New(testRPID, testOrigin)
+
+	credential := register(t, rp, authenticator)
+	if credential.Algorithm != webauthn.AlgES256 || len(credential.ID) == 0 {
+		t.Fatalf("unexpected credential: %+v", credential)
+	}
+
+	count, err := login(t, rp, authenticator, *credential)
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("sign count = %d, want 1", count)
+	}
+}
+
+func TestVerifyAssertion_SignCountRegression(t *testing.T) {
+	rp := newRelyingParty(t)
+	authenticator := webauthntest.New(testRPID, testOrigin)
+	credential := register(t, rp, authenticator)
+	credential.SignCount = 10
+
+	authenticator.SetSignCount(4)
+	if _, err := login(t, rp, authenticator, *credential); !errors.Is(err, webauthn.ErrSignCountRegression) {
+		t.Errorf("err = %v, want ErrSignCountRegression", err)
+	}
+}
+
+func TestFinishRegistration_Rejects(t *testing.T) {
+	rp := newRelyingParty(t)
+
+	tests := []struct {
+		name          string
+		authenticator *webauthntest.Authenticator
+		challenge     func(issued []byte) []byte
+		want          error
+	}{
+		{"wrong origin", webauthntest.New(testRPID, "https://evil.example"), nil, webauthn.ErrOriginMismatch},
+		{"wrong challenge", webauthntest.New(testRPID, testOrigin), func([]byte) []byte { return challenge(t) }, webauthn.ErrChallengeMismatch},
+		{"not verified", func() *webauthntest.Authenticator {
+			a := webauthntest.New(testRPID, testOrigin)
+			a.UserVerified = false
+			return a
+		}(), nil, webauthn.ErrUserNotVerified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issued := challenge(t)
+			options, err := rp.CreationOptions(webauthn.User{ID: []byte("user-1")}, issued, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := tt.authenticator.Register(options)
+			if err != nil {
+				t.Fatalf("Register: %v", err)
+			}
+			expected := issued
+			if tt.challenge != nil {
+				expected = tt.challenge(issued)
+			}
+			if _, err := rp.FinishRegistration(expected, response); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
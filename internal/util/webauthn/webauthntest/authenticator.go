@@ -0,0 +1,240 @@
+// Package webauthntest - программный аутентификатор WebAuthn для тестов:
+// отвечает на параметры create/get так же, как браузер с платформенным ключом ES256.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Authenticator хранит созданные учетные данные и их счетчики подписей
+type Authenticator struct {
+	RPID   string
+	Origin string
+	// UserVerified - выставлять ли флаг UV (PIN/биометрия подтверждены)
+	UserVerified bool
+
+	credentials map[string]*credential
+}
+
+type credential struct {
+	id         []byte
+	userHandle []byte
+	key        *ecdsa.PrivateKey
+	signCount  uint32
+}
+
+func New(rpID, origin string) *Authenticator {
+	return &Authenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		UserVerified: true,
+		credentials:  make(map[string]*credential),
+	}
+}
+
+type creationOptions struct {
+	RP struct {
+		ID string `json:"id"`
+	} `json:"rp"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Challenge string `json:"challenge"`
+}
+
+type requestOptions struct {
+	Challenge        string `json:"challenge"`
+	RPID             string `json:"rpId"`
+	AllowCredentials []struct {
+		ID string `json:"id"`
+	} `json:"allowCredentials"`
+}
+
+// Register создает учетные данные по CreationOptions и возвращает RegistrationResponseJSON
+// с аттестацией "none"
+func (a *Authenticator) Register(optionsJSON []byte) ([]byte, error) {
+	var options creationOptions
+	if err := json.Unmarshal(optionsJSON, &options); err != nil {
+		return nil, err
+	}
+	if options.RP.ID != a.RPID {
+		return nil, fmt.Errorf("rp id %q does not match authenticator %q", options.RP.ID, a.RPID)
+	}
+	userHandle, err := decode(options.User.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	cred := &credential{id: id, userHandle: userHandle, key: key}
+	a.credentials[string(id)] = cred
+
+	clientData := a.clientData("webauthn.create", options.Challenge)
+	authData := a.authenticatorData(0x40)
+	authData = binary.BigEndian.AppendUint16(append(authData, make([]byte, 16)...), uint16(len(id)))
+	authData = append(append(authData, id...), coseKey(&key.PublicKey)...)
+
+	attestation := encodeMap([][2][]byte{
+		{encodeText("fmt"), encodeText("none")},
+		{encodeText("attStmt"), encodeMap(nil)},
+		{encodeText("authData"), encodeBytes(authData)},
+	})
+
+	return json.Marshal(map[string]any{
+		"id":    encode(id),
+		"rawId": encode(id),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    encode(clientData),
+			"attestationObject": encode(attestation),
+			"transports":        []string{"internal"},
+		},
+	})
+}
+
+// Login подписывает challenge из RequestOptions и возвращает AuthenticationResponseJSON.
+// Если allowCredentials пуст, используются первые найденные учетные данные (discoverable).
+func (a *Authenticator) Login(optionsJSON []byte) ([]byte, error) {
+	var options requestOptions
+	if err := json.Unmarshal(optionsJSON, &options); err != nil {
+		return nil, err
+	}
+	if options.RPID != a.RPID {
+		return nil, fmt.Errorf("rp id %q does not match authenticator %q", options.RPID, a.RPID)
+	}
+
+	cred, err := a.pick(options)
+	if err != nil {
+		return nil, err
+	}
+	cred.signCount++
+
+	clientData := a.clientData("webauthn.get", options.Challenge)
+	authData := binary.BigEndian.AppendUint32(a.authenticatorData(0)[:33], cred.signCount)
+	digest := sha256.Sum256(clientData)
+	hash := sha256.Sum256(append(append([]byte(nil), authData...), digest[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, cred.key, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]any{
+		"id":    encode(cred.id),
+		"rawId": encode(cred.id),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode(cred.userHandle),
+		},
+	})
+}
+
+// SetSignCount выставляет счетчик всем учетным данным - так имитируется клон ключа
+func (a *Authenticator) SetSignCount(count uint32) {
+	for _, cred := range a.credentials {
+		cred.signCount = count
+	}
+}
+
+func (a *Authenticator) pick(options requestOptions) (*credential, error) {
+	if len(options.AllowCredentials) == 0 {
+		for _, cred := range a.credentials {
+			return cred, nil
+		}
+	}
+	for _, allowed := range options.AllowCredentials {
+		id, err := decode(allowed.ID)
+		if err != nil {
+			return nil, err
+		}
+		if cred, ok := a.credentials[string(id)]; ok {
+			return cred, nil
+		}
+	}
+	return nil, errors.New("no matching credential")
+}
+
+func (a *Authenticator) clientData(ceremony, challenge string) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   challenge,
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	return data
+}
+
+// authenticatorData - rpIdHash || flags || signCount(0)
+func (a *Authenticator) authenticatorData(extraFlags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags := byte(0x01) | extraFlags
+	if a.UserVerified {
+		flags |= 0x04
+	}
+	return append(append(rpIDHash[:], flags), 0, 0, 0, 0)
+}
+
+func coseKey(key *ecdsa.PublicKey) []byte {
+	raw, _ := key.Bytes() // 0x04 || X || Y
+	return encodeMap([][2][]byte{
+		{encodeInt(1), encodeInt(2)},  // kty: EC2
+		{encodeInt(3), encodeInt(-7)}, // alg: ES256
+		{encodeInt(-1), encodeInt(1)}, // crv: P-256
+		{encodeInt(-2), encodeBytes(raw[1:33])},
+		{encodeInt(-3), encodeBytes(raw[33:])},
+	})
+}
+
+func encode(data []byte) string { return base64.RawURLEncoding.EncodeToString(data) }
+
+func decode(data string) ([]byte, error) { return base64.RawURLEncoding.DecodeString(data) }
+
+// Минимальный CBOR энкодер (RFC 8949) - только то, что нужно аутентификатору
+func encodeHead(major byte, value uint64) []byte {
+	switch {
+	case value < 24:
+		return []byte{major<<5 | byte(value)}
+	case value <= 0xff:
+		return []byte{major<<5 | 24, byte(value)}
+	case value <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(value))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(value))
+	}
+}
+
+func encodeInt(value int64) []byte {
+	if value < 0 {
+		return encodeHead(1, uint64(-1-value))
+	}
+	return encodeHead(0, uint64(value))
+}
+
+func encodeBytes(value []byte) []byte { return append(encodeHead(2, uint64(len(value))), value...) }
+
+func encodeText(value string) []byte { return append(encodeHead(3, uint64(len(value))), value...) }
+
+func encodeMap(pairs [][2][]byte) []byte {
+	out := encodeHead(5, uint64(len(pairs)))
+	for _, pair := range pairs {
+		out = append(append(out, pair[0]...), pair[1]...)
+	}
+	return out
+}
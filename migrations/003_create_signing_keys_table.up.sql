@@ -0,0 +1,16 @@
+CREATE TABLE t_signing_keys (
+    id              VARCHAR(64)     NOT NULL,                   -- kid
+    version         BIGSERIAL       NOT NULL    UNIQUE,
+    algorithm       VARCHAR(16)     NOT NULL,
+    private_key     TEXT            NOT NULL,
+    state           VARCHAR(16)     NOT NULL,
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    activated_at    TIMESTAMP,
+    retired_at      TIMESTAMP,
+    revoked_at      TIMESTAMP,
+    PRIMARY KEY (id),
+    CHECK (state IN ('next', 'active', 'retiring', 'revoked'))
+);
+
+-- Одновременно может существовать только один active и один next ключ
+CREATE UNIQUE INDEX ux_signing_keys_single_state ON t_signing_keys (state) WHERE state IN ('next', 'active');
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS ix_refresh_tokens_auth_id;
+DROP INDEX IF EXISTS ix_refresh_tokens_family_id;
+DROP INDEX IF EXISTS ux_refresh_tokens_token_hash;
+
+ALTER TABLE t_refresh_tokens
+    DROP COLUMN IF EXISTS replaced_by,
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS used_at,
+    DROP COLUMN IF EXISTS family_id;
+
+ALTER TABLE t_refresh_tokens RENAME COLUMN token_hash TO token;
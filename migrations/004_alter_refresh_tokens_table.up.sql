@@ -0,0 +1,14 @@
+-- Токены в открытом виде никогда не выдавались, старые строки не переносим
+DELETE FROM t_refresh_tokens;
+
+ALTER TABLE t_refresh_tokens RENAME COLUMN token TO token_hash;
+
+ALTER TABLE t_refresh_tokens
+    ADD COLUMN family_id    UUID        NOT NULL,
+    ADD COLUMN used_at      TIMESTAMP,
+    ADD COLUMN revoked_at   TIMESTAMP,
+    ADD COLUMN replaced_by  UUID;
+
+CREATE UNIQUE INDEX ux_refresh_tokens_token_hash ON t_refresh_tokens (token_hash);
+CREATE INDEX ix_refresh_tokens_family_id ON t_refresh_tokens (family_id);
+CREATE INDEX ix_refresh_tokens_auth_id ON t_refresh_tokens (auth_id);
@@ -0,0 +1,8 @@
+CREATE TABLE t_revoked_tokens (
+    jti             VARCHAR(64)     NOT NULL,
+    expires_at      TIMESTAMP       NOT NULL,                   -- exp токена, после него запись не нужна
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (jti)
+);
+
+CREATE INDEX ix_revoked_tokens_expires_at ON t_revoked_tokens (expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE t_users
+    DROP COLUMN IF EXISTS scopes,
+    DROP COLUMN IF EXISTS roles;
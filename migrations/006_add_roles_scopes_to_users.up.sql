@@ -0,0 +1,3 @@
+ALTER TABLE t_users
+    ADD COLUMN roles    TEXT[]  NOT NULL    DEFAULT '{user}',
+    ADD COLUMN scopes   TEXT[]  NOT NULL    DEFAULT '{}';
@@ -0,0 +1,9 @@
+CREATE TABLE t_opaque_tokens (
+    token_hash      VARCHAR(64)     NOT NULL,                   -- SHA-256 от токена, сам токен не хранится
+    data            JSONB           NOT NULL,                   -- тип токена и claims
+    expires_at      TIMESTAMP       NOT NULL,
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (token_hash)
+);
+
+CREATE INDEX ix_opaque_tokens_expires_at ON t_opaque_tokens (expires_at);
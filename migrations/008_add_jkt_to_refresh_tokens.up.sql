@@ -0,0 +1,3 @@
+-- JWK thumbprint ключа клиента (DPoP, RFC 9449); пустая строка - токен не привязан к ключу
+ALTER TABLE t_refresh_tokens
+    ADD COLUMN jkt  VARCHAR(64)     NOT NULL    DEFAULT '';
@@ -0,0 +1,10 @@
+CREATE TABLE t_password_history (
+    id              UUID            NOT NULL,
+    user_id         UUID            NOT NULL    REFERENCES t_users (id) ON DELETE CASCADE,
+    password_hash   TEXT            NOT NULL,                   -- прежний хеш пароля (PHC)
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),  -- когда пароль был заменен
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX ix_password_history_user_id_create_at ON t_password_history (user_id, create_at DESC);
+CREATE INDEX ix_password_history_create_at ON t_password_history (create_at);
@@ -0,0 +1,2 @@
+ALTER TABLE t_users
+    DROP COLUMN IF EXISTS token_version;
@@ -0,0 +1,3 @@
+-- Версия токенов пользователя: увеличивается при смене пароля, токены со старой версией (claim ver) недействительны
+ALTER TABLE t_users
+    ADD COLUMN token_version    BIGINT  NOT NULL    DEFAULT 0;
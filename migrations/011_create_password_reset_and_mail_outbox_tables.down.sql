@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS t_mail_outbox;
+DROP TABLE IF EXISTS t_password_reset_tokens;
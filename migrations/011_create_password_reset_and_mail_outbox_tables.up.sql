@@ -0,0 +1,29 @@
+CREATE TABLE t_password_reset_tokens (
+    id              UUID            NOT NULL,
+    user_id         UUID            NOT NULL    REFERENCES t_users (id) ON DELETE CASCADE,
+    token_hash      VARCHAR(64)     NOT NULL    UNIQUE,         -- SHA-256 от токена из ссылки (hex)
+    expires_at      TIMESTAMP       NOT NULL,
+    used_at         TIMESTAMP       NULL,                       -- токен одноразовый
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX ix_password_reset_tokens_user_id ON t_password_reset_tokens (user_id);
+CREATE INDEX ix_password_reset_tokens_expires_at ON t_password_reset_tokens (expires_at);
+
+-- Письма отправляются фоновой задачей, чтобы запрос не зависел от доступности SMTP
+CREATE TABLE t_mail_outbox (
+    id              UUID            NOT NULL,
+    recipient       TEXT            NOT NULL,
+    subject         TEXT            NOT NULL,
+    body            TEXT            NOT NULL,                   -- очищается после отправки (содержит ссылки с токенами)
+    attempts        INTEGER         NOT NULL    DEFAULT 0,
+    last_error      TEXT            NULL,
+    next_attempt_at TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    sent_at         TIMESTAMP       NULL,
+    failed_at       TIMESTAMP       NULL,                       -- попытки исчерпаны
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX ix_mail_outbox_pending ON t_mail_outbox (next_attempt_at) WHERE sent_at IS NULL AND failed_at IS NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS t_email_verification_tokens;
+ALTER TABLE t_users
+    DROP COLUMN IF EXISTS email_verified_at;
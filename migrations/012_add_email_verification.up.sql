@@ -0,0 +1,16 @@
+-- Когда пользователь подтвердил владение email; NULL - не подтвержден
+ALTER TABLE t_users
+    ADD COLUMN email_verified_at    TIMESTAMP   NULL;
+
+CREATE TABLE t_email_verification_tokens (
+    id              UUID            NOT NULL,
+    user_id         UUID            NOT NULL    REFERENCES t_users (id) ON DELETE CASCADE,
+    token_hash      VARCHAR(64)     NOT NULL    UNIQUE,         -- SHA-256 от токена из ссылки (hex)
+    expires_at      TIMESTAMP       NOT NULL,
+    used_at         TIMESTAMP       NULL,
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX ix_email_verification_tokens_user_id_create_at ON t_email_verification_tokens (user_id, create_at DESC);
+CREATE INDEX ix_email_verification_tokens_expires_at ON t_email_verification_tokens (expires_at);
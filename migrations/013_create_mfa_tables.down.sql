@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS t_mfa_challenges;
+DROP TABLE IF EXISTS t_user_totp;
@@ -0,0 +1,25 @@
+-- TOTP фактор пользователя: секрет зашифрован (AES-GCM), фактор действует после подтверждения первым кодом
+CREATE TABLE t_user_totp (
+    user_id         UUID            NOT NULL    REFERENCES t_users (id) ON DELETE CASCADE,
+    secret          TEXT            NOT NULL,                   -- v<версия ключа>$base64(nonce|ciphertext)
+    confirmed_at    TIMESTAMP       NULL,
+    last_used_step  BIGINT          NOT NULL    DEFAULT 0,      -- шаг последнего принятого кода, защита от повтора
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    update_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (user_id)
+);
+
+-- Второй шаг входа: выдается после проверки пароля, обменивается на токены в VerifyMFA
+CREATE TABLE t_mfa_challenges (
+    id              UUID            NOT NULL,
+    user_id         UUID            NOT NULL    REFERENCES t_users (id) ON DELETE CASCADE,
+    token_hash      VARCHAR(64)     NOT NULL    UNIQUE,         -- SHA-256 от mfa токена (hex)
+    jkt             VARCHAR(64)     NOT NULL    DEFAULT '',     -- DPoP ключ, предъявленный при входе
+    attempts        INTEGER         NOT NULL    DEFAULT 0,      -- неверные коды
+    expires_at      TIMESTAMP       NOT NULL,
+    used_at         TIMESTAMP       NULL,
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX ix_mfa_challenges_expires_at ON t_mfa_challenges (expires_at);
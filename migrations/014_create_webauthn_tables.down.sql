@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS t_webauthn_sessions;
+DROP TABLE IF EXISTS t_webauthn_credentials;
@@ -0,0 +1,29 @@
+-- Учетные данные WebAuthn (passkeys, ключи безопасности) пользователя
+CREATE TABLE t_webauthn_credentials (
+    id              UUID            NOT NULL,
+    user_id         UUID            NOT NULL    REFERENCES t_users (id) ON DELETE CASCADE,
+    credential_id   BYTEA           NOT NULL    UNIQUE,         -- выдан аутентификатором
+    public_key      BYTEA           NOT NULL,                   -- COSE_Key
+    sign_count      BIGINT          NOT NULL    DEFAULT 0,      -- счетчик подписей, должен расти при каждом входе
+    transports      TEXT[]          NOT NULL    DEFAULT '{}',
+    name            VARCHAR(100)    NOT NULL    DEFAULT '',
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    last_used_at    TIMESTAMP       NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX ix_webauthn_credentials_user_id ON t_webauthn_credentials (user_id);
+
+-- Незавершенные церемонии регистрации и входа: challenge живет до finish или истечения
+CREATE TABLE t_webauthn_sessions (
+    id              UUID            NOT NULL,
+    user_id         UUID            NULL        REFERENCES t_users (id) ON DELETE CASCADE, -- NULL для входа без email
+    token_hash      VARCHAR(64)     NOT NULL    UNIQUE,         -- SHA-256 от session id (hex)
+    challenge       BYTEA           NOT NULL,
+    purpose         VARCHAR(20)     NOT NULL,                   -- registration | login
+    expires_at      TIMESTAMP       NOT NULL,
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX ix_webauthn_sessions_expires_at ON t_webauthn_sessions (expires_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS t_audit_events;
+DROP TABLE IF EXISTS t_mfa_recovery_codes;
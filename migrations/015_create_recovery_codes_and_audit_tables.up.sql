@@ -0,0 +1,22 @@
+-- Одноразовые коды восстановления MFA; новый набор заменяет старый целиком
+CREATE TABLE t_mfa_recovery_codes (
+    id              UUID            NOT NULL,
+    user_id         UUID            NOT NULL    REFERENCES t_users (id) ON DELETE CASCADE,
+    code_hash       VARCHAR(64)     NOT NULL,                   -- SHA-256 от нормализованного кода (hex)
+    used_at         TIMESTAMP       NULL,
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (id),
+    UNIQUE (user_id, code_hash)
+);
+
+-- Журнал событий безопасности
+CREATE TABLE t_audit_events (
+    id              UUID            NOT NULL,
+    user_id         UUID            NULL        REFERENCES t_users (id) ON DELETE SET NULL,
+    event_type      VARCHAR(64)     NOT NULL,
+    details         JSONB           NOT NULL    DEFAULT '{}',
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX ix_audit_events_user_id ON t_audit_events (user_id, create_at);
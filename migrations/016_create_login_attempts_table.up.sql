@@ -0,0 +1,10 @@
+-- Неудачные попытки входа по ключу (account:<email> или ip:<адрес>) для задержек и блокировки
+CREATE TABLE t_login_attempts (
+    key             VARCHAR(330)    NOT NULL,
+    failures        INTEGER         NOT NULL    DEFAULT 0,  -- неудачи в пределах окна
+    last_failure_at TIMESTAMP       NOT NULL,
+    locked_until    TIMESTAMP       NULL,                   -- до этого момента вход по ключу запрещен
+    PRIMARY KEY (key)
+);
+
+CREATE INDEX ix_login_attempts_last_failure_at ON t_login_attempts (last_failure_at);
@@ -0,0 +1,15 @@
+-- Одноразовые коды и ссылки для входа без пароля
+CREATE TABLE t_login_codes (
+    id              UUID            NOT NULL,
+    user_id         UUID            NOT NULL    REFERENCES t_users (id) ON DELETE CASCADE,
+    token_hash      VARCHAR(64)     NOT NULL    UNIQUE,         -- SHA-256 от токена из ссылки (hex)
+    code_hash       VARCHAR(64)     NOT NULL,                   -- SHA-256 от кода, привязанного к пользователю (hex)
+    attempts        INTEGER         NOT NULL    DEFAULT 0,      -- неверные коды
+    expires_at      TIMESTAMP       NOT NULL,
+    used_at         TIMESTAMP       NULL,
+    create_at       TIMESTAMP       NOT NULL    DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX ix_login_codes_user_id_create_at ON t_login_codes (user_id, create_at DESC);
+CREATE INDEX ix_login_codes_expires_at ON t_login_codes (expires_at);
@@ -0,0 +1,2 @@
+ALTER TABLE t_refresh_tokens
+    DROP COLUMN IF EXISTS token_version;
@@ -0,0 +1,8 @@
+-- Версия токенов пользователя на момент выдачи: refresh токен старой версии не обновляется
+ALTER TABLE t_refresh_tokens
+    ADD COLUMN token_version    BIGINT  NOT NULL    DEFAULT 0;
+
+UPDATE t_refresh_tokens r
+    SET token_version = u.token_version
+    FROM t_users u
+    WHERE u.id = r.auth_id;